@@ -0,0 +1,534 @@
+package main
+
+import (
+	"archive/zip"
+	"backend/internal/bulkimport"
+	"backend/internal/models"
+	"backend/internal/repository/storagerepo"
+	"backend/internal/validation"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// maxImportSize is the largest bulk import archive accepted, in bytes.
+const maxImportSize = 500 * 1024 * 1024
+
+// bulkImportObjectKey is where the archive of a bulk import is uploaded.
+func bulkImportObjectKey(id primitive.ObjectID) string {
+	return fmt.Sprintf("imports/%s.zip", id.Hex())
+}
+
+// createBulkImport starts a bulk import and returns a presigned POST policy
+// for uploading its ZIP archive. The language and licence in the body are
+// used for files whose manifest row does not name one. Imported documents are
+// saved as drafts unless submit is set.
+func (app *application) createBulkImport(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Language string `json:"language"`
+		Licence  string `json:"licence"`
+		Submit   bool   `json:"submit"`
+	}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if payload.Language != "" {
+		if payload.Language, err = models.NormalizeLanguage(payload.Language); err != nil {
+			app.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+	if payload.Licence != "" {
+		if payload.Licence, err = models.NormalizeLicence(payload.Licence); err != nil {
+			app.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+	}
+
+	bulkImport := &models.BulkImport{
+		ID:        primitive.NewObjectID(),
+		UserID:    userID,
+		Status:    "uploading",
+		Language:  payload.Language,
+		Licence:   payload.Licence,
+		Submit:    payload.Submit,
+		Results:   []models.BulkImportResult{},
+		CreatedAt: time.Now(),
+		ExpiresAt: time.Now().Add(uploadSessionLifetime),
+	}
+	bulkImport.ObjectKey = bulkImportObjectKey(bulkImport.ID)
+
+	presignedRequest, err := app.Storage.PostObject("share2teach", bulkImport.ObjectKey, "application/zip", maxImportSize, 3600)
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("error generating presigned URL: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.DB.CreateBulkImport(bulkImport)
+	if err != nil {
+		log.Printf("Error inserting bulk import into MongoDB: %v", err)
+		app.errorJSON(w, errors.New("could not start import"), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		ImportID     primitive.ObjectID `json:"import_id"`
+		PresignedURL string             `json:"presigned_url"`
+		Fields       map[string]string  `json:"fields"`
+	}{
+		ImportID:     bulkImport.ID,
+		PresignedURL: presignedRequest.URL,
+		Fields:       presignedRequest.Values,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+// bulkImportForUser loads the bulk import in the URL if the user started it
+// or is an admin. It writes the error response and returns nil otherwise.
+func (app *application) bulkImportForUser(w http.ResponseWriter, r *http.Request) *models.BulkImport {
+	userID, role, err := app.userFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return nil
+	}
+
+	importID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid import ID"), http.StatusBadRequest)
+		return nil
+	}
+
+	bulkImport, err := app.DB.GetBulkImport(importID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			app.errorJSON(w, errors.New("import not found"), http.StatusNotFound)
+			return nil
+		}
+		log.Printf("Error fetching bulk import: %v", err)
+		app.errorJSON(w, errors.New("could not fetch import"), http.StatusInternalServerError)
+		return nil
+	}
+
+	if bulkImport.UserID != userID && role != "admin" {
+		app.errorJSON(w, errors.New("import not found"), http.StatusNotFound)
+		return nil
+	}
+
+	return bulkImport
+}
+
+// startBulkImport queues an uploaded archive for processing.
+func (app *application) startBulkImport(w http.ResponseWriter, r *http.Request) {
+	bulkImport := app.bulkImportForUser(w, r)
+	if bulkImport == nil {
+		return
+	}
+
+	if bulkImport.Status != "uploading" {
+		app.errorJSON(w, fmt.Errorf("import is already %s", bulkImport.Status), http.StatusConflict)
+		return
+	}
+
+	if time.Now().After(bulkImport.ExpiresAt) {
+		app.errorJSON(w, errors.New("import has expired"), http.StatusGone)
+		return
+	}
+
+	_, err := app.Storage.HeadObject("share2teach", bulkImport.ObjectKey)
+	if err != nil {
+		if errors.Is(err, storagerepo.ErrObjectNotFound) {
+			app.errorJSON(w, errors.New("archive has not been uploaded"), http.StatusBadRequest)
+			return
+		}
+		app.errorJSON(w, fmt.Errorf("error verifying uploaded archive: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	started, err := app.DB.SetBulkImportStatus(bulkImport.ID, "uploading", "queued")
+	if err != nil {
+		log.Printf("Error queueing bulk import: %v", err)
+		app.errorJSON(w, errors.New("could not start import"), http.StatusInternalServerError)
+		return
+	}
+	if !started {
+		app.errorJSON(w, errors.New("import has already been started"), http.StatusConflict)
+		return
+	}
+
+	app.queueBulkImport(bulkImport.ID)
+	bulkImport.Status = "queued"
+
+	err = app.writeJSON(w, http.StatusAccepted, bulkImport)
+	if err != nil {
+		return
+	}
+}
+
+// getBulkImport reports the state of a bulk import and, once it is done, the
+// outcome for every file in its manifest.
+func (app *application) getBulkImport(w http.ResponseWriter, r *http.Request) {
+	bulkImport := app.bulkImportForUser(w, r)
+	if bulkImport == nil {
+		return
+	}
+
+	err := app.writeJSON(w, http.StatusOK, bulkImport)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) listBulkImports(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	bulkImports, err := app.DB.GetBulkImportsByUser(userID)
+	if err != nil {
+		log.Printf("Error fetching bulk imports: %v", err)
+		app.errorJSON(w, errors.New("could not fetch imports"), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, bulkImports)
+	if err != nil {
+		return
+	}
+}
+
+// queueBulkImport schedules a bulk import for processing. When the queue is
+// full the import stays queued and is picked up by the requeue job instead.
+func (app *application) queueBulkImport(importID primitive.ObjectID) {
+	select {
+	case app.importQueue <- importID:
+	default:
+		log.Printf("Import queue full, bulk import %s will be processed later", importID.Hex())
+	}
+}
+
+// importWorker processes queued bulk imports one at a time.
+func (app *application) importWorker() {
+	for importID := range app.importQueue {
+		if err := app.processBulkImport(importID); err != nil {
+			log.Printf("Error processing bulk import %s: %v", importID.Hex(), err)
+		}
+	}
+}
+
+// requeueBulkImports queues every import still waiting to be processed.
+func (app *application) requeueBulkImports() error {
+	bulkImports, err := app.DB.FindBulkImportsByStatus("queued")
+	if err != nil {
+		return err
+	}
+
+	for _, bulkImport := range bulkImports {
+		app.queueBulkImport(bulkImport.ID)
+	}
+
+	return nil
+}
+
+// failInterruptedImports marks imports that were being processed when the
+// server stopped as failed. Their report says which files made it in.
+func (app *application) failInterruptedImports() error {
+	bulkImports, err := app.DB.FindBulkImportsByStatus("processing")
+	if err != nil {
+		return err
+	}
+
+	for _, bulkImport := range bulkImports {
+		bulkImport.Status = "failed"
+		bulkImport.Error = "import was interrupted by a server restart"
+		bulkImport.CompletedAt = time.Now()
+		if err := app.DB.FinishBulkImport(&bulkImport); err != nil {
+			log.Printf("Error failing interrupted bulk import %s: %v", bulkImport.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+// expireBulkImports removes imports whose archive was never started, along
+// with the archive if it was uploaded.
+func (app *application) expireBulkImports() error {
+	bulkImports, err := app.DB.FindBulkImportsByStatus("uploading")
+	if err != nil {
+		return err
+	}
+
+	for _, bulkImport := range bulkImports {
+		if time.Now().Before(bulkImport.ExpiresAt) {
+			continue
+		}
+
+		err := app.Storage.DeleteObjects("share2teach", []string{bulkImport.ObjectKey})
+		if err != nil {
+			log.Printf("Error deleting archive of bulk import %s: %v", bulkImport.ID.Hex(), err)
+			continue
+		}
+
+		err = app.DB.DeleteBulkImport(bulkImport.ID)
+		if err != nil {
+			log.Printf("Error deleting bulk import %s: %v", bulkImport.ID.Hex(), err)
+		}
+	}
+
+	return nil
+}
+
+// processBulkImport unpacks the archive of a bulk import, creates a document
+// for every valid manifest entry and records the outcome of each.
+func (app *application) processBulkImport(importID primitive.ObjectID) error {
+	started, err := app.DB.SetBulkImportStatus(importID, "queued", "processing")
+	if err != nil || !started {
+		// Already picked up by another worker
+		return err
+	}
+
+	bulkImport, err := app.DB.GetBulkImport(importID)
+	if err != nil {
+		return err
+	}
+
+	results, err := app.importArchive(bulkImport)
+	bulkImport.Results = results
+	bulkImport.Status = "completed"
+	if err != nil {
+		bulkImport.Status = "failed"
+		bulkImport.Error = err.Error()
+	}
+	bulkImport.CompletedAt = time.Now()
+
+	// The archive is not needed once its files are stored as documents
+	if err := app.Storage.DeleteObjects("share2teach", []string{bulkImport.ObjectKey}); err != nil {
+		log.Printf("Error deleting archive of bulk import %s: %v", importID.Hex(), err)
+	}
+
+	return app.DB.FinishBulkImport(bulkImport)
+}
+
+// importArchive imports the files of a bulk import archive. An error is
+// returned when the archive as a whole cannot be used; problems with single
+// files are reported in their result instead.
+func (app *application) importArchive(bulkImport *models.BulkImport) ([]models.BulkImportResult, error) {
+	results := []models.BulkImportResult{}
+
+	tmp, size, err := app.spoolObjectUpTo(bulkImport.ObjectKey, maxImportSize)
+	if errors.Is(err, errObjectTooLarge) {
+		return results, fmt.Errorf("archive exceeds the maximum size of %d bytes", maxImportSize)
+	}
+	if err != nil {
+		return results, fmt.Errorf("could not read archive: %v", err)
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	archive, err := zip.NewReader(tmp, size)
+	if err != nil {
+		return results, errors.New("file is not a valid ZIP archive")
+	}
+
+	manifestFile, ok := bulkimport.FindFile(archive, bulkimport.ManifestName)
+	if !ok {
+		return results, fmt.Errorf("archive does not contain %s", bulkimport.ManifestName)
+	}
+
+	manifest, err := manifestFile.Open()
+	if err != nil {
+		return results, fmt.Errorf("could not read %s: %v", bulkimport.ManifestName, err)
+	}
+	entries, err := bulkimport.ParseManifest(io.LimitReader(manifest, 1024*1024))
+	manifest.Close()
+	if err != nil {
+		return results, err
+	}
+
+	seen := map[string]bool{}
+	for _, entry := range entries {
+		result := models.BulkImportResult{Line: entry.Line, Filename: entry.Filename, Status: "failed"}
+
+		if seen[entry.Filename] {
+			result.Error = "file is listed more than once"
+		} else {
+			seen[entry.Filename] = true
+			result.DocumentID, err = app.importEntry(bulkImport, archive, entry)
+			if err != nil {
+				result.Error = err.Error()
+			} else {
+				result.Status = "imported"
+			}
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// importEntry validates one manifest entry and its file and creates a
+// document for it the same way a single upload does.
+func (app *application) importEntry(bulkImport *models.BulkImport, archive *zip.Reader, entry bulkimport.Entry) (primitive.ObjectID, error) {
+	if entry.Filename == "" || entry.Title == "" || entry.Subject == "" || entry.Grade == "" {
+		return primitive.NilObjectID, errors.New("filename, title, subject and grade must be provided")
+	}
+
+	details := documentDetails{
+		Description: entry.Description,
+		Tags:        entry.Tags,
+		Language:    entry.Language,
+		Licence:     entry.Licence,
+	}
+	if details.Language == "" {
+		details.Language = bulkImport.Language
+	}
+	if details.Licence == "" {
+		details.Licence = bulkImport.Licence
+	}
+	if err := details.normalize(); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	contentType, ok := models.ContentTypeForFilename(entry.Filename)
+	if !ok {
+		return primitive.NilObjectID, errors.New("file type is not allowed")
+	}
+
+	file, ok := bulkimport.FindFile(archive, entry.Filename)
+	if !ok {
+		return primitive.NilObjectID, errors.New("file is not in the archive")
+	}
+	if file.UncompressedSize64 > maxDocumentSize {
+		return primitive.NilObjectID, fmt.Errorf("file exceeds the maximum size of %d bytes", maxDocumentSize)
+	}
+
+	tmp, size, err := extractArchiveFile(file)
+	if err != nil {
+		return primitive.NilObjectID, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	// Catch broken files now rather than after they were stored
+	result := validation.Inspect(tmp, size, contentType)
+	if !result.Valid {
+		return primitive.NilObjectID, errors.New(result.Reason)
+	}
+
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return primitive.NilObjectID, err
+	}
+
+	documentID := primitive.NewObjectID()
+	objectKey := revisionObjectKey(documentID, 1)
+
+	err = app.Storage.WriteObject("share2teach", objectKey, contentType, tmp)
+	if err != nil {
+		return primitive.NilObjectID, errors.New("could not store file")
+	}
+
+	etag := ""
+	if object, err := app.Storage.HeadObject("share2teach", objectKey); err == nil {
+		etag = strings.Trim(aws.ToString(object.ETag), `"`)
+	}
+
+	document := &models.Document{
+		ID:               documentID,
+		Title:            entry.Title,
+		Description:      details.Description,
+		Tags:             details.Tags,
+		Language:         details.Language,
+		Licence:          details.Licence,
+		OriginalFilename: path.Base(strings.ReplaceAll(entry.Filename, "\\", "/")),
+		CreatedAt:        time.Now().UTC().Add(2 * time.Hour),
+		UserID:           bulkImport.UserID,
+		Moderated:        false,
+		Subject:          entry.Subject,
+		Grade:            entry.Grade,
+		Reported:         false,
+		RatingID:         primitive.NewObjectID(),
+		Size:             size,
+		ContentType:      contentType,
+		ETag:             etag,
+
+		ValidationStatus: "pending",
+		ScanStatus:       "pending",
+	}
+
+	// Like single uploads, imports stay private drafts unless submitted
+	document.ApprovalStatus = "draft"
+	if bulkImport.Submit {
+		document.ApprovalStatus = "pending"
+	}
+
+	err = app.createDocument(document)
+	if err != nil {
+		log.Printf("Error creating imported document: %v", err)
+		if err := app.Storage.DeleteObjects("share2teach", []string{objectKey}); err != nil {
+			log.Printf("Error deleting file of failed import %s: %v", objectKey, err)
+		}
+		return primitive.NilObjectID, errors.New("could not create document")
+	}
+
+	return documentID, nil
+}
+
+// extractArchiveFile unpacks a file of an archive to a temporary file,
+// refusing files that inflate past maxDocumentSize whatever their header says.
+// The caller must close and remove the file.
+func extractArchiveFile(file *zip.File) (*os.File, int64, error) {
+	rc, err := file.Open()
+	if err != nil {
+		return nil, 0, fmt.Errorf("could not unpack file: %v", err)
+	}
+	defer rc.Close()
+
+	tmp, err := os.CreateTemp("", "share2teach-import-*")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	size, err := io.Copy(tmp, io.LimitReader(rc, maxDocumentSize+1))
+	switch {
+	case err != nil:
+		err = fmt.Errorf("could not unpack file: %v", err)
+	case size > maxDocumentSize:
+		err = fmt.Errorf("file exceeds the maximum size of %d bytes", maxDocumentSize)
+	case size == 0:
+		err = errors.New("file is empty")
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, 0, err
+	}
+
+	return tmp, size, nil
+}
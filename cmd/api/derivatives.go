@@ -0,0 +1,155 @@
+package main
+
+import (
+	"backend/internal/models"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// lineageEntry is a short reference to a document in the lineage of another.
+type lineageEntry struct {
+	DocumentID primitive.ObjectID `json:"document_id"`
+	Title      string             `json:"title"`
+	UserID     primitive.ObjectID `json:"user_id"`
+	Licence    string             `json:"licence"`
+}
+
+func newLineageEntry(document models.Document) lineageEntry {
+	return lineageEntry{
+		DocumentID: document.ID,
+		Title:      document.Title,
+		UserID:     document.UserID,
+		Licence:    document.Licence,
+	}
+}
+
+// parentForDerivative loads the document a new upload is derived from and
+// checks that it is published and that its licence allows the derivative to
+// be shared under licence. It writes the error response and returns nil
+// otherwise.
+func (app *application) parentForDerivative(w http.ResponseWriter, parentID primitive.ObjectID, licence string) *models.Document {
+	parent, err := app.DB.GetDocumentByID(parentID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			app.errorJSON(w, errors.New("the original document does not exist"), http.StatusBadRequest)
+			return nil
+		}
+		log.Printf("Error fetching original document: %v", err)
+		app.errorJSON(w, errors.New("could not fetch the original document"), http.StatusInternalServerError)
+		return nil
+	}
+
+	if !isPublished(*parent) {
+		app.errorJSON(w, errors.New("only approved documents can be adapted"), http.StatusBadRequest)
+		return nil
+	}
+
+	err = models.CheckDerivativeLicence(parent.Licence, licence)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnprocessableEntity)
+		return nil
+	}
+
+	return parent
+}
+
+// attribution returns the credit line for a derivative of parent, naming the
+// uploader of parent as its author.
+func (app *application) attribution(parent *models.Document) string {
+	author := ""
+	user, err := app.DB.GetUserByID(parent.UserID)
+	if err != nil {
+		log.Printf("Error fetching author of document %s: %v", parent.ID.Hex(), err)
+	} else {
+		author = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+
+	return models.Attribution(*parent, author)
+}
+
+// documentLineage returns the originals a document was derived from, nearest
+// first, and the documents derived from it that the viewer may see. The chain
+// of originals stops at the first one the viewer may not see.
+func (app *application) documentLineage(document *models.Document, userID primitive.ObjectID, role string) ([]lineageEntry, []lineageEntry, error) {
+	ancestors := []lineageEntry{}
+	parentID := document.DerivedFrom
+	for parentID != nil && len(ancestors) < models.MaxLineageDepth {
+		parent, err := app.DB.GetDocumentByID(*parentID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			break
+		}
+		if err != nil {
+			return nil, nil, err
+		}
+		if !canViewDocument(*parent, userID, role) {
+			break
+		}
+
+		ancestors = append(ancestors, newLineageEntry(*parent))
+		parentID = parent.DerivedFrom
+	}
+
+	derived, err := app.DB.GetDerivedDocuments(document.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	children := []lineageEntry{}
+	for _, child := range derived {
+		if canViewDocument(child, userID, role) {
+			children = append(children, newLineageEntry(child))
+		}
+	}
+
+	return ancestors, children, nil
+}
+
+// getDocument returns a single document with its previews, attribution and
+// lineage.
+func (app *application) getDocument(w http.ResponseWriter, r *http.Request) {
+	documentID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid document ID"), http.StatusBadRequest)
+		return
+	}
+
+	// Signing in is optional, but lets owners and staff see unpublished documents
+	userID, role, _ := app.userFromRequest(w, r)
+
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil || !canViewDocument(*document, userID, role) {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return
+	}
+
+	ancestors, children, err := app.documentLineage(document, userID, role)
+	if err != nil {
+		log.Printf("Error fetching lineage of document %s: %v", document.ID.Hex(), err)
+		app.errorJSON(w, errors.New("could not fetch document"), http.StatusInternalServerError)
+		return
+	}
+
+	type lineage struct {
+		Ancestors []lineageEntry `json:"ancestors"`
+		Children  []lineageEntry `json:"children"`
+	}
+
+	response := struct {
+		documentResult
+		Lineage lineage `json:"lineage"`
+	}{
+		documentResult: app.withPreviews([]models.Document{*document})[0],
+		Lineage:        lineage{Ancestors: ancestors, Children: children},
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"backend/internal/models"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Sources of download events.
+const (
+	downloadSourceDirect     = "direct"
+	downloadSourceCollection = "collection"
+	downloadSourceShareLink  = "share_link"
+	downloadSourceLTI        = "lti"
+)
+
+// recordDownload stores a download event for a document. Failing to record
+// a download never stops the download itself.
+func (app *application) recordDownload(w http.ResponseWriter, r *http.Request, document *models.Document, source string) {
+	event := &models.DownloadEvent{
+		ID:           primitive.NewObjectID(),
+		DocumentID:   document.ID,
+		UserID:       app.viewerFromRequest(w, r),
+		Subject:      document.Subject,
+		Source:       source,
+		Client:       clientClass(r.UserAgent()),
+		DownloadedAt: time.Now(),
+	}
+
+	err := app.DB.RecordDownload(event)
+	if err != nil {
+		log.Printf("Error recording download of document %s: %v", document.ID.Hex(), err)
+	}
+}
+
+// clientClass reduces a user agent to a coarse kind of client, so usage can
+// be understood without storing anything that identifies the user.
+func clientClass(userAgent string) string {
+	ua := strings.ToLower(userAgent)
+	switch {
+	case ua == "":
+		return "unknown"
+	case strings.Contains(ua, "bot") || strings.Contains(ua, "crawler") || strings.Contains(ua, "spider"):
+		return "bot"
+	case strings.Contains(ua, "ipad") || strings.Contains(ua, "tablet"):
+		return "tablet"
+	case strings.Contains(ua, "mobi") || strings.Contains(ua, "android") || strings.Contains(ua, "iphone"):
+		return "mobile"
+	case strings.Contains(ua, "mozilla"):
+		return "desktop"
+	default:
+		return "other"
+	}
+}
+
+// popularPeriods maps the periods accepted by the popularity endpoint to how
+// far back they reach.
+var popularPeriods = map[string]time.Duration{
+	"week":  7 * 24 * time.Hour,
+	"month": 30 * 24 * time.Hour,
+}
+
+// popularDocuments lists the most downloaded published documents of the past
+// week or month, grouped by subject.
+func (app *application) popularDocuments(w http.ResponseWriter, r *http.Request) {
+	period := r.URL.Query().Get("period")
+	if period == "" {
+		period = "week"
+	}
+
+	window, ok := popularPeriods[period]
+	if !ok {
+		app.errorJSON(w, errors.New("period must be week or month"), http.StatusBadRequest)
+		return
+	}
+
+	subject := strings.TrimSpace(r.URL.Query().Get("subject"))
+	limit := app.readLimit(r, 10, 50)
+
+	subjects, err := app.DB.GetPopularDocuments(time.Now().Add(-window), subject, limit)
+	if err != nil {
+		log.Printf("Error fetching popular documents: %v", err)
+		app.errorJSON(w, errors.New("could not fetch popular documents"), http.StatusInternalServerError)
+		return
+	}
+
+	var ids []primitive.ObjectID
+	for _, popularity := range subjects {
+		for _, popular := range popularity.Documents {
+			ids = append(ids, popular.DocumentID)
+		}
+	}
+
+	documents, err := app.DB.GetDocumentsByIDs(ids)
+	if err != nil {
+		log.Printf("Error fetching popular documents: %v", err)
+		app.errorJSON(w, errors.New("could not fetch popular documents"), http.StatusInternalServerError)
+		return
+	}
+
+	published := map[primitive.ObjectID]documentResult{}
+	for _, result := range app.withPreviews(documents) {
+		if isPublished(result.Document) {
+			published[result.ID] = result
+		}
+	}
+
+	type popularResult struct {
+		documentResult
+		PeriodDownloads int `json:"period_downloads"`
+	}
+
+	type subjectResult struct {
+		Subject   string          `json:"subject"`
+		Documents []popularResult `json:"documents"`
+	}
+
+	response := []subjectResult{}
+	for _, popularity := range subjects {
+		result := subjectResult{Subject: popularity.Subject, Documents: []popularResult{}}
+		for _, popular := range popularity.Documents {
+			// Documents taken down since they were downloaded are not promoted
+			if document, ok := published[popular.DocumentID]; ok {
+				result.Documents = append(result.Documents, popularResult{documentResult: document, PeriodDownloads: popular.Downloads})
+			}
+		}
+		if len(result.Documents) > 0 {
+			response = append(response, result)
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+// presignDownload signs a download URL for one of a document's files that
+// makes browsers save it under a sanitized version of its original name. It
+// returns errFileChanged if the file is not the one that was scanned.
+func (app *application) presignDownload(document *models.Document, file *models.Revision) (*v4.PresignedHTTPRequest, error) {
+	object, err := app.Storage.HeadObject("share2teach", file.ObjectKey)
+	if err != nil {
+		return nil, err
+	}
+
+	err = app.checkScannedObject(file, object)
+	if err != nil {
+		return nil, err
+	}
+
+	// Later versions can be of a different file type than the original upload
+	contentType := document.ContentType
+	if aws.ToString(object.ContentType) != "" {
+		contentType = aws.ToString(object.ContentType)
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	filename := models.DownloadFilename(document.OriginalFilename, document.Title, contentType)
+
+	return app.Storage.GetObjectAttachment("share2teach", file.ObjectKey, filename, contentType, 3600)
+}
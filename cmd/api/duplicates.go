@@ -0,0 +1,91 @@
+package main
+
+import (
+	"backend/internal/models"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// duplicateDocument is a short reference to another document holding the
+// exact same file.
+type duplicateDocument struct {
+	DocumentID     primitive.ObjectID `json:"document_id"`
+	Title          string             `json:"title"`
+	UserID         primitive.ObjectID `json:"user_id"`
+	ApprovalStatus string             `json:"approvalStatus"`
+}
+
+// fileSHA256 returns the hex encoded SHA-256 of the first size bytes of r.
+func fileSHA256(r io.ReaderAt, size int64) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, io.NewSectionReader(r, 0, size)); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// findDuplicates maps the ID of each document to the other documents with the
+// same file hash. With approvedOnly set only published duplicates are listed,
+// which is what uploaders are allowed to see.
+func (app *application) findDuplicates(documents []models.Document, approvedOnly bool) (map[primitive.ObjectID][]duplicateDocument, error) {
+	return app.findFileDuplicates(documentHashes(documents...), approvedOnly)
+}
+
+// documentHashes maps the ID of each document to the hash of its original
+// upload, for documents whose upload has been hashed.
+func documentHashes(documents ...models.Document) map[primitive.ObjectID][]string {
+	hashes := map[primitive.ObjectID][]string{}
+	for _, document := range documents {
+		if document.SHA256 != "" {
+			hashes[document.ID] = append(hashes[document.ID], document.SHA256)
+		}
+	}
+	return hashes
+}
+
+// findFileDuplicates is findDuplicates for documents with several files,
+// given the hashes of the files of each document.
+func (app *application) findFileDuplicates(files map[primitive.ObjectID][]string, approvedOnly bool) (map[primitive.ObjectID][]duplicateDocument, error) {
+	var hashes []string
+	for _, fileHashes := range files {
+		hashes = append(hashes, fileHashes...)
+	}
+
+	matches, err := app.DB.FindDocumentsBySHA256(hashes)
+	if err != nil {
+		return nil, err
+	}
+
+	byHash := map[string][]models.Document{}
+	for _, match := range matches {
+		if match.DeletedAt != nil || (approvedOnly && !isPublished(match)) {
+			continue
+		}
+		byHash[match.SHA256] = append(byHash[match.SHA256], match)
+	}
+
+	duplicates := map[primitive.ObjectID][]duplicateDocument{}
+	for documentID, fileHashes := range files {
+		// A document can share more than one of its files with another
+		seen := map[primitive.ObjectID]bool{documentID: true}
+		for _, hash := range fileHashes {
+			for _, match := range byHash[hash] {
+				if seen[match.ID] {
+					continue
+				}
+				seen[match.ID] = true
+				duplicates[documentID] = append(duplicates[documentID], duplicateDocument{
+					DocumentID:     match.ID,
+					Title:          match.Title,
+					UserID:         match.UserID,
+					ApprovalStatus: match.ApprovalStatus,
+				})
+			}
+		}
+	}
+
+	return duplicates, nil
+}
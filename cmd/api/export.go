@@ -0,0 +1,78 @@
+package main
+
+import (
+	"backend/internal/export"
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// catalogueColumns are the columns of a catalogue export.
+var catalogueColumns = []string{
+	"document_id", "title", "subject", "grade", "language", "licence", "tags", "description",
+	"uploader_id", "uploader_name", "uploaded_at", "approval_status", "moderated", "moderated_at", "reported",
+	"average_rating", "times_rated", "download_count", "content_type", "size", "page_count",
+	"original_filename", "derived_from",
+}
+
+// catalogueRow lays out an entry in the order of catalogueColumns.
+func catalogueRow(entry *models.CatalogueEntry) []any {
+	derivedFrom := ""
+	if entry.DerivedFrom != nil {
+		derivedFrom = entry.DerivedFrom.Hex()
+	}
+
+	return []any{
+		entry.ID.Hex(), entry.Title, entry.Subject, entry.Grade, entry.Language, entry.Licence,
+		strings.Join(entry.Tags, "; "), entry.Description,
+		// created_at is stored shifted to South African time, so the upload time comes from the ID
+		entry.UserID.Hex(), entry.UploaderName, entry.ID.Timestamp(), entry.ApprovalStatus, entry.Moderated, entry.ModeratedAt, entry.Reported,
+		entry.AverageRating, entry.TimesRated, entry.DownloadCount, entry.ContentType, entry.Size, entry.PageCount,
+		entry.OriginalFilename, derivedFrom,
+	}
+}
+
+// exportCatalogue streams the metadata of every document matching the search
+// filters as CSV, JSON Lines or an Excel workbook, chosen by the "format"
+// query parameter.
+func (app *application) exportCatalogue(w http.ResponseWriter, r *http.Request) {
+	formatName := r.URL.Query().Get("format")
+	if formatName == "" {
+		formatName = "csv"
+	}
+
+	format, ok := export.Formats[formatName]
+	if !ok {
+		app.errorJSON(w, errors.New("format must be csv, jsonl or xlsx"), http.StatusBadRequest)
+		return
+	}
+
+	filename := fmt.Sprintf("share2teach-catalogue-%s.%s", time.Now().Format("2006-01-02"), format.Extension)
+	w.Header().Set("Content-Type", format.ContentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	writer, err := export.NewWriter(formatName, w, catalogueColumns)
+	if err != nil {
+		log.Printf("Error starting catalogue export: %v", err)
+		return
+	}
+
+	// Once the first bytes are sent the status can no longer change, so
+	// failures are only logged and leave the client with a truncated file
+	err = app.DB.ExportCatalogue(documentQueryFromRequest(r), func(entry *models.CatalogueEntry) error {
+		return writer.WriteRow(catalogueRow(entry))
+	})
+	if err != nil {
+		log.Printf("Error exporting catalogue: %v", err)
+		return
+	}
+
+	err = writer.Close()
+	if err != nil {
+		log.Printf("Error finishing catalogue export: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"backend/internal/extraction"
+	"backend/internal/models"
+	"errors"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// queueExtraction schedules the text of a document to be (re-)extracted.
+func (app *application) queueExtraction(documentID primitive.ObjectID) {
+	select {
+	case app.extractionQueue <- documentID:
+	default:
+		log.Printf("Extraction queue full, skipping text extraction of document %s", documentID.Hex())
+	}
+}
+
+// extractionWorker extracts the text of queued documents one at a time.
+func (app *application) extractionWorker() {
+	for documentID := range app.extractionQueue {
+		if err := app.extractDocumentText(documentID); err != nil {
+			log.Printf("Error extracting text of document %s: %v", documentID.Hex(), err)
+		}
+	}
+}
+
+// extractDocumentText extracts the text of the file currently served for a
+// document and stores it. Nothing is done when the stored text was already
+// extracted from the same object.
+func (app *application) extractDocumentText(documentID primitive.ObjectID) error {
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil {
+		return err
+	}
+
+	file, err := app.downloadRevision(document, "", true)
+	if err != nil {
+		return err
+	}
+	objectKey := file.ObjectKey
+
+	object, err := app.Storage.HeadObject("share2teach", objectKey)
+	if err != nil {
+		return err
+	}
+
+	etag := strings.Trim(aws.ToString(object.ETag), `"`)
+	contentType := aws.ToString(object.ContentType)
+
+	existing, err := app.DB.GetDocumentText(documentID)
+	if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+	if existing != nil && existing.ObjectKey == objectKey && existing.ETag == etag {
+		return nil
+	}
+
+	if !extraction.Supported(contentType) {
+		return nil
+	}
+
+	tmp, size, err := app.spoolObject(objectKey)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	text, err := extraction.Extract(tmp, size, contentType)
+	if err != nil {
+		return err
+	}
+
+	// Stored text must stay well below MongoDB's 16 MB document limit
+	err = app.DB.SaveDocumentText(&models.DocumentText{
+		DocumentID:  documentID,
+		Content:     extraction.Truncate(text.Content, extraction.MaxTextSize),
+		Pages:       text.Pages,
+		Slides:      text.Slides,
+		ObjectKey:   objectKey,
+		ETag:        etag,
+		ExtractedAt: time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Slides are the pages of a presentation
+	pageCount := text.Pages
+	if text.Slides > 0 {
+		pageCount = text.Slides
+	}
+	if pageCount == 0 {
+		return nil
+	}
+
+	return app.DB.UpdateDocumentsByID(documentID, bson.M{"$set": bson.M{"page_count": pageCount}})
+}
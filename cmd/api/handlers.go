@@ -2,18 +2,22 @@ package main
 
 import (
 	"backend/internal/models"
+	"backend/internal/repository/storagerepo"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/go-chi/chi/v5"
 
 	"github.com/golang-jwt/jwt"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
 )
 
 func (app *application) Home(w http.ResponseWriter, r *http.Request) {
@@ -284,6 +288,12 @@ func (app *application) uploadDocumentMetadata(w http.ResponseWriter, r *http.Re
 		Title      string             `json:"title"`
 		Subject    string             `json:"subject"`
 		Grade      string             `json:"grade"`
+		documentDetails
+
+		// DerivedFrom marks the upload as an adaptation of another document
+		DerivedFrom *primitive.ObjectID `json:"derived_from"`
+		// Submit sends the document for review straight away instead of saving a draft
+		Submit bool `json:"submit"`
 	}
 
 	err = app.readJSON(w, r, &payload)
@@ -295,67 +305,241 @@ func (app *application) uploadDocumentMetadata(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	newDocument := &models.Document{
-		ID:        payload.DocumentID,
-		Title:     payload.Title,
-		CreatedAt: time.Now().UTC().Add(2 * time.Hour),
-		UserID:    userID,
-		Moderated: false,
-		Subject:   payload.Subject,
-		Grade:     payload.Grade,
-		Reported:  false,
-		RatingID:  ratingID,
+	payload.Title = strings.TrimSpace(payload.Title)
+	payload.Subject = strings.TrimSpace(payload.Subject)
+	payload.Grade = strings.TrimSpace(payload.Grade)
+	if payload.Title == "" || payload.Subject == "" || payload.Grade == "" {
+		app.errorJSON(w, errors.New("title, subject and grade must be provided"), http.StatusBadRequest)
+		return
 	}
 
-	err = app.DB.UploadDocumentMetadata(newDocument)
+	err = payload.documentDetails.normalize()
 	if err != nil {
-		log.Printf("Error inserting document into MongoDB: %v", err)
-		err := app.errorJSON(w, err, http.StatusInternalServerError)
-		if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	var parent *models.Document
+	if payload.DerivedFrom != nil {
+		parent = app.parentForDerivative(w, *payload.DerivedFrom, payload.Licence)
+		if parent == nil {
 			return
 		}
+	}
+
+	// Only accept document IDs that were issued to this user and are still valid
+	session, err := app.DB.GetUploadSession(payload.DocumentID)
+	if err != nil || session.UserID != userID {
+		app.errorJSON(w, errors.New("unknown upload"), http.StatusForbidden)
 		return
 	}
 
-	initialRating := &models.Rating{
-		ID:    ratingID,
-		DocID: newDocument.ID,
+	if time.Now().After(session.ExpiresAt) {
+		app.errorJSON(w, errors.New("upload has expired"), http.StatusGone)
+		return
 	}
 
-	err = app.DB.CreateDocumentRating(initialRating)
+	// Make sure the file was actually uploaded before accepting its metadata
+	object, err := app.Storage.HeadObject("share2teach", payload.DocumentID.Hex())
 	if err != nil {
-		log.Printf("Error inserting document rating into MongoDB: %v", err)
-		err := app.errorJSON(w, err, http.StatusInternalServerError)
-		if err != nil {
+		if errors.Is(err, storagerepo.ErrObjectNotFound) {
+			app.errorJSON(w, errors.New("document file has not been uploaded"), http.StatusBadRequest)
 			return
 		}
+		app.errorJSON(w, fmt.Errorf("error verifying uploaded document: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	size := aws.ToInt64(object.ContentLength)
+	if size > maxDocumentSize {
+		app.discardUpload(payload.DocumentID)
+		app.errorJSON(w, fmt.Errorf("document exceeds the maximum size of %d bytes", maxDocumentSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	if aws.ToString(object.ContentType) != session.ContentType {
+		app.discardUpload(payload.DocumentID)
+		app.errorJSON(w, errors.New("uploaded file does not match the requested type"), http.StatusUnsupportedMediaType)
 		return
 	}
 
+	newDocument := &models.Document{
+		ID:               payload.DocumentID,
+		Title:            payload.Title,
+		Description:      payload.Description,
+		Tags:             payload.Tags,
+		Language:         payload.Language,
+		Licence:          payload.Licence,
+		OriginalFilename: session.Filename,
+		CreatedAt:        time.Now().UTC().Add(2 * time.Hour),
+		UserID:           userID,
+		Moderated:        false,
+		Subject:          payload.Subject,
+		Grade:            payload.Grade,
+		Reported:         false,
+		RatingID:         ratingID,
+		Size:             size,
+		ContentType:      aws.ToString(object.ContentType),
+		ETag:             strings.Trim(aws.ToString(object.ETag), `"`),
+
+		ValidationStatus: "pending",
+		ScanStatus:       "pending",
+	}
+
+	// Uploads stay private drafts until their owner submits them for review
+	newDocument.ApprovalStatus = "draft"
+	if payload.Submit {
+		newDocument.ApprovalStatus = "pending"
+	}
+
+	if parent != nil {
+		newDocument.DerivedFrom = &parent.ID
+		newDocument.Attribution = app.attribution(parent)
+	}
+
+	err = app.createDocument(newDocument)
+	if err != nil {
+		log.Printf("Error creating document: %v", err)
+		app.errorJSON(w, err, http.StatusInternalServerError)
+		return
+	}
+
+	// The upload is complete, so its session is no longer needed
+	err = app.DB.DeleteUploadSession(newDocument.ID)
+	if err != nil {
+		log.Printf("Error deleting upload session: %v", err)
+	}
+
 	err = app.writeJSON(w, http.StatusCreated, newDocument)
 	if err != nil {
 		return
 	}
 }
 
+// discardUpload deletes a rejected upload from the bucket so it does not
+// linger until its session expires. The session is kept, so the client can
+// upload a corrected file while it is valid.
+func (app *application) discardUpload(documentID primitive.ObjectID) {
+	err := app.Storage.DeleteObjects("share2teach", []string{documentID.Hex()})
+	if err != nil {
+		log.Printf("Error deleting rejected upload %s: %v", documentID.Hex(), err)
+	}
+}
+
+// createDocument stores the metadata of a newly uploaded document together
+// with its empty rating and first revision, and queues its file for
+// validation and malware scanning. The file must already be in the bucket
+// under the document ID.
+func (app *application) createDocument(document *models.Document) error {
+	err := app.DB.UploadDocumentMetadata(document)
+	if err != nil {
+		return fmt.Errorf("error inserting document into MongoDB: %v", err)
+	}
+
+	initialRating := &models.Rating{
+		ID:    document.RatingID,
+		DocID: document.ID,
+	}
+
+	err = app.DB.CreateDocumentRating(initialRating)
+	if err != nil {
+		return fmt.Errorf("error inserting document rating into MongoDB: %v", err)
+	}
+
+	// The uploaded file becomes the first revision of the document
+	initialRevision := &models.Revision{
+		ID:             primitive.NewObjectID(),
+		DocumentID:     document.ID,
+		Version:        1,
+		ObjectKey:      document.ID.Hex(),
+		UploadedBy:     document.UserID,
+		UploadedAt:     time.Now(),
+		Changelog:      "Initial upload",
+		ApprovalStatus: "pending",
+	}
+
+	err = app.DB.CreateRevision(initialRevision)
+	if err != nil {
+		return fmt.Errorf("error inserting document revision into MongoDB: %v", err)
+	}
+
+	app.queueValidation(document.ID)
+	app.queueScan(document.ID)
+
+	return nil
+}
+
+// uploadContentType checks the intended file name and MIME type of an upload
+// against the allow-list and returns the content type the upload must use.
+func uploadContentType(filename, contentType string) (string, error) {
+	allowed, ok := models.ContentTypeForFilename(filename)
+	if !ok {
+		return "", fmt.Errorf("file type of %q is not allowed", filename)
+	}
+
+	if contentType != "" && contentType != allowed {
+		return "", fmt.Errorf("content type %q does not match file %q", contentType, filename)
+	}
+
+	return allowed, nil
+}
+
 func (app *application) generatePresignedURLForUpload(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	filename := r.URL.Query().Get("filename")
+	if filename == "" {
+		app.errorJSON(w, errors.New("filename must be provided"), http.StatusBadRequest)
+		return
+	}
+
+	// Refuse disallowed file types before handing out an upload policy
+	contentType, err := uploadContentType(filename, r.URL.Query().Get("content_type"))
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
 	documentID := primitive.NewObjectID()
 	objectKey := fmt.Sprint( /*"documents/%s",*/ documentID.Hex()) // Object key for S3
 
-	// Generate the presigned URL for the client to upload the document
-	presignedRequest, err := app.Storage.PutObject("share2teach", objectKey, 3600)
+	// Generate the presigned POST policy for the client to upload the document
+	presignedRequest, err := app.Storage.PostObject("share2teach", objectKey, contentType, maxDocumentSize, 3600)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("error generating presigned URL: %v", err), http.StatusInternalServerError)
 		return
 	}
 
-	// Return the presigned URL to the client along with the document ID
+	// Bind the document ID to the requesting user until the metadata step
+	session := &models.UploadSession{
+		DocumentID:  documentID,
+		UserID:      userID,
+		Filename:    filename,
+		ContentType: contentType,
+		CreatedAt:   time.Now(),
+		ExpiresAt:   time.Now().Add(uploadSessionLifetime),
+	}
+
+	err = app.DB.CreateUploadSession(session)
+	if err != nil {
+		log.Printf("Error inserting upload session into MongoDB: %v", err)
+		app.errorJSON(w, errors.New("could not start upload"), http.StatusInternalServerError)
+		return
+	}
+
+	// Return the presigned URL and form fields to the client along with the document ID
 	response := struct {
 		DocumentID   primitive.ObjectID `json:"document_id"`
 		PresignedURL string             `json:"presigned_url"`
+		Fields       map[string]string  `json:"fields"`
 	}{
 		DocumentID:   documentID,
 		PresignedURL: presignedRequest.URL,
+		Fields:       presignedRequest.Values,
 	}
 
 	err = app.writeJSON(w, http.StatusOK, response)
@@ -365,13 +549,11 @@ func (app *application) generatePresignedURLForUpload(w http.ResponseWriter, r *
 }
 
 func (app *application) searchDocuments(w http.ResponseWriter, r *http.Request) {
-	title := r.URL.Query().Get("title")
-	subject := r.URL.Query().Get("subject")
-	grade := r.URL.Query().Get("grade")
+	query := documentQueryFromRequest(r)
 	correctRole := false
 
-	// finds the documents that match the given title
-	documents, err := app.DB.FindDocuments(title, subject, grade, correctRole)
+	// finds the documents that match the given filters
+	documents, err := app.DB.FindDocuments(query, correctRole)
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("error finding documents: %v", err), http.StatusInternalServerError)
 		log.Println("error finding documents:", err)
@@ -383,7 +565,7 @@ func (app *application) searchDocuments(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	err = app.writeJSON(w, http.StatusOK, documents)
+	err = app.writeJSON(w, http.StatusOK, app.withPreviews(documents))
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("error encoding response: %v", err), http.StatusInternalServerError)
 		return
@@ -391,13 +573,11 @@ func (app *application) searchDocuments(w http.ResponseWriter, r *http.Request)
 }
 
 func (app *application) searchDocumentsAdminOrModerator(w http.ResponseWriter, r *http.Request) {
-    title := r.URL.Query().Get("title")
-    subject := r.URL.Query().Get("subject")
-    grade := r.URL.Query().Get("grade")
+    query := documentQueryFromRequest(r)
     correctRole := true
 
-    // finds the documents that match the given title
-    documents, err := app.DB.FindDocuments(title, subject, grade, correctRole)
+    // finds the documents that match the given filters
+    documents, err := app.DB.FindDocuments(query, correctRole)
     if err != nil {
         app.errorJSON(w, fmt.Errorf("error finding documents: %v", err), http.StatusInternalServerError)
         log.Println("error finding documents:", err)
@@ -409,7 +589,18 @@ func (app *application) searchDocumentsAdminOrModerator(w http.ResponseWriter, r
         return
     }
 
-    err = app.writeJSON(w, http.StatusOK, documents)
+    results := app.withPreviews(documents)
+
+    // Show moderators the existing copies of each file so they review it only once
+    duplicates, err := app.findDuplicates(documents, false)
+    if err != nil {
+        log.Println("error finding duplicate documents:", err)
+    }
+    for i := range results {
+        results[i].Duplicates = duplicates[results[i].ID]
+    }
+
+    err = app.writeJSON(w, http.StatusOK, results)
     if err != nil {
         app.errorJSON(w, fmt.Errorf("error encoding response: %v", err), http.StatusInternalServerError)
         return
@@ -431,15 +622,47 @@ func (app *application) generatePresignedURLForDownload(w http.ResponseWriter, r
 		return
 	}
 
-	objectKey := fmt.Sprint(documentID.Hex())
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return
+	}
+
+	// Downloads follow the same visibility rules as search. Signing in is
+	// optional, so an invalid token is treated like an anonymous request.
+	userID, role, _ := app.userFromRequest(w, r)
+	if !canViewDocument(*document, userID, role) {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return
+	}
+
+	// Owners and staff can fetch versions awaiting review
+	reviewer := document.UserID == userID || isStaff(role)
+	file, err := app.downloadRevision(document, r.URL.Query().Get("version"), reviewer)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	}
+
+	// Never serve a file that has not been scanned and found clean
+	if file.ScanStatus != "clean" {
+		app.errorJSON(w, errors.New("document is not available for download until it has passed the malware scan"), http.StatusConflict)
+		return
+	}
 
-	// Generate the presigned URL
-	presignedRequest, err := app.Storage.GetObject("share2teach", objectKey, 3600)
+	// Generate the presigned URL, served under a readable file name
+	presignedRequest, err := app.presignDownload(document, file)
+	if errors.Is(err, errFileChanged) {
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	}
 	if err != nil {
 		app.errorJSON(w, fmt.Errorf("error generating presigned URL: %v", err), http.StatusInternalServerError)
 		return
 	}
 
+	app.recordDownload(w, r, document, downloadSourceDirect)
+
 	// Return the presigned URL
 	response := struct {
 		PresignedURL string `json:"presigned_url"`
@@ -658,6 +881,26 @@ func (app *application) moderateDocument(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil || document.DeletedAt != nil {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return
+	}
+
+	if document.ApprovalStatus == "draft" {
+		app.errorJSON(w, errors.New("document has not been submitted for review"), http.StatusConflict)
+		return
+	}
+
+	// Only files that passed validation and the malware scan can be approved
+	if payload.ApprovalStatus == "approved" {
+		err = fileApprovalError(withFileStatus(document, models.Revision{Version: 1}))
+		if err != nil {
+			app.errorJSON(w, err, http.StatusConflict)
+			return
+		}
+	}
+
 	err = app.DB.InsertModerationData(userID, documentID, payload.ApprovalStatus, payload.Comments)
 	if err != nil {
 		app.errorJSON(w, errors.New("could not complete action"), http.StatusInternalServerError)
@@ -669,6 +912,7 @@ func (app *application) moderateDocument(w http.ResponseWriter, r *http.Request)
 		"$set": bson.M{
 			"moderated": true,
 			"approvalStatus": payload.ApprovalStatus, // Store approval status ("approved" or "denied")
+			"moderated_at":   time.Now(),             // Orders the follower feed by approval time
 		},
 	}
 
@@ -680,6 +924,21 @@ func (app *application) moderateDocument(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// The initial upload is moderated together with the document while it is
+	// under review. Later versions are moderated on their own, so a decision on
+	// an already moderated document leaves its versions alone.
+	initialRevision, err := app.DB.GetRevision(documentID, 1)
+	if err == nil && initialRevision.ApprovalStatus == "pending" {
+		err = app.DB.SetRevisionStatus(documentID, 1, payload.ApprovalStatus)
+	} else if errors.Is(err, mongo.ErrNoDocuments) {
+		// Documents uploaded before revisions existed have no revision to update
+		err = nil
+	}
+	if err != nil {
+		app.errorJSON(w, errors.New("could not update revision"), http.StatusInternalServerError)
+		return
+	}
+
 	// Respond with success message and additional details
 	response := map[string]interface{}{
 		"message":        "Action complete",
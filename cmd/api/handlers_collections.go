@@ -0,0 +1,451 @@
+package main
+
+import (
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// collectionPayload is the body accepted when creating or replacing a collection.
+type collectionPayload struct {
+	Title       string               `json:"title"`
+	Description string               `json:"description"`
+	DocumentIDs []primitive.ObjectID `json:"document_ids"`
+	Public      bool                 `json:"public"`
+}
+
+// validateCollection checks a collection payload for the user saving it. Public
+// collections may only hold published documents; private ones may also hold
+// the user's own unpublished documents.
+func (app *application) validateCollection(userID primitive.ObjectID, payload *collectionPayload) error {
+	payload.Title = strings.TrimSpace(payload.Title)
+	if payload.Title == "" {
+		return errors.New("title must be provided")
+	}
+
+	description, err := models.NormalizeDescription(payload.Description)
+	if err != nil {
+		return err
+	}
+	payload.Description = description
+
+	if len(payload.DocumentIDs) > models.MaxCollectionDocuments {
+		return fmt.Errorf("a collection can hold at most %d documents", models.MaxCollectionDocuments)
+	}
+
+	seen := map[primitive.ObjectID]bool{}
+	for _, id := range payload.DocumentIDs {
+		if seen[id] {
+			return fmt.Errorf("document %s appears more than once", id.Hex())
+		}
+		seen[id] = true
+	}
+	if payload.DocumentIDs == nil {
+		payload.DocumentIDs = []primitive.ObjectID{}
+	}
+
+	documents, err := app.DB.GetDocumentsByIDs(payload.DocumentIDs)
+	if err != nil {
+		return fmt.Errorf("could not look up documents: %v", err)
+	}
+	if len(documents) != len(payload.DocumentIDs) {
+		return errors.New("collection refers to documents that do not exist")
+	}
+
+	for _, document := range documents {
+		if isPublished(document) {
+			continue
+		}
+		if payload.Public {
+			return fmt.Errorf("document %s is not approved and cannot be in a public collection", document.ID.Hex())
+		}
+		if document.UserID != userID || document.DeletedAt != nil {
+			return fmt.Errorf("document %s is not available", document.ID.Hex())
+		}
+	}
+
+	return nil
+}
+
+// collectionForViewer loads the collection in the URL if the viewer may see
+// it. It writes the error response and returns nil otherwise. Private
+// collections are reported as missing to everyone but their owner.
+func (app *application) collectionForViewer(w http.ResponseWriter, r *http.Request, viewerID primitive.ObjectID) *models.Collection {
+	collectionID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid collection ID"), http.StatusBadRequest)
+		return nil
+	}
+
+	collection, err := app.DB.GetCollection(collectionID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			app.errorJSON(w, errors.New("collection not found"), http.StatusNotFound)
+			return nil
+		}
+		log.Printf("Error fetching collection: %v", err)
+		app.errorJSON(w, errors.New("could not fetch collection"), http.StatusInternalServerError)
+		return nil
+	}
+
+	if !collection.Public && collection.UserID != viewerID {
+		app.errorJSON(w, errors.New("collection not found"), http.StatusNotFound)
+		return nil
+	}
+
+	return collection
+}
+
+// collectionDocuments returns the documents of a collection that the viewer
+// may see, in collection order. Documents unpublished after they were added
+// only remain visible to their own uploader, and deleted ones to nobody.
+func (app *application) collectionDocuments(collection *models.Collection, viewerID primitive.ObjectID) ([]models.Document, error) {
+	documents, err := app.DB.GetDocumentsByIDs(collection.DocumentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	visible := []models.Document{}
+	for _, document := range documents {
+		if document.DeletedAt == nil && (isPublished(document) || document.UserID == viewerID) {
+			visible = append(visible, document)
+		}
+	}
+
+	return visible, nil
+}
+
+// viewerFromRequest returns the ID of the authenticated user, or the nil ID
+// for anonymous requests to public endpoints.
+func (app *application) viewerFromRequest(w http.ResponseWriter, r *http.Request) primitive.ObjectID {
+	userID, _, err := app.userFromRequest(w, r)
+	if err != nil {
+		return primitive.NilObjectID
+	}
+	return userID
+}
+
+func (app *application) listCollections(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	collections, err := app.DB.GetCollectionsByUser(userID)
+	if err != nil {
+		log.Printf("Error fetching collections: %v", err)
+		app.errorJSON(w, errors.New("could not fetch collections"), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, collections)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) createCollection(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	var payload collectionPayload
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.validateCollection(userID, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	collection := &models.Collection{
+		ID:          primitive.NewObjectID(),
+		UserID:      userID,
+		Title:       payload.Title,
+		Description: payload.Description,
+		DocumentIDs: payload.DocumentIDs,
+		Public:      payload.Public,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err = app.DB.CreateCollection(collection)
+	if err != nil {
+		log.Printf("Error creating collection: %v", err)
+		app.errorJSON(w, errors.New("could not create collection"), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, collection)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) getCollection(w http.ResponseWriter, r *http.Request) {
+	viewerID := app.viewerFromRequest(w, r)
+
+	collection := app.collectionForViewer(w, r, viewerID)
+	if collection == nil {
+		return
+	}
+
+	documents, err := app.collectionDocuments(collection, viewerID)
+	if err != nil {
+		log.Printf("Error fetching collection documents: %v", err)
+		app.errorJSON(w, errors.New("could not fetch collection"), http.StatusInternalServerError)
+		return
+	}
+
+	// Only list the IDs of documents the viewer can still see
+	visible := *collection
+	visible.DocumentIDs = []primitive.ObjectID{}
+	for _, document := range documents {
+		visible.DocumentIDs = append(visible.DocumentIDs, document.ID)
+	}
+
+	response := struct {
+		*models.Collection
+		Documents []documentResult `json:"documents"`
+	}{
+		Collection: &visible,
+		Documents:  app.withPreviews(documents),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) updateCollection(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	collection := app.collectionForViewer(w, r, userID)
+	if collection == nil {
+		return
+	}
+	if collection.UserID != userID {
+		app.errorJSON(w, errors.New("only the owner of a collection can change it"), http.StatusForbidden)
+		return
+	}
+
+	var payload collectionPayload
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.validateCollection(userID, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	collection.Title = payload.Title
+	collection.Description = payload.Description
+	collection.DocumentIDs = payload.DocumentIDs
+	collection.Public = payload.Public
+	collection.UpdatedAt = time.Now()
+
+	err = app.DB.UpdateCollection(collection)
+	if err != nil {
+		log.Printf("Error updating collection: %v", err)
+		app.errorJSON(w, errors.New("could not update collection"), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, collection)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) deleteCollection(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	collection := app.collectionForViewer(w, r, userID)
+	if collection == nil {
+		return
+	}
+	if collection.UserID != userID {
+		app.errorJSON(w, errors.New("only the owner of a collection can delete it"), http.StatusForbidden)
+		return
+	}
+
+	err = app.DB.DeleteCollection(collection.ID)
+	if err != nil {
+		log.Printf("Error deleting collection: %v", err)
+		app.errorJSON(w, errors.New("could not delete collection"), http.StatusInternalServerError)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Collection deleted",
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+// collectionManifest lists a presigned download URL for every document in a
+// collection that can currently be downloaded. Each entry also carries a
+// download link that redirects to a fresh URL and counts the download.
+func (app *application) collectionManifest(w http.ResponseWriter, r *http.Request) {
+	viewerID := app.viewerFromRequest(w, r)
+
+	collection := app.collectionForViewer(w, r, viewerID)
+	if collection == nil {
+		return
+	}
+
+	documents, err := app.collectionDocuments(collection, viewerID)
+	if err != nil {
+		log.Printf("Error fetching collection documents: %v", err)
+		app.errorJSON(w, errors.New("could not fetch collection"), http.StatusInternalServerError)
+		return
+	}
+
+	type manifestEntry struct {
+		DocumentID   primitive.ObjectID `json:"document_id"`
+		Title        string             `json:"title"`
+		Filename     string             `json:"filename,omitempty"`
+		PresignedURL string             `json:"presigned_url"`
+		DownloadURL  string             `json:"download_url"`
+	}
+
+	entries := []manifestEntry{}
+	for _, document := range documents {
+		file, err := app.downloadRevision(&document, "", document.UserID == viewerID)
+		if err != nil {
+			log.Printf("Error resolving file of document %s: %v", document.ID.Hex(), err)
+			continue
+		}
+
+		// Files that have not passed the malware scan are left out
+		if file.ScanStatus != "clean" {
+			continue
+		}
+
+		presignedRequest, err := app.presignDownload(&document, file)
+		if errors.Is(err, errFileChanged) {
+			continue
+		}
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("error generating presigned URL: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		entries = append(entries, manifestEntry{
+			DocumentID:   document.ID,
+			Title:        document.Title,
+			Filename:     document.OriginalFilename,
+			PresignedURL: presignedRequest.URL,
+			DownloadURL:  fmt.Sprintf("/collections/%s/documents/%s/download", collection.ID.Hex(), document.ID.Hex()),
+		})
+	}
+
+	response := struct {
+		CollectionID primitive.ObjectID `json:"collection_id"`
+		Title        string             `json:"title"`
+		Documents    []manifestEntry    `json:"documents"`
+	}{
+		CollectionID: collection.ID,
+		Title:        collection.Title,
+		Documents:    entries,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+// downloadCollectionDocument counts a download of a document listed in a
+// collection's manifest and redirects to a presigned URL for it.
+func (app *application) downloadCollectionDocument(w http.ResponseWriter, r *http.Request) {
+	viewerID := app.viewerFromRequest(w, r)
+
+	collection := app.collectionForViewer(w, r, viewerID)
+	if collection == nil {
+		return
+	}
+
+	documentID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "documentID"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid document ID"), http.StatusBadRequest)
+		return
+	}
+
+	documents, err := app.collectionDocuments(collection, viewerID)
+	if err != nil {
+		log.Printf("Error fetching collection documents: %v", err)
+		app.errorJSON(w, errors.New("could not fetch collection"), http.StatusInternalServerError)
+		return
+	}
+
+	var document *models.Document
+	for i := range documents {
+		if documents[i].ID == documentID {
+			document = &documents[i]
+			break
+		}
+	}
+	if document == nil {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return
+	}
+
+	file, err := app.downloadRevision(document, "", document.UserID == viewerID)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusNotFound)
+		return
+	}
+
+	// Never serve a file that has not been scanned and found clean
+	if file.ScanStatus != "clean" {
+		app.errorJSON(w, errors.New("document is not available for download until it has passed the malware scan"), http.StatusConflict)
+		return
+	}
+
+	presignedRequest, err := app.presignDownload(document, file)
+	if errors.Is(err, errFileChanged) {
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("error generating presigned URL: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	app.recordDownload(w, r, document, downloadSourceCollection)
+
+	http.Redirect(w, r, presignedRequest.URL, http.StatusSeeOther)
+}
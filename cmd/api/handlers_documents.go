@@ -0,0 +1,385 @@
+package main
+
+import (
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// documentForOwnerOrAdmin loads the document in the URL and checks that the
+// authenticated user owns it or is an admin. It writes the error response and
+// returns nil when the document cannot be used. Documents in the trash are
+// reported as missing.
+func (app *application) documentForOwnerOrAdmin(w http.ResponseWriter, r *http.Request) *models.Document {
+	return app.ownedDocument(w, r, false)
+}
+
+// trashedDocumentForOwnerOrAdmin is documentForOwnerOrAdmin for documents in
+// the trash.
+func (app *application) trashedDocumentForOwnerOrAdmin(w http.ResponseWriter, r *http.Request) *models.Document {
+	return app.ownedDocument(w, r, true)
+}
+
+func (app *application) ownedDocument(w http.ResponseWriter, r *http.Request, trashed bool) *models.Document {
+	userID, role, err := app.userFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return nil
+	}
+
+	documentID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid document ID"), http.StatusBadRequest)
+		return nil
+	}
+
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+			return nil
+		}
+		log.Printf("Error fetching document: %v", err)
+		app.errorJSON(w, errors.New("could not fetch document"), http.StatusInternalServerError)
+		return nil
+	}
+
+	if (document.DeletedAt != nil) != trashed {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return nil
+	}
+
+	if document.UserID != userID && role != "admin" {
+		app.errorJSON(w, errors.New("only the owner of a document can change it"), http.StatusForbidden)
+		return nil
+	}
+
+	return document
+}
+
+func (app *application) updateDocument(w http.ResponseWriter, r *http.Request) {
+	document := app.documentForOwnerOrAdmin(w, r)
+	if document == nil {
+		return
+	}
+
+	var payload struct {
+		Title       *string   `json:"title"`
+		Subject     *string   `json:"subject"`
+		Grade       *string   `json:"grade"`
+		Description *string   `json:"description"`
+		Tags        *[]string `json:"tags"`
+		Language    *string   `json:"language"`
+		Licence     *string   `json:"licence"`
+	}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	fields := bson.M{}
+	for name, value := range map[string]*string{"title": payload.Title, "subject": payload.Subject, "grade": payload.Grade} {
+		if value == nil {
+			continue
+		}
+		if strings.TrimSpace(*value) == "" {
+			app.errorJSON(w, fmt.Errorf("%s must not be empty", name), http.StatusBadRequest)
+			return
+		}
+		fields[name] = strings.TrimSpace(*value)
+	}
+
+	if payload.Description != nil {
+		fields["description"], err = models.NormalizeDescription(*payload.Description)
+	}
+	if err == nil && payload.Tags != nil {
+		fields["tags"], err = models.NormalizeTags(*payload.Tags)
+	}
+	if err == nil && payload.Language != nil {
+		fields["language"], err = models.NormalizeLanguage(*payload.Language)
+	}
+	if err == nil && payload.Licence != nil {
+		fields["licence"], err = models.NormalizeLicence(*payload.Licence)
+	}
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// A derivative has to stay under a licence its original allows
+	if licence, ok := fields["licence"].(string); ok && document.DerivedFrom != nil {
+		parent, err := app.DB.GetDocumentByID(*document.DerivedFrom)
+		if err != nil && !errors.Is(err, mongo.ErrNoDocuments) {
+			log.Printf("Error fetching original document: %v", err)
+			app.errorJSON(w, errors.New("could not update document"), http.StatusInternalServerError)
+			return
+		}
+		// Once the original has been purged there is nothing left to check against
+		if err == nil {
+			if err := models.CheckDerivativeLicence(parent.Licence, licence); err != nil {
+				app.errorJSON(w, err, http.StatusUnprocessableEntity)
+				return
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		app.errorJSON(w, errors.New("nothing to update"), http.StatusBadRequest)
+		return
+	}
+
+	// Approved metadata was checked by a moderator, so changing it needs a new review
+	if document.ApprovalStatus == "approved" {
+		fields["approvalStatus"] = "pending"
+		fields["moderated"] = false
+	}
+
+	err = app.DB.UpdateDocumentsByID(document.ID, bson.M{"$set": fields})
+	if err != nil {
+		log.Printf("Error updating document: %v", err)
+		app.errorJSON(w, errors.New("could not update document"), http.StatusInternalServerError)
+		return
+	}
+
+	updated, err := app.DB.GetDocumentByID(document.ID)
+	if err != nil {
+		log.Printf("Error fetching updated document: %v", err)
+		app.errorJSON(w, errors.New("could not fetch document"), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, updated)
+	if err != nil {
+		return
+	}
+}
+
+// submitDocument submits a draft for review. Denied documents can be
+// submitted again once their owner has addressed the moderator's comments.
+func (app *application) submitDocument(w http.ResponseWriter, r *http.Request) {
+	document := app.documentForOwnerOrAdmin(w, r)
+	if document == nil {
+		return
+	}
+
+	if document.ValidationStatus == "failed" {
+		app.errorJSON(w, fmt.Errorf("document file failed validation: %s", document.ValidationReason), http.StatusConflict)
+		return
+	}
+
+	app.setApprovalStatus(w, document, []string{"draft", "denied"}, "pending",
+		errors.New("only drafts and denied documents can be submitted for review"))
+}
+
+// withdrawDocument turns a document that is waiting for review back into a
+// draft. Documents uploaded before drafts existed have no approval status yet.
+func (app *application) withdrawDocument(w http.ResponseWriter, r *http.Request) {
+	document := app.documentForOwnerOrAdmin(w, r)
+	if document == nil {
+		return
+	}
+
+	app.setApprovalStatus(w, document, []string{"", "pending"}, "draft",
+		errors.New("only documents waiting for review can be withdrawn"))
+}
+
+// setApprovalStatus moves a document from one of the statuses in from to
+// status to and responds with the updated document, or with conflictErr when
+// the document is in another state.
+func (app *application) setApprovalStatus(w http.ResponseWriter, document *models.Document, from []string, to string, conflictErr error) {
+	ok, err := app.DB.SetDocumentApprovalStatus(document.ID, from, to)
+	if err != nil {
+		log.Printf("Error updating approval status of document %s: %v", document.ID.Hex(), err)
+		app.errorJSON(w, errors.New("could not update document"), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		app.errorJSON(w, conflictErr, http.StatusConflict)
+		return
+	}
+
+	document.ApprovalStatus = to
+	document.Moderated = false
+
+	err = app.writeJSON(w, http.StatusOK, document)
+	if err != nil {
+		return
+	}
+}
+
+// deleteDocument moves a document to the trash. It can be restored until the
+// retention period runs out and it is purged.
+func (app *application) deleteDocument(w http.ResponseWriter, r *http.Request) {
+	document := app.documentForOwnerOrAdmin(w, r)
+	if document == nil {
+		return
+	}
+
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	deletedAt := time.Now()
+	err = app.DB.TrashDocument(document.ID, userID, deletedAt)
+	if err != nil {
+		log.Printf("Error deleting document %s: %v", document.ID.Hex(), err)
+		app.errorJSON(w, errors.New("could not delete document"), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Message  string    `json:"message"`
+		PurgedAt time.Time `json:"purged_at"`
+	}{
+		Message:  "Document moved to the trash",
+		PurgedAt: deletedAt.Add(app.TrashRetention),
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) restoreDocument(w http.ResponseWriter, r *http.Request) {
+	document := app.trashedDocumentForOwnerOrAdmin(w, r)
+	if document == nil {
+		return
+	}
+
+	err := app.DB.RestoreDocument(document.ID)
+	if err != nil {
+		log.Printf("Error restoring document %s: %v", document.ID.Hex(), err)
+		app.errorJSON(w, errors.New("could not restore document"), http.StatusInternalServerError)
+		return
+	}
+
+	document.DeletedAt = nil
+	document.DeletedBy = nil
+
+	err = app.writeJSON(w, http.StatusOK, document)
+	if err != nil {
+		return
+	}
+}
+
+// listTrash lists the documents in the trash of the authenticated user, or of
+// every user for admins.
+func (app *application) listTrash(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.userFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	owner := userID
+	if role == "admin" {
+		owner = primitive.NilObjectID
+	}
+
+	documents, err := app.DB.GetTrashedDocuments(owner)
+	if err != nil {
+		log.Printf("Error fetching trash: %v", err)
+		app.errorJSON(w, errors.New("could not fetch trash"), http.StatusInternalServerError)
+		return
+	}
+
+	type trashedDocument struct {
+		models.Document
+		PurgedAt time.Time `json:"purged_at"`
+	}
+
+	trash := []trashedDocument{}
+	for _, document := range documents {
+		trash = append(trash, trashedDocument{Document: document, PurgedAt: document.DeletedAt.Add(app.TrashRetention)})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, trash)
+	if err != nil {
+		return
+	}
+}
+
+// purgeTrash permanently removes documents that have been in the trash for
+// longer than the retention period.
+func (app *application) purgeTrash() error {
+	documents, err := app.DB.FindDocumentsTrashedBefore(time.Now().Add(-app.TrashRetention))
+	if err != nil {
+		return err
+	}
+
+	purged := 0
+	for i := range documents {
+		if err := app.purgeDocument(&documents[i]); err != nil {
+			log.Printf("Error purging document %s: %v", documents[i].ID.Hex(), err)
+			continue
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		log.Printf("Purged %d documents from the trash", purged)
+	}
+
+	return nil
+}
+
+// purgeDocument removes a document with its rating, revisions and stored files.
+func (app *application) purgeDocument(document *models.Document) error {
+	// Collect the stored files before the revisions that point at them are removed
+	objectKeys, err := app.documentObjectKeys(document)
+	if err != nil {
+		return err
+	}
+
+	err = app.DB.DeleteDocument(document)
+	if err != nil {
+		return err
+	}
+
+	// The document is gone from the database at this point, so a storage
+	// failure only leaves unreachable objects behind
+	err = app.Storage.DeleteObjects("share2teach", objectKeys)
+	if err != nil {
+		log.Printf("Error deleting files of document %s: %v", document.ID.Hex(), err)
+	}
+
+	return nil
+}
+
+// documentObjectKeys lists every object stored for a document: the file of
+// each revision and the previews rendered from them.
+func (app *application) documentObjectKeys(document *models.Document) ([]string, error) {
+	revisions, err := app.DB.GetRevisions(document.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Documents uploaded before revisions existed only have their original object
+	fileKeys := []string{revisionObjectKey(document.ID, 1)}
+	for _, revision := range revisions {
+		if revision.ObjectKey != fileKeys[0] {
+			fileKeys = append(fileKeys, revision.ObjectKey)
+		}
+	}
+
+	var keys []string
+	for _, key := range fileKeys {
+		keys = append(keys, key, fmt.Sprintf("previews/%s/thumbnail.jpg", key), fmt.Sprintf("previews/%s/preview.jpg", key))
+	}
+
+	return keys, nil
+}
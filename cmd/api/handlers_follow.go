@@ -0,0 +1,191 @@
+package main
+
+import (
+	"backend/internal/repository/dbrepo"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (app *application) listFollows(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	follows, err := app.DB.GetFollows(userID)
+	if err != nil {
+		log.Printf("Error fetching follows: %v", err)
+		app.errorJSON(w, errors.New("could not fetch follows"), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, follows)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) followUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	followeeID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid user ID"), http.StatusBadRequest)
+		return
+	}
+
+	if followeeID == userID {
+		app.errorJSON(w, errors.New("you cannot follow yourself"), http.StatusBadRequest)
+		return
+	}
+
+	// Make sure the educator being followed exists
+	_, err = app.DB.GetUserByID(followeeID)
+	if err != nil {
+		app.errorJSON(w, errors.New("user not found"), http.StatusNotFound)
+		return
+	}
+
+	err = app.DB.FollowUser(userID, followeeID)
+	if err != nil {
+		log.Printf("Error following user: %v", err)
+		app.errorJSON(w, errors.New("could not follow user"), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]string{"message": "Now following user"}
+	app.writeJSON(w, http.StatusOK, resp)
+}
+
+func (app *application) unfollowUser(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	followeeID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid user ID"), http.StatusBadRequest)
+		return
+	}
+
+	err = app.DB.UnfollowUser(userID, followeeID)
+	if err != nil {
+		log.Printf("Error unfollowing user: %v", err)
+		app.errorJSON(w, errors.New("could not unfollow user"), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]string{"message": "No longer following user"}
+	app.writeJSON(w, http.StatusOK, resp)
+}
+
+func (app *application) followSubject(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Subject string `json:"subject"`
+		Grade   string `json:"grade"`
+	}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if strings.TrimSpace(payload.Subject) == "" || strings.TrimSpace(payload.Grade) == "" {
+		app.errorJSON(w, errors.New("subject and grade must be provided"), http.StatusBadRequest)
+		return
+	}
+
+	err = app.DB.FollowSubject(userID, payload.Subject, payload.Grade)
+	if err != nil {
+		log.Printf("Error following subject: %v", err)
+		app.errorJSON(w, errors.New("could not follow subject"), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]string{"message": "Now following subject"}
+	app.writeJSON(w, http.StatusOK, resp)
+}
+
+func (app *application) unfollowSubject(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Subject string `json:"subject"`
+		Grade   string `json:"grade"`
+	}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = app.DB.UnfollowSubject(userID, payload.Subject, payload.Grade)
+	if err != nil {
+		log.Printf("Error unfollowing subject: %v", err)
+		app.errorJSON(w, errors.New("could not unfollow subject"), http.StatusInternalServerError)
+		return
+	}
+
+	resp := map[string]string{"message": "No longer following subject"}
+	app.writeJSON(w, http.StatusOK, resp)
+}
+
+func (app *application) feed(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	cursor := r.URL.Query().Get("cursor")
+	limit := app.readLimit(r, 20, 100)
+
+	documents, nextCursor, err := app.DB.GetFeed(userID, cursor, limit)
+	if err != nil {
+		if errors.Is(err, dbrepo.ErrInvalidCursor) {
+			app.errorJSON(w, err, http.StatusBadRequest)
+			return
+		}
+		log.Println("error building feed:", err)
+		app.errorJSON(w, fmt.Errorf("error building feed: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Documents  interface{} `json:"documents"`
+		NextCursor string      `json:"next_cursor,omitempty"`
+	}{
+		Documents:  app.withPreviews(documents),
+		NextCursor: nextCursor,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
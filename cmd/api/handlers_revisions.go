@@ -0,0 +1,360 @@
+package main
+
+import (
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// revisionUploadLifetime is how long the upload policy of a new version is valid.
+const revisionUploadLifetime = time.Hour
+
+// revisionObjectKey returns the S3 key of a document revision. The first
+// version keeps the bare document ID so documents uploaded before revisions
+// existed resolve to the same object.
+func revisionObjectKey(documentID primitive.ObjectID, version int) string {
+	if version <= 1 {
+		return documentID.Hex()
+	}
+	return fmt.Sprintf("%s/v%d", documentID.Hex(), version)
+}
+
+// downloadRevision resolves the revision whose file is served for a
+// document, with the results of the checks on that file. An empty version
+// selects the latest approved revision. Reviewers, i.e. the owner and staff,
+// may also fetch versions that have not been approved yet.
+func (app *application) downloadRevision(document *models.Document, versionStr string, reviewer bool) (*models.Revision, error) {
+	if versionStr == "" {
+		revision, err := app.DB.GetLatestApprovedRevision(document.ID)
+		if err == nil {
+			file := withFileStatus(document, *revision)
+			return &file, nil
+		}
+		if !errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("could not look up document versions: %v", err)
+		}
+
+		// Until a version is approved only the original upload can be served,
+		// to everyone only if it was approved before revisions existed
+		if !reviewer && document.ApprovalStatus != "approved" {
+			return nil, errors.New("document has no approved version")
+		}
+		file := withFileStatus(document, models.Revision{DocumentID: document.ID, Version: 1, ObjectKey: revisionObjectKey(document.ID, 1)})
+		return &file, nil
+	}
+
+	version, err := strconv.Atoi(versionStr)
+	if err != nil {
+		return nil, errors.New("invalid version")
+	}
+
+	revision, err := app.DB.GetRevision(document.ID, version)
+	if err != nil || (revision.ApprovalStatus != "approved" && !reviewer) {
+		return nil, fmt.Errorf("version %d not found", version)
+	}
+
+	file := withFileStatus(document, *revision)
+	return &file, nil
+}
+
+// withFileStatus returns revision with the results of the checks on its file.
+// Those of the original upload are recorded on the document itself.
+func withFileStatus(document *models.Document, revision models.Revision) models.Revision {
+	if revision.Version <= 1 {
+		revision.ValidationStatus = document.ValidationStatus
+		revision.ValidationReason = document.ValidationReason
+		revision.DetectedType = document.DetectedType
+		revision.SHA256 = document.SHA256
+		revision.ScanStatus = document.ScanStatus
+		revision.ScanSignature = document.ScanSignature
+		revision.ScannedETag = document.ScannedETag
+	}
+	return revision
+}
+
+// fileApprovalError explains why the file of a revision cannot be approved,
+// or returns nil if it can.
+func fileApprovalError(revision models.Revision) error {
+	switch {
+	case revision.ValidationStatus == "failed":
+		return fmt.Errorf("document file failed validation: %s", revision.ValidationReason)
+	case revision.ValidationStatus == "pending", revision.ValidationStatus == "" && revision.Version > 1:
+		// Original uploads from before validation existed have no status and
+		// stay approvable; later versions without one are validated first
+		return errors.New("document file is still being validated")
+	}
+
+	// Only files that were scanned and found clean can be approved
+	if revision.ScanStatus != "clean" {
+		return fmt.Errorf("document file has not passed the malware scan (scan status: %s)", revision.ScanStatus)
+	}
+
+	return nil
+}
+
+func (app *application) listDocumentVersions(w http.ResponseWriter, r *http.Request) {
+	documentID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid document ID"), http.StatusBadRequest)
+		return
+	}
+
+	// Versions follow the visibility of their document
+	userID, role, _ := app.userFromRequest(w, r)
+
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil || !canViewDocument(*document, userID, role) {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return
+	}
+
+	revisions, err := app.DB.GetRevisions(documentID)
+	if err != nil {
+		log.Printf("Error fetching revisions: %v", err)
+		app.errorJSON(w, errors.New("could not fetch versions"), http.StatusInternalServerError)
+		return
+	}
+
+	// Only approved versions are listed publicly
+	approved := []models.Revision{}
+	for _, revision := range revisions {
+		if revision.ApprovalStatus == "approved" {
+			approved = append(approved, revision)
+		}
+	}
+
+	err = app.writeJSON(w, http.StatusOK, approved)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) createDocumentVersion(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	documentID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid document ID"), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		Changelog   string `json:"changelog"`
+		Filename    string `json:"filename"`
+		ContentType string `json:"content_type"`
+	}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if payload.Changelog == "" {
+		app.errorJSON(w, errors.New("changelog must be provided"), http.StatusBadRequest)
+		return
+	}
+
+	contentType, err := uploadContentType(payload.Filename, payload.ContentType)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil || document.DeletedAt != nil {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return
+	}
+
+	if document.UserID != userID {
+		app.errorJSON(w, errors.New("only the owner can upload a new version"), http.StatusForbidden)
+		return
+	}
+
+	revisions, err := app.DB.GetRevisions(documentID)
+	if err != nil {
+		log.Printf("Error fetching revisions: %v", err)
+		app.errorJSON(w, errors.New("could not fetch versions"), http.StatusInternalServerError)
+		return
+	}
+
+	// Documents uploaded before revisions existed get their original file recorded as version 1
+	latest := 1
+	if len(revisions) == 0 {
+		initialRevision := models.Revision{
+			ID:             primitive.NewObjectID(),
+			DocumentID:     documentID,
+			Version:        1,
+			ObjectKey:      revisionObjectKey(documentID, 1),
+			UploadedBy:     document.UserID,
+			UploadedAt:     document.CreatedAt,
+			Changelog:      "Initial upload",
+			ApprovalStatus: document.ApprovalStatus,
+		}
+
+		err = app.DB.CreateInitialRevision(&initialRevision)
+		if err != nil {
+			log.Printf("Error inserting document revision into MongoDB: %v", err)
+			app.errorJSON(w, errors.New("could not create version"), http.StatusInternalServerError)
+			return
+		}
+	} else {
+		latest = revisions[len(revisions)-1].Version
+	}
+
+	// Allocated atomically, so concurrent uploads never share a version or object key
+	version, err := app.DB.NextRevisionVersion(documentID, latest)
+	if err != nil {
+		log.Printf("Error allocating document version: %v", err)
+		app.errorJSON(w, errors.New("could not create version"), http.StatusInternalServerError)
+		return
+	}
+
+	newRevision := &models.Revision{
+		ID:             primitive.NewObjectID(),
+		DocumentID:     documentID,
+		Version:        version,
+		ObjectKey:      revisionObjectKey(documentID, version),
+		UploadedBy:     userID,
+		UploadedAt:     time.Now(),
+		Changelog:      payload.Changelog,
+		ApprovalStatus: "pending",
+		ContentType:    contentType,
+
+		ValidationStatus: "pending",
+		ScanStatus:       "pending",
+	}
+
+	presignedRequest, err := app.Storage.PostObject("share2teach", newRevision.ObjectKey, contentType, maxDocumentSize, int64(revisionUploadLifetime.Seconds()))
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("error generating presigned URL: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.DB.CreateRevision(newRevision)
+	if err != nil {
+		log.Printf("Error inserting document revision into MongoDB: %v", err)
+		app.errorJSON(w, errors.New("could not create version"), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Revision     *models.Revision  `json:"revision"`
+		PresignedURL string            `json:"presigned_url"`
+		Fields       map[string]string `json:"fields"`
+	}{
+		Revision:     newRevision,
+		PresignedURL: presignedRequest.URL,
+		Fields:       presignedRequest.Values,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) moderateDocumentVersion(w http.ResponseWriter, r *http.Request) {
+	documentID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid document ID"), http.StatusBadRequest)
+		return
+	}
+
+	version, err := strconv.Atoi(chi.URLParam(r, "version"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid version"), http.StatusBadRequest)
+		return
+	}
+
+	var payload struct {
+		ApprovalStatus string `json:"approvalStatus"`
+		Comments       string `json:"comments"`
+	}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if payload.ApprovalStatus != "approved" && payload.ApprovalStatus != "denied" {
+		app.errorJSON(w, errors.New("approvalStatus must be approved or denied"), http.StatusBadRequest)
+		return
+	}
+
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil || document.DeletedAt != nil {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return
+	}
+
+	revision, err := app.DB.GetRevision(documentID, version)
+	if err != nil {
+		app.errorJSON(w, errors.New("version not found"), http.StatusNotFound)
+		return
+	}
+
+	// Only files that passed their checks can be approved
+	if payload.ApprovalStatus == "approved" {
+		err = fileApprovalError(withFileStatus(document, *revision))
+		if err != nil {
+			// The version may have been uploaded since the checks last ran
+			app.queueValidation(documentID)
+			app.queueScan(documentID)
+			app.errorJSON(w, err, http.StatusConflict)
+			return
+		}
+	}
+
+	comments := fmt.Sprintf("version %d: %s", version, payload.Comments)
+	err = app.DB.InsertModerationData(userID, documentID, payload.ApprovalStatus, comments)
+	if err != nil {
+		app.errorJSON(w, errors.New("could not complete action"), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.DB.SetRevisionStatus(documentID, version, payload.ApprovalStatus)
+	if err != nil {
+		app.errorJSON(w, errors.New("could not update revision"), http.StatusInternalServerError)
+		return
+	}
+
+	// A newly approved version changes the file served, so its text and previews are refreshed
+	if payload.ApprovalStatus == "approved" {
+		app.queueExtraction(documentID)
+		app.queuePreview(documentID)
+	}
+
+	response := map[string]interface{}{
+		"message":        "Action complete",
+		"documentID":     documentID.Hex(),
+		"version":        version,
+		"approvalStatus": payload.ApprovalStatus,
+		"comments":       payload.Comments,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
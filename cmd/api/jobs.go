@@ -0,0 +1,138 @@
+package main
+
+import (
+	"backend/internal/repository/storagerepo"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// startJobs launches the periodic maintenance jobs in the background. Only
+// failing interrupted imports runs before it returns, so that no import
+// started after startup is mistaken for one the previous shutdown cut off.
+func (app *application) startJobs() {
+	go app.runEvery("expire upload sessions", 15*time.Minute, app.expireUploadSessions)
+	go app.runEvery("abort abandoned multipart uploads", time.Hour, app.abortAbandonedMultipartUploads)
+	go app.validationWorker()
+	go app.runEvery("requeue pending validations", 10*time.Minute, app.requeuePendingValidations)
+	go app.scanWorker()
+	go app.runEvery("requeue pending scans", 10*time.Minute, app.requeuePendingScans)
+	go app.extractionWorker()
+	go app.previewWorker()
+	go app.uploadPlaceholders()
+	go app.runEvery("expire bulk imports", 15*time.Minute, app.expireBulkImports)
+	go app.importWorker()
+	go app.runEvery("requeue bulk imports", 10*time.Minute, app.requeueBulkImports)
+	go app.runEvery("purge trash", time.Hour, app.purgeTrash)
+	go app.runEvery("expire LTI sessions", 15*time.Minute, app.expireLTISessions)
+
+	// Documents approved before approval times were recorded get one, once.
+	// It walks the whole catalogue, so the server does not wait for it.
+	go func() {
+		if err := app.DB.BackfillModeratedAt(); err != nil {
+			log.Printf("Error backfilling approval times: %v", err)
+		}
+	}()
+
+	// Searches match extracted text through its index
+	go func() {
+		if err := app.DB.CreateDocumentTextIndex(); err != nil {
+			log.Printf("Error creating the document text index: %v", err)
+		}
+	}()
+
+	// Only imports cut off by the previous shutdown can be processing right now
+	if err := app.failInterruptedImports(); err != nil {
+		log.Printf("Error failing interrupted bulk imports: %v", err)
+	}
+}
+
+// runEvery calls job on every tick of interval for the lifetime of the process.
+// Failures are logged and retried on the next tick.
+func (app *application) runEvery(name string, interval time.Duration, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := job(); err != nil {
+			log.Printf("Job %q failed: %v", name, err)
+		}
+	}
+}
+
+// jobSet tracks the documents that are queued for or being processed by a
+// worker, so the requeue jobs do not queue the same document twice.
+type jobSet struct {
+	mu  sync.Mutex
+	ids map[primitive.ObjectID]bool
+}
+
+// add marks id as queued and reports whether it was not already.
+func (s *jobSet) add(id primitive.ObjectID) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.ids == nil {
+		s.ids = make(map[primitive.ObjectID]bool)
+	}
+	if s.ids[id] {
+		return false
+	}
+	s.ids[id] = true
+	return true
+}
+
+// done releases id once it has been processed or could not be queued.
+func (s *jobSet) done(id primitive.ObjectID) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.ids, id)
+}
+
+// expireUploadSessions removes upload sessions that were never completed,
+// along with any object that was uploaded for them.
+func (app *application) expireUploadSessions() error {
+	sessions, err := app.DB.GetExpiredUploadSessions(time.Now())
+	if err != nil {
+		return err
+	}
+
+	for _, session := range sessions {
+		// Leave the object alone if the metadata made it in despite the session lingering
+		_, err := app.DB.GetDocumentByID(session.DocumentID)
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			if session.MultipartUploadID != "" {
+				// The session is kept until the upload is gone, so the abandoned
+				// upload job can still find it
+				err = app.Storage.AbortMultipartUpload("share2teach", session.DocumentID.Hex(), session.MultipartUploadID)
+				if err != nil && !errors.Is(err, storagerepo.ErrUploadNotFound) {
+					continue
+				}
+			}
+			err = app.Storage.DeleteObjects("share2teach", []string{session.DocumentID.Hex()})
+			if err != nil {
+				log.Printf("Error deleting stray object %s: %v", session.DocumentID.Hex(), err)
+				continue
+			}
+		} else if err != nil {
+			log.Printf("Error checking document %s: %v", session.DocumentID.Hex(), err)
+			continue
+		}
+
+		err = app.DB.DeleteUploadSession(session.DocumentID)
+		if err != nil {
+			log.Printf("Error deleting upload session %s: %v", session.DocumentID.Hex(), err)
+		}
+	}
+
+	if len(sessions) > 0 {
+		log.Printf("Expired %d upload sessions", len(sessions))
+	}
+
+	return nil
+}
@@ -0,0 +1,523 @@
+package main
+
+import (
+	"backend/internal/lti"
+	"backend/internal/models"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+const (
+	// ltiLoginLifetime is how long a platform has to complete an OIDC login.
+	ltiLoginLifetime = 10 * time.Minute
+	// ltiDeepLinkLifetime is how long a teacher has to choose documents.
+	ltiDeepLinkLifetime = time.Hour
+	// maxDeepLinkItems is the largest number of documents added to a course at once.
+	maxDeepLinkItems = 50
+	// ltiStateCookie ties a launch to the browser that started the login.
+	ltiStateCookie = "lti_state"
+)
+
+// ltiStateCookieFor returns the cookie holding the state of a login. The
+// launch is posted from the platform's site, so the cookie must be sent
+// cross-site.
+func ltiStateCookieFor(state string, lifetime time.Duration) *http.Cookie {
+	return &http.Cookie{
+		Name:     ltiStateCookie,
+		Path:     "/lti",
+		Value:    state,
+		Expires:  time.Now().Add(lifetime),
+		MaxAge:   int(lifetime.Seconds()),
+		SameSite: http.SameSiteNoneMode,
+		HttpOnly: true,
+		Secure:   true,
+	}
+}
+
+// ltiKeySet publishes the public key that Deep Linking responses are signed
+// with, for platforms to verify them.
+func (app *application) ltiKeySet(w http.ResponseWriter, r *http.Request) {
+	keySet := lti.KeySet{Keys: []lti.JWK{lti.NewJWK(&app.LTIKey.PublicKey)}}
+
+	err := app.writeJSON(w, http.StatusOK, keySet)
+	if err != nil {
+		return
+	}
+}
+
+// ltiLogin answers the third-party login initiation of a platform by sending
+// the browser back to the platform's authorization endpoint with a fresh
+// state and nonce. The platform then posts the launch to ltiLaunch.
+func (app *application) ltiLogin(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	issuer := r.Form.Get("iss")
+	loginHint := r.Form.Get("login_hint")
+	if issuer == "" || loginHint == "" || r.Form.Get("target_link_uri") == "" {
+		app.errorJSON(w, errors.New("iss, login_hint and target_link_uri must be provided"), http.StatusBadRequest)
+		return
+	}
+
+	platform, err := app.DB.GetLTIPlatform(issuer, r.Form.Get("client_id"))
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		app.errorJSON(w, errors.New("this platform is not registered"), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching LTI platform: %v", err)
+		app.errorJSON(w, errors.New("could not start the launch"), http.StatusInternalServerError)
+		return
+	}
+
+	state, err := models.GenerateLTIToken()
+	if err != nil {
+		app.errorJSON(w, errors.New("could not start the launch"), http.StatusInternalServerError)
+		return
+	}
+	nonce, err := models.GenerateLTIToken()
+	if err != nil {
+		app.errorJSON(w, errors.New("could not start the launch"), http.StatusInternalServerError)
+		return
+	}
+
+	login := &models.LTILogin{
+		ID:         primitive.NewObjectID(),
+		State:      state,
+		Nonce:      nonce,
+		PlatformID: platform.ID,
+		ExpiresAt:  time.Now().Add(ltiLoginLifetime),
+	}
+
+	err = app.DB.CreateLTILogin(login)
+	if err != nil {
+		log.Printf("Error storing LTI login: %v", err)
+		app.errorJSON(w, errors.New("could not start the launch"), http.StatusInternalServerError)
+		return
+	}
+
+	authURL, err := url.Parse(platform.AuthLoginURL)
+	if err != nil {
+		app.errorJSON(w, errors.New("the platform's authorization endpoint is invalid"), http.StatusInternalServerError)
+		return
+	}
+
+	query := authURL.Query()
+	query.Set("scope", "openid")
+	query.Set("response_type", "id_token")
+	query.Set("response_mode", "form_post")
+	query.Set("prompt", "none")
+	query.Set("client_id", platform.ClientID)
+	query.Set("redirect_uri", app.APIURL+"/lti/launch")
+	query.Set("login_hint", loginHint)
+	query.Set("state", state)
+	query.Set("nonce", nonce)
+	if hint := r.Form.Get("lti_message_hint"); hint != "" {
+		query.Set("lti_message_hint", hint)
+	}
+	authURL.RawQuery = query.Encode()
+
+	http.SetCookie(w, ltiStateCookieFor(state, ltiLoginLifetime))
+	http.Redirect(w, r, authURL.String(), http.StatusFound)
+}
+
+// ltiLaunch validates the id_token a platform posts after the login. A
+// resource link launch opens the linked document; a Deep Linking launch
+// sends the teacher to the document picker.
+func (app *application) ltiLaunch(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	// The state must come back to the browser that started the login, so a
+	// launch cannot be forced on someone else
+	state := r.PostForm.Get("state")
+	cookie, err := r.Cookie(ltiStateCookie)
+	if err != nil || state == "" || subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		app.errorJSON(w, errors.New("the launch did not come from this browser, launch again from the course"), http.StatusBadRequest)
+		return
+	}
+	http.SetCookie(w, ltiStateCookieFor("", -time.Second))
+
+	// The state can only be used once, so a launch cannot be replayed
+	login, err := app.DB.TakeLTILogin(state, time.Now())
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		app.errorJSON(w, errors.New("unknown or expired login, launch again from the course"), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching LTI login: %v", err)
+		app.errorJSON(w, errors.New("could not complete the launch"), http.StatusInternalServerError)
+		return
+	}
+
+	platform, err := app.DB.GetLTIPlatformByID(login.PlatformID)
+	if err != nil {
+		app.errorJSON(w, errors.New("this platform is no longer registered"), http.StatusNotFound)
+		return
+	}
+
+	registration := lti.Registration{Issuer: platform.Issuer, ClientID: platform.ClientID, DeploymentIDs: platform.DeploymentIDs}
+	claims, err := lti.ParseLaunch(r.PostForm.Get("id_token"), app.ltiKeySets.Keyfunc(platform.KeySetURL), registration, login.Nonce)
+	if err != nil {
+		log.Printf("Refused LTI launch from %s: %v", platform.Issuer, err)
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	switch claims.MessageType {
+	case lti.ResourceLinkRequest:
+		app.ltiOpenDocument(w, r, claims)
+	case lti.DeepLinkingRequest:
+		app.ltiStartDeepLinking(w, r, platform, claims)
+	}
+}
+
+// ltiOpenDocument redirects a resource link launch to the document the link
+// was created for.
+func (app *application) ltiOpenDocument(w http.ResponseWriter, r *http.Request, claims *lti.LaunchClaims) {
+	documentID, err := primitive.ObjectIDFromHex(claims.CustomString("document_id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("the link does not name a document"), http.StatusBadRequest)
+		return
+	}
+
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil || !isPublished(*document) {
+		app.errorJSON(w, errors.New("this document is no longer available"), http.StatusNotFound)
+		return
+	}
+
+	file, err := app.downloadRevision(document, "", false)
+	if err != nil {
+		log.Printf("Error resolving file of document %s: %v", document.ID.Hex(), err)
+		app.errorJSON(w, errors.New("could not open the document"), http.StatusInternalServerError)
+		return
+	}
+
+	// Never serve a file that has not been scanned and found clean
+	if file.ScanStatus != "clean" {
+		app.errorJSON(w, errors.New("this document is not available right now"), http.StatusConflict)
+		return
+	}
+
+	presignedRequest, err := app.presignDownload(document, file)
+	if errors.Is(err, errFileChanged) {
+		app.errorJSON(w, errors.New("this document is not available right now"), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("error generating presigned URL: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	app.recordDownload(w, r, document, downloadSourceLTI)
+
+	http.Redirect(w, r, presignedRequest.URL, http.StatusSeeOther)
+}
+
+// ltiStartDeepLinking remembers a Deep Linking request and sends the teacher
+// to the picker, which reads it with the token in its URL.
+func (app *application) ltiStartDeepLinking(w http.ResponseWriter, r *http.Request, platform *models.LTIPlatform, claims *lti.LaunchClaims) {
+	if !claims.IsInstructor() {
+		app.errorJSON(w, errors.New("only teachers can add documents to a course"), http.StatusForbidden)
+		return
+	}
+
+	settings := claims.DeepLinkingSettings
+	if !settings.Accepts(lti.ResourceLinkItemType) {
+		app.errorJSON(w, errors.New("the platform does not accept LTI resource links here"), http.StatusBadRequest)
+		return
+	}
+
+	token, err := models.GenerateLTIToken()
+	if err != nil {
+		app.errorJSON(w, errors.New("could not start document selection"), http.StatusInternalServerError)
+		return
+	}
+
+	deepLink := &models.LTIDeepLink{
+		ID:             primitive.NewObjectID(),
+		Token:          token,
+		PlatformID:     platform.ID,
+		DeploymentID:   claims.DeploymentID,
+		ReturnURL:      settings.ReturnURL,
+		Data:           settings.Data,
+		AcceptMultiple: settings.AcceptMultiple,
+		ExpiresAt:      time.Now().Add(ltiDeepLinkLifetime),
+	}
+	if claims.Context != nil {
+		deepLink.CourseTitle = claims.Context.Title
+	}
+
+	err = app.DB.CreateLTIDeepLink(deepLink)
+	if err != nil {
+		log.Printf("Error storing LTI deep link: %v", err)
+		app.errorJSON(w, errors.New("could not start document selection"), http.StatusInternalServerError)
+		return
+	}
+
+	pickerURL, err := url.Parse(app.LTIPickerURL)
+	if err != nil {
+		app.errorJSON(w, errors.New("the document picker is misconfigured"), http.StatusInternalServerError)
+		return
+	}
+	query := pickerURL.Query()
+	query.Set("deep_link", token)
+	pickerURL.RawQuery = query.Encode()
+
+	http.Redirect(w, r, pickerURL.String(), http.StatusSeeOther)
+}
+
+// getLTIDeepLink describes a pending Deep Linking request to the picker.
+func (app *application) getLTIDeepLink(w http.ResponseWriter, r *http.Request) {
+	deepLink, err := app.DB.GetLTIDeepLink(chi.URLParam(r, "token"), time.Now())
+	if err != nil {
+		app.errorJSON(w, errors.New("unknown or expired document selection"), http.StatusNotFound)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, deepLink)
+	if err != nil {
+		return
+	}
+}
+
+// completeLTIDeepLink signs the teacher's selection of published documents
+// as a Deep Linking response. The picker posts the returned JWT to the
+// return URL in a form field named JWT, which adds the links to the course.
+// A selection without documents cancels the request.
+func (app *application) completeLTIDeepLink(w http.ResponseWriter, r *http.Request) {
+	deepLink, err := app.DB.GetLTIDeepLink(chi.URLParam(r, "token"), time.Now())
+	if err != nil {
+		app.errorJSON(w, errors.New("unknown or expired document selection"), http.StatusNotFound)
+		return
+	}
+
+	var payload struct {
+		DocumentIDs []string `json:"document_ids"`
+	}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if len(payload.DocumentIDs) > maxDeepLinkItems {
+		app.errorJSON(w, fmt.Errorf("at most %d documents can be added at once", maxDeepLinkItems), http.StatusBadRequest)
+		return
+	}
+	if len(payload.DocumentIDs) > 1 && !deepLink.AcceptMultiple {
+		app.errorJSON(w, errors.New("the platform accepts a single document here"), http.StatusBadRequest)
+		return
+	}
+
+	launchURL := app.APIURL + "/lti/launch"
+	items := []lti.ContentItem{}
+	for _, hex := range payload.DocumentIDs {
+		documentID, err := primitive.ObjectIDFromHex(hex)
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("invalid document ID %q", hex), http.StatusBadRequest)
+			return
+		}
+
+		document, err := app.DB.GetDocumentByID(documentID)
+		if err != nil || !isPublished(*document) {
+			app.errorJSON(w, fmt.Errorf("document %s is not published", hex), http.StatusBadRequest)
+			return
+		}
+
+		items = append(items, lti.ContentItem{
+			Type:   lti.ResourceLinkItemType,
+			Title:  document.Title,
+			Text:   document.Description,
+			URL:    launchURL,
+			Custom: map[string]string{"document_id": document.ID.Hex()},
+		})
+	}
+
+	platform, err := app.DB.GetLTIPlatformByID(deepLink.PlatformID)
+	if err != nil {
+		app.errorJSON(w, errors.New("this platform is no longer registered"), http.StatusNotFound)
+		return
+	}
+
+	response, err := lti.SignDeepLinkingResponse(app.LTIKey, lti.DeepLinkingReturn{
+		Issuer:       platform.Issuer,
+		ClientID:     platform.ClientID,
+		DeploymentID: deepLink.DeploymentID,
+		Data:         deepLink.Data,
+	}, items)
+	if err != nil {
+		log.Printf("Error signing deep linking response: %v", err)
+		app.errorJSON(w, errors.New("could not complete document selection"), http.StatusInternalServerError)
+		return
+	}
+
+	// Only one selection is returned for each request
+	ok, err := app.DB.DeleteLTIDeepLink(deepLink.ID)
+	if err != nil {
+		log.Printf("Error deleting LTI deep link: %v", err)
+		app.errorJSON(w, errors.New("could not complete document selection"), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		app.errorJSON(w, errors.New("this selection has already been completed"), http.StatusConflict)
+		return
+	}
+
+	result := struct {
+		ReturnURL string `json:"return_url"`
+		JWT       string `json:"jwt"`
+	}{
+		ReturnURL: deepLink.ReturnURL,
+		JWT:       response,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, result)
+	if err != nil {
+		return
+	}
+}
+
+// ltiToolConfiguration lists the URLs an administrator enters in a platform
+// when registering Share2Teach as a tool.
+type ltiToolConfiguration struct {
+	LoginURL       string `json:"login_url"`
+	RedirectURL    string `json:"redirect_url"`
+	KeySetURL      string `json:"key_set_url"`
+	DeepLinkingURL string `json:"deep_linking_url"`
+}
+
+func (app *application) ltiToolURLs() ltiToolConfiguration {
+	root := app.APIURL + "/lti"
+	return ltiToolConfiguration{
+		LoginURL:       root + "/login",
+		RedirectURL:    root + "/launch",
+		KeySetURL:      root + "/jwks",
+		DeepLinkingURL: root + "/launch",
+	}
+}
+
+func (app *application) listLTIPlatforms(w http.ResponseWriter, r *http.Request) {
+	platforms, err := app.DB.GetLTIPlatforms()
+	if err != nil {
+		log.Printf("Error fetching LTI platforms: %v", err)
+		app.errorJSON(w, errors.New("could not fetch platforms"), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Tool      ltiToolConfiguration `json:"tool"`
+		Platforms []models.LTIPlatform `json:"platforms"`
+	}{
+		Tool:      app.ltiToolURLs(),
+		Platforms: platforms,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+// registerLTIPlatform registers a learning management system that may launch
+// Share2Teach.
+func (app *application) registerLTIPlatform(w http.ResponseWriter, r *http.Request) {
+	var platform models.LTIPlatform
+
+	err := app.readJSON(w, r, &platform)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	err = platform.Validate()
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	_, err = app.DB.GetLTIPlatform(platform.Issuer, platform.ClientID)
+	if err == nil {
+		app.errorJSON(w, errors.New("this platform and client ID are already registered"), http.StatusConflict)
+		return
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		log.Printf("Error fetching LTI platform: %v", err)
+		app.errorJSON(w, errors.New("could not register platform"), http.StatusInternalServerError)
+		return
+	}
+
+	platform.ID = primitive.NewObjectID()
+	platform.CreatedAt = time.Now()
+
+	err = app.DB.CreateLTIPlatform(&platform)
+	if err != nil {
+		log.Printf("Error storing LTI platform: %v", err)
+		app.errorJSON(w, errors.New("could not register platform"), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		Tool     ltiToolConfiguration `json:"tool"`
+		Platform models.LTIPlatform   `json:"platform"`
+	}{
+		Tool:     app.ltiToolURLs(),
+		Platform: platform,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) deleteLTIPlatform(w http.ResponseWriter, r *http.Request) {
+	platformID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid platform ID"), http.StatusBadRequest)
+		return
+	}
+
+	ok, err := app.DB.DeleteLTIPlatform(platformID)
+	if err != nil {
+		log.Printf("Error deleting LTI platform: %v", err)
+		app.errorJSON(w, errors.New("could not delete platform"), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		app.errorJSON(w, errors.New("platform not found"), http.StatusNotFound)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Platform deleted",
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+// expireLTISessions removes LTI logins and Deep Linking requests that were
+// never completed.
+func (app *application) expireLTISessions() error {
+	return app.DB.DeleteExpiredLTISessions(time.Now())
+}
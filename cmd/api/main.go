@@ -1,11 +1,14 @@
 package main
 
 import (
+	"backend/internal/lti"
 	"backend/internal/repository"
 	"backend/internal/repository/dbrepo"
 	"backend/internal/repository/mailrepo"
 	"backend/internal/repository/storagerepo"
+	"backend/internal/scanner"
 	"context"
+	"crypto/rsa"
 	"flag"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -14,24 +17,65 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 
 	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 const port = 8080
 
+// maxDocumentSize is the largest file, in bytes, accepted for a document.
+const maxDocumentSize = 50 * 1024 * 1024
+
+// uploadSessionLifetime is how long an issued document ID may be used to
+// upload a file and submit its metadata.
+const uploadSessionLifetime = 2 * time.Hour
+
 type application struct {
 	DSN          string
 	Domain       string
 	DB           repository.DatabaseRepo
 	Storage      repository.StorageRepo
 	EM           repository.MailRepo
+	Scanner      scanner.Scanner
 	auth         Auth
 	JWTSecret    string
 	JWTIssuer    string
 	JWTAudience  string
 	CookieDomain string
+
+	// APIURL is the public URL of the API, used in links given to other systems
+	APIURL string
+
+	// TrashRetention is how long deleted documents can be restored before they are purged
+	TrashRetention time.Duration
+
+	// OAIAdminEmail is the contact address given to metadata harvesters
+	OAIAdminEmail string
+
+	// LTIKey signs the messages sent to LTI platforms
+	LTIKey *rsa.PrivateKey
+	// LTIPickerURL is the frontend page where teachers choose documents for their course
+	LTIPickerURL string
+	// ltiKeySets caches the keys LTI platforms sign launches with
+	ltiKeySets *lti.KeySets
+
+	// validationQueue holds the IDs of uploaded documents waiting for file validation
+	validationQueue chan primitive.ObjectID
+	// validationJobs holds the IDs of documents queued or being validated
+	validationJobs jobSet
+	// scanQueue holds the IDs of uploaded documents waiting for a malware scan
+	scanQueue chan primitive.ObjectID
+	// scanJobs holds the IDs of documents queued or being scanned
+	scanJobs jobSet
+	// extractionQueue holds the IDs of documents whose text needs to be extracted
+	extractionQueue chan primitive.ObjectID
+	// previewQueue holds the IDs of documents whose thumbnail and preview need rendering
+	previewQueue chan primitive.ObjectID
+	// importQueue holds the IDs of bulk imports waiting to be unpacked
+	importQueue chan primitive.ObjectID
 }
 
 func main() {
@@ -51,6 +95,13 @@ func main() {
 	mongoURI := os.Getenv("MONGODB_URI")
 	awsRegion := os.Getenv("AWS_REGION")
 	fromAddress := os.Getenv("FROM_ADDRESS")
+	clamdAddress := os.Getenv("CLAMD_ADDRESS")
+	if clamdAddress == "" {
+		clamdAddress = "tcp://localhost:3310"
+	}
+
+	var scannerKind string
+	var ltiKeyPath string
 
 	// read from command line
 	flag.StringVar(&app.DSN, "dsn", mongoURI, "MongoDB connection string")
@@ -59,8 +110,17 @@ func main() {
 	flag.StringVar(&app.JWTAudience, "jwt-audience", "example.com", "signing audience")
 	flag.StringVar(&app.CookieDomain, "cookie-domain", "localhost", "cookie domain")
 	flag.StringVar(&app.Domain, "domain", "example.com", "domain")
+	flag.StringVar(&scannerKind, "scanner", "clamd", "malware scanner to use (clamd or stub)")
+	flag.StringVar(&clamdAddress, "clamd-address", clamdAddress, "clamd address (tcp://host:port or unix:///path)")
+	flag.DurationVar(&app.TrashRetention, "trash-retention", 30*24*time.Hour, "how long deleted documents are kept in the trash")
+	flag.StringVar(&app.APIURL, "api-url", fmt.Sprintf("http://localhost:%d", port), "public URL of the API, used in links given to other systems")
+	flag.StringVar(&app.OAIAdminEmail, "oai-admin-email", fromAddress, "contact address published by the OAI-PMH endpoint")
+	flag.StringVar(&ltiKeyPath, "lti-key", "", "PEM file with the RSA key that signs LTI messages")
+	flag.StringVar(&app.LTIPickerURL, "lti-picker-url", "http://localhost:3000/lti/select", "frontend page where teachers choose documents for an LMS course")
 	flag.Parse()
 
+	app.APIURL = strings.TrimSuffix(app.APIURL, "/")
+
 	// connect to the database
 	conn, err := app.connectToMongoDB()
 	if err != nil {
@@ -111,6 +171,40 @@ func main() {
 		FromAddress: fromAddress,
 	}
 
+	switch scannerKind {
+	case "clamd":
+		app.Scanner, err = scanner.NewClamdScanner(clamdAddress, 2*time.Minute)
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "stub":
+		log.Println("Using the stub malware scanner, uploads are not really scanned")
+		app.Scanner = scanner.StubScanner{}
+	default:
+		log.Fatalf("unknown scanner %q", scannerKind)
+	}
+
+	// Platforms pin the keyset, so a key that changes on every restart would
+	// break them: without a key the LTI routes are left out altogether
+	if ltiKeyPath != "" {
+		app.LTIKey, err = lti.LoadPrivateKey(ltiKeyPath)
+		if err != nil {
+			log.Fatalf("unable to load LTI key, %v", err)
+		}
+	} else {
+		log.Println("No -lti-key given, LTI is disabled")
+	}
+	app.ltiKeySets = lti.NewKeySets(&http.Client{Timeout: 10 * time.Second})
+
+	app.validationQueue = make(chan primitive.ObjectID, 100)
+	app.scanQueue = make(chan primitive.ObjectID, 100)
+	app.extractionQueue = make(chan primitive.ObjectID, 100)
+	app.previewQueue = make(chan primitive.ObjectID, 100)
+	app.importQueue = make(chan primitive.ObjectID, 100)
+
+	// start background jobs
+	app.startJobs()
+
 	log.Println("Starting application on port", port)
 
 	// start a web server
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"backend/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// documentDetails are the descriptive fields an uploader provides for a document.
+type documentDetails struct {
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Language    string   `json:"language"`
+	Licence     string   `json:"licence"`
+}
+
+// normalize validates the details and brings them into their stored form.
+// The licence and language are optional: a document without a licence is
+// treated as all rights reserved.
+func (d *documentDetails) normalize() error {
+	var err error
+
+	if d.Licence != "" {
+		if d.Licence, err = models.NormalizeLicence(d.Licence); err != nil {
+			return err
+		}
+	}
+
+	if d.Language != "" {
+		if d.Language, err = models.NormalizeLanguage(d.Language); err != nil {
+			return err
+		}
+	}
+
+	if d.Description, err = models.NormalizeDescription(d.Description); err != nil {
+		return err
+	}
+
+	d.Tags, err = models.NormalizeTags(d.Tags)
+	return err
+}
+
+// documentQueryFromRequest reads the search and filter parameters shared by
+// the search endpoints. Tags can be repeated or comma separated.
+func documentQueryFromRequest(r *http.Request) models.DocumentQuery {
+	values := r.URL.Query()
+
+	query := models.DocumentQuery{
+		Text:        strings.TrimSpace(values.Get("q")),
+		Title:       values.Get("title"),
+		Subject:     values.Get("subject"),
+		Grade:       values.Get("grade"),
+		ContentType: values.Get("content_type"),
+		Filename:    strings.TrimSpace(values.Get("filename")),
+	}
+
+	// Malformed bounds are ignored, as if they were not given
+	query.MinSize, _ = strconv.ParseInt(values.Get("min_size"), 10, 64)
+	query.MaxSize, _ = strconv.ParseInt(values.Get("max_size"), 10, 64)
+	query.MinPages, _ = strconv.Atoi(values.Get("min_pages"))
+	query.MaxPages, _ = strconv.Atoi(values.Get("max_pages"))
+
+	var tags []string
+	for _, value := range values["tag"] {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	// Invalid tags cannot match anything, so they are simply left out
+	query.Tags, _ = models.NormalizeTags(tags)
+
+	// Unknown languages and licences are kept as given so they match nothing
+	query.Language = values.Get("language")
+	if language, err := models.NormalizeLanguage(query.Language); err == nil {
+		query.Language = language
+	}
+	query.Licence = values.Get("licence")
+	if licence, err := models.NormalizeLicence(query.Licence); err == nil {
+		query.Licence = licence
+	}
+
+	return query
+}
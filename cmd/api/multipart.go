@@ -0,0 +1,378 @@
+package main
+
+import (
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// minPartSize is the smallest part S3 accepts, except for the last part.
+	minPartSize = 5 * 1024 * 1024
+	// maxPartNumber is the highest part number S3 accepts.
+	maxPartNumber = 10000
+	// maxPartsPerRequest limits how many part URLs are signed in one request.
+	maxPartsPerRequest = 100
+)
+
+// initiateMultipartUpload starts an upload that is sent in parts, so that a
+// dropped connection only loses the part in flight. Like the single presigned
+// POST it issues a document ID that the metadata step completes.
+func (app *application) initiateMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		Filename    string `json:"filename"`
+		ContentType string `json:"content_type"`
+	}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if payload.Filename == "" {
+		app.errorJSON(w, errors.New("filename must be provided"), http.StatusBadRequest)
+		return
+	}
+
+	contentType, err := uploadContentType(payload.Filename, payload.ContentType)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnsupportedMediaType)
+		return
+	}
+
+	documentID := primitive.NewObjectID()
+	objectKey := revisionObjectKey(documentID, 1)
+
+	uploadID, err := app.Storage.CreateMultipartUpload("share2teach", objectKey, contentType)
+	if err != nil {
+		app.errorJSON(w, errors.New("could not start upload"), http.StatusInternalServerError)
+		return
+	}
+
+	session := &models.UploadSession{
+		DocumentID:        documentID,
+		UserID:            userID,
+		Filename:          payload.Filename,
+		ContentType:       contentType,
+		MultipartUploadID: uploadID,
+		CreatedAt:         time.Now(),
+		ExpiresAt:         time.Now().Add(uploadSessionLifetime),
+	}
+
+	err = app.DB.CreateUploadSession(session)
+	if err != nil {
+		log.Printf("Error inserting upload session into MongoDB: %v", err)
+		_ = app.Storage.AbortMultipartUpload("share2teach", objectKey, uploadID)
+		app.errorJSON(w, errors.New("could not start upload"), http.StatusInternalServerError)
+		return
+	}
+
+	response := struct {
+		DocumentID  primitive.ObjectID `json:"document_id"`
+		MinPartSize int64              `json:"min_part_size"`
+		MaxSize     int64              `json:"max_size"`
+		ExpiresAt   time.Time          `json:"expires_at"`
+	}{
+		DocumentID:  documentID,
+		MinPartSize: minPartSize,
+		MaxSize:     maxDocumentSize,
+		ExpiresAt:   session.ExpiresAt,
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, response)
+	if err != nil {
+		return
+	}
+}
+
+// multipartSessionForUser loads the multipart upload session in the URL if it
+// belongs to the authenticated user and has not expired. It writes the error
+// response and returns nil otherwise.
+func (app *application) multipartSessionForUser(w http.ResponseWriter, r *http.Request) *models.UploadSession {
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return nil
+	}
+
+	documentID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid document ID"), http.StatusBadRequest)
+		return nil
+	}
+
+	session, err := app.DB.GetUploadSession(documentID)
+	if err != nil || session.UserID != userID || session.MultipartUploadID == "" {
+		app.errorJSON(w, errors.New("unknown upload"), http.StatusNotFound)
+		return nil
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		app.errorJSON(w, errors.New("upload has expired"), http.StatusGone)
+		return nil
+	}
+
+	return session
+}
+
+// presignUploadParts returns presigned URLs for uploading the requested parts.
+// Each URL only accepts a part of the size it was requested for, and all parts
+// together must fit within the maximum document size. A part can be signed
+// again to retry it after a failure.
+func (app *application) presignUploadParts(w http.ResponseWriter, r *http.Request) {
+	session := app.multipartSessionForUser(w, r)
+	if session == nil {
+		return
+	}
+
+	var payload struct {
+		Parts []struct {
+			PartNumber int32 `json:"part_number"`
+			Size       int64 `json:"size"`
+		} `json:"parts"`
+	}
+
+	err := app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if len(payload.Parts) == 0 || len(payload.Parts) > maxPartsPerRequest {
+		app.errorJSON(w, fmt.Errorf("between 1 and %d parts must be requested", maxPartsPerRequest), http.StatusBadRequest)
+		return
+	}
+
+	// Parts already uploaded count towards the size, unless they are re-sent
+	uploaded, err := app.Storage.ListParts("share2teach", session.DocumentID.Hex(), session.MultipartUploadID)
+	if err != nil {
+		app.errorJSON(w, errors.New("could not list uploaded parts"), http.StatusInternalServerError)
+		return
+	}
+
+	sizes := map[int32]int64{}
+	for _, part := range uploaded {
+		sizes[aws.ToInt32(part.PartNumber)] = aws.ToInt64(part.Size)
+	}
+
+	for _, part := range payload.Parts {
+		if part.PartNumber < 1 || part.PartNumber > maxPartNumber {
+			app.errorJSON(w, fmt.Errorf("part numbers must be between 1 and %d", maxPartNumber), http.StatusBadRequest)
+			return
+		}
+		if part.Size < 1 {
+			app.errorJSON(w, errors.New("the size of every part must be given"), http.StatusBadRequest)
+			return
+		}
+		sizes[part.PartNumber] = part.Size
+	}
+
+	var total int64
+	for _, size := range sizes {
+		total += size
+	}
+	if total > maxDocumentSize {
+		app.errorJSON(w, fmt.Errorf("document exceeds the maximum size of %d bytes", maxDocumentSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	type partURL struct {
+		PartNumber   int32  `json:"part_number"`
+		PresignedURL string `json:"presigned_url"`
+	}
+
+	urls := []partURL{}
+	for _, part := range payload.Parts {
+		request, err := app.Storage.UploadPart("share2teach", session.DocumentID.Hex(), session.MultipartUploadID, part.PartNumber, part.Size, 3600)
+		if err != nil {
+			app.errorJSON(w, fmt.Errorf("error generating presigned URL: %v", err), http.StatusInternalServerError)
+			return
+		}
+
+		urls = append(urls, partURL{PartNumber: part.PartNumber, PresignedURL: request.URL})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, urls)
+	if err != nil {
+		return
+	}
+}
+
+// listUploadedParts reports the parts S3 has received, so a client that lost
+// its connection knows where to resume.
+func (app *application) listUploadedParts(w http.ResponseWriter, r *http.Request) {
+	session := app.multipartSessionForUser(w, r)
+	if session == nil {
+		return
+	}
+
+	parts, err := app.Storage.ListParts("share2teach", session.DocumentID.Hex(), session.MultipartUploadID)
+	if err != nil {
+		app.errorJSON(w, errors.New("could not list uploaded parts"), http.StatusInternalServerError)
+		return
+	}
+
+	type uploadedPart struct {
+		PartNumber int32  `json:"part_number"`
+		ETag       string `json:"etag"`
+		Size       int64  `json:"size"`
+	}
+
+	uploaded := []uploadedPart{}
+	for _, part := range parts {
+		uploaded = append(uploaded, uploadedPart{
+			PartNumber: aws.ToInt32(part.PartNumber),
+			ETag:       aws.ToString(part.ETag),
+			Size:       aws.ToInt64(part.Size),
+		})
+	}
+
+	err = app.writeJSON(w, http.StatusOK, uploaded)
+	if err != nil {
+		return
+	}
+}
+
+// completeMultipartUpload assembles every uploaded part into the document's
+// file. The metadata is then submitted to POST /upload-document as usual.
+func (app *application) completeMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	session := app.multipartSessionForUser(w, r)
+	if session == nil {
+		return
+	}
+
+	objectKey := session.DocumentID.Hex()
+
+	// S3 is the authority on which parts arrived intact
+	parts, err := app.Storage.ListParts("share2teach", objectKey, session.MultipartUploadID)
+	if err != nil {
+		app.errorJSON(w, errors.New("could not list uploaded parts"), http.StatusInternalServerError)
+		return
+	}
+
+	if len(parts) == 0 {
+		app.errorJSON(w, errors.New("no parts have been uploaded"), http.StatusBadRequest)
+		return
+	}
+
+	var size int64
+	for _, part := range parts {
+		size += aws.ToInt64(part.Size)
+	}
+	if size > maxDocumentSize {
+		app.errorJSON(w, fmt.Errorf("document exceeds the maximum size of %d bytes", maxDocumentSize), http.StatusRequestEntityTooLarge)
+		return
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return aws.ToInt32(parts[i].PartNumber) < aws.ToInt32(parts[j].PartNumber) })
+
+	completed := make([]types.CompletedPart, 0, len(parts))
+	for _, part := range parts {
+		completed = append(completed, types.CompletedPart{PartNumber: part.PartNumber, ETag: part.ETag})
+	}
+
+	err = app.Storage.CompleteMultipartUpload("share2teach", objectKey, session.MultipartUploadID, completed)
+	if err != nil {
+		// Most often a part other than the last one is smaller than the S3 minimum
+		app.errorJSON(w, fmt.Errorf("could not assemble the uploaded parts; every part but the last must be at least %d bytes", minPartSize), http.StatusBadRequest)
+		return
+	}
+
+	response := struct {
+		DocumentID primitive.ObjectID `json:"document_id"`
+		Size       int64              `json:"size"`
+	}{
+		DocumentID: session.DocumentID,
+		Size:       size,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+// abortMultipartUpload cancels an upload and discards its parts.
+func (app *application) abortMultipartUpload(w http.ResponseWriter, r *http.Request) {
+	session := app.multipartSessionForUser(w, r)
+	if session == nil {
+		return
+	}
+
+	err := app.Storage.AbortMultipartUpload("share2teach", session.DocumentID.Hex(), session.MultipartUploadID)
+	if err != nil {
+		app.errorJSON(w, errors.New("could not cancel upload"), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.DB.DeleteUploadSession(session.DocumentID)
+	if err != nil {
+		log.Printf("Error deleting upload session: %v", err)
+	}
+
+	response := map[string]string{
+		"message": "Upload cancelled",
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+// abortAbandonedMultipartUploads aborts multipart uploads that were started
+// longer ago than an upload session lasts. S3 keeps, and bills for, the parts
+// of unfinished uploads until they are aborted. Only uploads recorded in an
+// upload session are touched, so uploads by other users of the bucket are
+// left alone.
+func (app *application) abortAbandonedMultipartUploads() error {
+	uploads, err := app.Storage.ListMultipartUploads("share2teach")
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-uploadSessionLifetime)
+	aborted := 0
+	for _, upload := range uploads {
+		if upload.Initiated == nil || upload.Initiated.After(cutoff) {
+			continue
+		}
+
+		documentID, err := primitive.ObjectIDFromHex(aws.ToString(upload.Key))
+		if err != nil {
+			continue
+		}
+		session, err := app.DB.GetUploadSession(documentID)
+		if err != nil || session.MultipartUploadID != aws.ToString(upload.UploadId) {
+			continue
+		}
+
+		err = app.Storage.AbortMultipartUpload("share2teach", aws.ToString(upload.Key), aws.ToString(upload.UploadId))
+		if err != nil {
+			continue
+		}
+		aborted++
+	}
+
+	if aborted > 0 {
+		log.Printf("Aborted %d abandoned multipart uploads", aborted)
+	}
+
+	return nil
+}
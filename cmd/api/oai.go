@@ -0,0 +1,388 @@
+package main
+
+import (
+	"backend/internal/models"
+	"backend/internal/oaipmh"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// oaiPageSize is the number of records on each page of an OAI-PMH list.
+const oaiPageSize = 100
+
+// oaiPMH serves the OAI-PMH 2.0 endpoint through which aggregators harvest
+// the metadata of published documents in Dublin Core. Subjects and grades
+// are exposed as sets and records are dated by their approval, so harvesters
+// can fetch what was approved since their last visit.
+func (app *application) oaiPMH(w http.ResponseWriter, r *http.Request) {
+	var request oaipmh.Request
+	oaiErr := &oaipmh.Error{Code: oaipmh.BadArgument, Message: "the request could not be parsed"}
+	if r.ParseForm() == nil {
+		request, oaiErr = oaipmh.ParseRequest(r.Form)
+	}
+
+	response := oaipmh.NewResponse(app.oaiBaseURL(), r.Form, oaiErr)
+
+	if oaiErr == nil {
+		var err error
+		switch request.Verb {
+		case "Identify":
+			err = app.oaiIdentify(response, app.oaiBaseURL())
+		case "ListMetadataFormats":
+			err = app.oaiListMetadataFormats(response, request)
+		case "ListSets":
+			err = app.oaiListSets(response)
+		case "GetRecord":
+			err = app.oaiGetRecord(response, request)
+		case "ListIdentifiers", "ListRecords":
+			err = app.oaiList(response, request)
+		}
+
+		if err != nil {
+			log.Printf("Error answering OAI-PMH %s request: %v", request.Verb, err)
+			app.errorJSON(w, errors.New("could not answer the harvesting request"), http.StatusInternalServerError)
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	_ = response.Write(w)
+}
+
+// oaiBaseURL returns the public URL of the OAI-PMH endpoint.
+func (app *application) oaiBaseURL() string {
+	return app.APIURL + "/oai"
+}
+
+func (app *application) oaiIdentify(response *oaipmh.Response, baseURL string) error {
+	// Nothing can be older than the first approval
+	earliest := time.Unix(0, 0)
+	first, err := app.DB.HarvestDocuments(models.HarvestQuery{Limit: 1})
+	if err != nil {
+		return err
+	}
+	if len(first) > 0 {
+		earliest = first[0].ModeratedAt
+	}
+
+	response.Identify = &oaipmh.Identify{
+		RepositoryName:    "Share2Teach",
+		BaseURL:           baseURL,
+		ProtocolVersion:   "2.0",
+		AdminEmail:        app.OAIAdminEmail,
+		EarliestDatestamp: oaipmh.Datestamp(earliest),
+		// Unpublished and trashed documents stop being harvested without a
+		// deleted record, so harvesters must not rely on seeing deletions
+		DeletedRecord: "transient",
+		Granularity:   oaipmh.Granularity,
+	}
+
+	return nil
+}
+
+func (app *application) oaiListMetadataFormats(response *oaipmh.Response, request oaipmh.Request) error {
+	if request.Identifier != "" {
+		document, err := app.oaiDocument(request.Identifier)
+		if err != nil {
+			return err
+		}
+		if document == nil {
+			response.Fail(oaipmh.IDDoesNotExist, "no published document has this identifier")
+			return nil
+		}
+	}
+
+	response.ListMetadataFormats = &oaipmh.ListMetadataFormats{
+		MetadataFormats: []oaipmh.MetadataFormat{oaipmh.DublinCoreFormat},
+	}
+
+	return nil
+}
+
+func (app *application) oaiListSets(response *oaipmh.Response) error {
+	sets, _, err := app.oaiSets()
+	if err != nil {
+		return err
+	}
+
+	if len(sets) == 0 {
+		response.Fail(oaipmh.NoSetHierarchy, "no documents have been published yet")
+		return nil
+	}
+
+	response.ListSets = &oaipmh.ListSets{Sets: sets}
+	return nil
+}
+
+func (app *application) oaiGetRecord(response *oaipmh.Response, request oaipmh.Request) error {
+	document, err := app.oaiDocument(request.Identifier)
+	if err != nil {
+		return err
+	}
+	if document == nil {
+		response.Fail(oaipmh.IDDoesNotExist, "no published document has this identifier")
+		return nil
+	}
+
+	if request.MetadataPrefix != oaipmh.DublinCoreFormat.MetadataPrefix {
+		response.Fail(oaipmh.CannotDisseminateFormat, "only oai_dc is supported")
+		return nil
+	}
+
+	response.GetRecord = &oaipmh.GetRecord{
+		Record: app.oaiRecord(*document, map[primitive.ObjectID]string{}),
+	}
+
+	return nil
+}
+
+// oaiList answers ListIdentifiers and ListRecords with a page of documents
+// in order of approval.
+func (app *application) oaiList(response *oaipmh.Response, request oaipmh.Request) error {
+	if request.MetadataPrefix != oaipmh.DublinCoreFormat.MetadataPrefix {
+		response.Fail(oaipmh.CannotDisseminateFormat, "only oai_dc is supported")
+		return nil
+	}
+
+	// Fetch one extra document to find out whether there is another page
+	query := models.HarvestQuery{
+		From:   request.From,
+		Before: request.Before,
+		Limit:  oaiPageSize + 1,
+	}
+
+	if request.Set != "" {
+		_, members, err := app.oaiSets()
+		if err != nil {
+			return err
+		}
+
+		values, ok := members[request.Set]
+		if !ok {
+			response.Fail(oaipmh.NoRecordsMatch, "no published documents are in this set")
+			return nil
+		}
+		if strings.HasPrefix(request.Set, "grade") {
+			query.Grades = values
+		} else {
+			query.Subjects = values
+		}
+	}
+
+	if request.Resumed {
+		lastID, err := primitive.ObjectIDFromHex(request.LastID)
+		if err != nil {
+			response.Fail(oaipmh.BadResumptionToken, "the resumption token is invalid")
+			return nil
+		}
+		query.AfterModeratedAt = request.LastDatestamp
+		query.AfterID = lastID
+	}
+
+	documents, err := app.DB.HarvestDocuments(query)
+	if err != nil {
+		return err
+	}
+
+	if len(documents) == 0 {
+		response.Fail(oaipmh.NoRecordsMatch, "no published documents match the request")
+		return nil
+	}
+
+	var token *oaipmh.ResumptionToken
+	if len(documents) > oaiPageSize {
+		documents = documents[:oaiPageSize]
+		last := documents[len(documents)-1]
+		token = &oaipmh.ResumptionToken{
+			Token:  request.NextToken(len(documents), last.ModeratedAt, last.ID.Hex()),
+			Cursor: request.Cursor,
+		}
+	} else if request.Resumed {
+		// An empty token tells the harvester the list is complete
+		token = &oaipmh.ResumptionToken{Cursor: request.Cursor}
+	}
+
+	if request.Verb == "ListIdentifiers" {
+		headers := make([]oaipmh.Header, 0, len(documents))
+		for _, document := range documents {
+			headers = append(headers, app.oaiHeader(document))
+		}
+		response.ListIdentifiers = &oaipmh.ListIdentifiers{Headers: headers, ResumptionToken: token}
+		return nil
+	}
+
+	records := make([]oaipmh.Record, 0, len(documents))
+	names := map[primitive.ObjectID]string{}
+	for _, document := range documents {
+		records = append(records, app.oaiRecord(document, names))
+	}
+	response.ListRecords = &oaipmh.ListRecords{Records: records, ResumptionToken: token}
+
+	return nil
+}
+
+// oaiIdentifier returns the OAI identifier of a document.
+func (app *application) oaiIdentifier(id primitive.ObjectID) string {
+	return fmt.Sprintf("oai:%s:%s", app.Domain, id.Hex())
+}
+
+// oaiDocument loads the document an OAI identifier names. It returns nil if
+// there is no such document or it cannot be harvested.
+func (app *application) oaiDocument(identifier string) (*models.Document, error) {
+	hex, ok := strings.CutPrefix(identifier, fmt.Sprintf("oai:%s:", app.Domain))
+	if !ok {
+		return nil, nil
+	}
+	id, err := primitive.ObjectIDFromHex(hex)
+	if err != nil {
+		return nil, nil
+	}
+
+	document, err := app.DB.GetDocumentByID(id)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !isPublished(*document) || document.ModeratedAt.IsZero() {
+		return nil, nil
+	}
+
+	return document, nil
+}
+
+// subjectSetSpec and gradeSetSpec return the specs of the sets a subject or
+// grade belongs to. "Grade 10" and "10" are the same grade.
+func subjectSetSpec(subject string) string {
+	return oaipmh.SetSpec("subject", subject)
+}
+
+func gradeSetSpec(grade string) string {
+	return oaipmh.SetSpec("grade", strings.TrimPrefix(strings.ToLower(strings.TrimSpace(grade)), "grade"))
+}
+
+// oaiSets returns the sets of the repository, ordered by spec, and the
+// subjects or grades that make up each of them. The sets "subject" and
+// "grade" hold every document with a subject or grade.
+func (app *application) oaiSets() ([]oaipmh.Set, map[string][]string, error) {
+	values, err := app.DB.GetPublishedSubjectsAndGrades()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	names := map[string]string{}
+	members := map[string][]string{}
+	add := func(parent, parentName, spec, name, value string) {
+		if strings.HasSuffix(spec, ":") {
+			return
+		}
+		if _, ok := names[parent]; !ok {
+			names[parent] = parentName
+		}
+		if _, ok := names[spec]; !ok {
+			names[spec] = name
+		}
+		members[parent] = append(members[parent], value)
+		members[spec] = append(members[spec], value)
+	}
+
+	sort.Strings(values.Subjects)
+	for _, subject := range values.Subjects {
+		add("subject", "Subjects", subjectSetSpec(subject), strings.TrimSpace(subject), subject)
+	}
+	sort.Strings(values.Grades)
+	for _, grade := range values.Grades {
+		spec := gradeSetSpec(grade)
+		add("grade", "Grades", spec, "Grade "+strings.TrimPrefix(spec, "grade:"), grade)
+	}
+
+	sets := make([]oaipmh.Set, 0, len(names))
+	for spec, name := range names {
+		sets = append(sets, oaipmh.Set{SetSpec: spec, SetName: name})
+	}
+	sort.Slice(sets, func(i, j int) bool { return sets[i].SetSpec < sets[j].SetSpec })
+
+	return sets, members, nil
+}
+
+// oaiHeader returns the header of a document's record, dated by its approval.
+func (app *application) oaiHeader(document models.Document) oaipmh.Header {
+	header := oaipmh.Header{
+		Identifier: app.oaiIdentifier(document.ID),
+		Datestamp:  oaipmh.Datestamp(document.ModeratedAt),
+	}
+	if spec := subjectSetSpec(document.Subject); !strings.HasSuffix(spec, ":") {
+		header.SetSpecs = append(header.SetSpecs, spec)
+	}
+	if spec := gradeSetSpec(document.Grade); !strings.HasSuffix(spec, ":") {
+		header.SetSpecs = append(header.SetSpecs, spec)
+	}
+	return header
+}
+
+// oaiRecord describes a document in Dublin Core. names caches the names of
+// uploaders across the records of a page.
+func (app *application) oaiRecord(document models.Document, names map[primitive.ObjectID]string) oaipmh.Record {
+	dc := oaipmh.NewDublinCore()
+
+	dc.Title = []string{document.Title}
+	dc.Identifier = []string{fmt.Sprintf("%s/documents/%s", app.APIURL, document.ID.Hex())}
+	dc.Publisher = []string{"Share2Teach"}
+	dc.Date = []string{document.ID.Timestamp().UTC().Format("2006-01-02")}
+	dc.Type = []string{"Text"}
+	dc.Format = []string{document.ContentType}
+
+	name, ok := names[document.UserID]
+	if !ok {
+		user, err := app.DB.GetUserByID(document.UserID)
+		if err != nil {
+			log.Printf("Error fetching uploader of document %s: %v", document.ID.Hex(), err)
+		} else {
+			name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		}
+		names[document.UserID] = name
+	}
+	if name != "" {
+		dc.Creator = []string{name}
+	}
+
+	if document.Subject != "" {
+		dc.Subject = append(dc.Subject, document.Subject)
+	}
+	if document.Grade != "" {
+		dc.Subject = append(dc.Subject, "Grade "+strings.TrimPrefix(gradeSetSpec(document.Grade), "grade:"))
+	}
+	dc.Subject = append(dc.Subject, document.Tags...)
+
+	if document.Description != "" {
+		dc.Description = []string{document.Description}
+	}
+	if document.Language != "" {
+		dc.Language = []string{document.Language}
+	}
+	if document.Licence != "" {
+		dc.Rights = []string{models.LicenceLabel(document.Licence)}
+	}
+	if document.Attribution != "" {
+		dc.Rights = append(dc.Rights, document.Attribution)
+	}
+	if document.DerivedFrom != nil {
+		dc.Relation = []string{app.oaiIdentifier(*document.DerivedFrom)}
+	}
+
+	return oaipmh.Record{
+		Header:   app.oaiHeader(document),
+		Metadata: oaipmh.Metadata{DublinCore: dc},
+	}
+}
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"backend/internal/models"
+	"backend/internal/preview"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"os"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// previewURLLifetime is how long the presigned thumbnail and preview URLs in
+// search responses stay valid, in seconds.
+const previewURLLifetime = 60 * 60
+
+// documentResult is a document as returned by search, along with links to its
+// thumbnail and first page preview.
+type documentResult struct {
+	models.Document
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	PreviewURL   string `json:"preview_url,omitempty"`
+	// Duplicates is only filled in for moderators
+	Duplicates []duplicateDocument `json:"duplicates,omitempty"`
+}
+
+// placeholderKey is the object key of the placeholder image for a kind of file.
+func placeholderKey(kind string) string {
+	return fmt.Sprintf("previews/placeholders/%s.png", kind)
+}
+
+// withPreviews presigns the thumbnail and preview of every document. Documents
+// without a rendered preview get the placeholder for their file type.
+func (app *application) withPreviews(documents []models.Document) []documentResult {
+	results := make([]documentResult, 0, len(documents))
+	for _, document := range documents {
+		thumbnailKey, previewKey := document.ThumbnailKey, document.PreviewKey
+		if thumbnailKey == "" || previewKey == "" {
+			thumbnailKey = placeholderKey(preview.PlaceholderKind(document.ContentType))
+			previewKey = thumbnailKey
+		}
+
+		result := documentResult{Document: document}
+		if request, err := app.Storage.GetObject("share2teach", thumbnailKey, previewURLLifetime); err == nil {
+			result.ThumbnailURL = request.URL
+		}
+		if request, err := app.Storage.GetObject("share2teach", previewKey, previewURLLifetime); err == nil {
+			result.PreviewURL = request.URL
+		}
+		results = append(results, result)
+	}
+
+	return results
+}
+
+// queuePreview schedules the thumbnail and preview of a document to be (re-)rendered.
+func (app *application) queuePreview(documentID primitive.ObjectID) {
+	select {
+	case app.previewQueue <- documentID:
+	default:
+		log.Printf("Preview queue full, skipping preview of document %s", documentID.Hex())
+	}
+}
+
+// queuePreviewIfChecked queues the previews of a document once its original
+// upload has passed both validation and the malware scan. Both checks call
+// it when they finish, so whichever finishes last queues the previews.
+func (app *application) queuePreviewIfChecked(documentID primitive.ObjectID) {
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil {
+		log.Printf("Error fetching document %s: %v", documentID.Hex(), err)
+		return
+	}
+
+	if document.ValidationStatus == "passed" && document.ScanStatus == "clean" {
+		app.queuePreview(documentID)
+	}
+}
+
+// previewWorker renders the previews of queued documents one at a time.
+func (app *application) previewWorker() {
+	for documentID := range app.previewQueue {
+		if err := app.renderPreviews(documentID); err != nil {
+			log.Printf("Error rendering preview of document %s: %v", documentID.Hex(), err)
+		}
+	}
+}
+
+// renderPreviews renders a thumbnail and a first page preview of the file
+// currently served for a document and stores them next to it in the bucket.
+// Files that cannot be rendered are pointed at the placeholder for their type.
+func (app *application) renderPreviews(documentID primitive.ObjectID) error {
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil {
+		return err
+	}
+
+	file, err := app.downloadRevision(document, "", true)
+	if err != nil {
+		return err
+	}
+	objectKey := file.ObjectKey
+
+	// Untrusted files are only rendered once they were found clean
+	if file.ScanStatus != "clean" {
+		return nil
+	}
+
+	object, err := app.Storage.HeadObject("share2teach", objectKey)
+	if err != nil {
+		return err
+	}
+	contentType := aws.ToString(object.ContentType)
+
+	img, err := app.renderObject(objectKey, contentType)
+	if err != nil {
+		if !errors.Is(err, preview.ErrUnsupported) {
+			log.Printf("Could not render preview of document %s, using placeholder: %v", documentID.Hex(), err)
+		}
+
+		key, err := app.ensurePlaceholder(preview.PlaceholderKind(contentType))
+		if err != nil {
+			return err
+		}
+		return app.setPreviewKeys(documentID, key, key)
+	}
+
+	// Previews are versioned with the object they were rendered from so a new
+	// revision never shows a stale cached image
+	prefix := fmt.Sprintf("previews/%s", objectKey)
+	thumbnailKey := prefix + "/thumbnail.jpg"
+	previewKey := prefix + "/preview.jpg"
+
+	for key, size := range map[string]int{thumbnailKey: preview.ThumbnailSize, previewKey: preview.PreviewSize} {
+		var buf bytes.Buffer
+		if err := preview.EncodeJPEG(&buf, preview.Scale(img, size)); err != nil {
+			return err
+		}
+		if err := app.Storage.WriteObject("share2teach", key, "image/jpeg", bytes.NewReader(buf.Bytes())); err != nil {
+			return err
+		}
+	}
+
+	return app.setPreviewKeys(documentID, thumbnailKey, previewKey)
+}
+
+// renderObject downloads an object and renders its first page.
+func (app *application) renderObject(objectKey, contentType string) (image.Image, error) {
+	tmp, _, err := app.spoolObject(objectKey)
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	return preview.Render(tmp.Name(), contentType)
+}
+
+// ensurePlaceholder uploads the placeholder image for a kind of file unless it
+// is already in the bucket and returns its key.
+func (app *application) ensurePlaceholder(kind string) (string, error) {
+	key := placeholderKey(kind)
+	if _, err := app.Storage.HeadObject("share2teach", key); err == nil {
+		return key, nil
+	}
+
+	content, err := preview.Placeholder(kind)
+	if err != nil {
+		return "", err
+	}
+
+	return key, app.Storage.WriteObject("share2teach", key, "image/png", bytes.NewReader(content))
+}
+
+func (app *application) setPreviewKeys(documentID primitive.ObjectID, thumbnailKey, previewKey string) error {
+	update := bson.M{
+		"$set": bson.M{
+			"thumbnail_key": thumbnailKey,
+			"preview_key":   previewKey,
+		},
+	}
+
+	return app.DB.UpdateDocumentsByID(documentID, update)
+}
+
+// uploadPlaceholders makes sure every placeholder image is in the bucket, so
+// documents that have not been rendered yet still link to an image.
+func (app *application) uploadPlaceholders() {
+	for _, kind := range preview.PlaceholderKinds {
+		if _, err := app.ensurePlaceholder(kind); err != nil {
+			log.Printf("Error uploading %s placeholder: %v", kind, err)
+		}
+	}
+}
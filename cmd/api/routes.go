@@ -45,6 +45,27 @@ func (app *application) routes() http.Handler {
 
 		// Step 2: Route to confirm document upload and store metadata
 		mux.Post("/", app.uploadDocumentMetadata)
+
+		// Alternative to step 1 for uploading large files in resumable parts
+		mux.Route("/multipart", func(mux chi.Router) {
+			mux.Post("/", app.initiateMultipartUpload)
+			mux.Post("/{id}/parts", app.presignUploadParts)
+			mux.Get("/{id}/parts", app.listUploadedParts)
+			mux.Post("/{id}/complete", app.completeMultipartUpload)
+			mux.Delete("/{id}", app.abortMultipartUpload)
+		})
+	})
+
+	// Routes for uploading many documents at once as a ZIP archive
+	mux.Route("/bulk-imports", func(mux chi.Router) {
+		mux.Use(func(next http.Handler) http.Handler {
+			return app.authRequired(next, "educator", "moderator", "admin")
+		})
+
+		mux.Get("/", app.listBulkImports)
+		mux.Post("/", app.createBulkImport)
+		mux.Get("/{id}", app.getBulkImport)
+		mux.Post("/{id}/start", app.startBulkImport)
 	})
 
 	mux.Get("/search", app.searchDocuments)
@@ -58,8 +79,26 @@ func (app *application) routes() http.Handler {
 		mux.Get("/", app.searchDocumentsAdminOrModerator)
 	})
 
+	// Route for exporting the catalogue of documents for reporting
+	mux.Route("/admin-export", func(mux chi.Router) {
+		mux.Use(func(next http.Handler) http.Handler {
+			return app.authRequired(next, "admin", "moderator")
+		})
+
+		mux.Get("/", app.exportCatalogue)
+	})
+
 	mux.Get("/download-document/{id}", app.generatePresignedURLForDownload)
 
+	mux.Get("/popular", app.popularDocuments)
+
+	// Route for harvesting the metadata of published documents over OAI-PMH
+	mux.Get("/oai", app.oaiPMH)
+	mux.Post("/oai", app.oaiPMH)
+
+	// Route for opening a share link, which needs no account
+	mux.Get("/share/{token}", app.openShareLink)
+
 	mux.Get("/faqs", app.FAQs)
 
 	// Route for moderating documents
@@ -69,6 +108,58 @@ func (app *application) routes() http.Handler {
 		})
 
 		mux.Put("/", app.moderateDocument) // Changed from Post to Put
+		mux.Put("/versions/{version}", app.moderateDocumentVersion)
+	})
+
+	// Routes for managing an individual document
+	mux.Route("/documents/{id}", func(mux chi.Router) {
+		mux.Get("/", app.getDocument)
+		mux.Get("/versions", app.listDocumentVersions)
+
+		mux.Group(func(mux chi.Router) {
+			mux.Use(func(next http.Handler) http.Handler {
+				return app.authRequired(next, "educator", "moderator", "admin")
+			})
+
+			mux.Patch("/", app.updateDocument)
+			mux.Delete("/", app.deleteDocument)
+			mux.Post("/restore", app.restoreDocument)
+			mux.Post("/submit", app.submitDocument)
+			mux.Post("/withdraw", app.withdrawDocument)
+			mux.Get("/share-links", app.listShareLinks)
+			mux.Post("/share-links", app.createShareLink)
+			mux.Delete("/share-links/{linkID}", app.revokeShareLink)
+			mux.Get("/status", app.documentStatus)
+			mux.Post("/versions", app.createDocumentVersion)
+		})
+	})
+
+	// Route for listing deleted documents that can still be restored
+	mux.Route("/trash", func(mux chi.Router) {
+		mux.Use(func(next http.Handler) http.Handler {
+			return app.authRequired(next, "educator", "moderator", "admin")
+		})
+
+		mux.Get("/", app.listTrash)
+	})
+
+	// Routes for curated collections of documents. Public collections can be
+	// viewed and downloaded without signing in.
+	mux.Route("/collections", func(mux chi.Router) {
+		mux.Get("/{id}", app.getCollection)
+		mux.Get("/{id}/manifest", app.collectionManifest)
+		mux.Get("/{id}/documents/{documentID}/download", app.downloadCollectionDocument)
+
+		mux.Group(func(mux chi.Router) {
+			mux.Use(func(next http.Handler) http.Handler {
+				return app.authRequired(next, "educator", "moderator", "admin")
+			})
+
+			mux.Get("/", app.listCollections)
+			mux.Post("/", app.createCollection)
+			mux.Put("/{id}", app.updateCollection)
+			mux.Delete("/{id}", app.deleteCollection)
+		})
 	})
 
 	// Route for rating documents
@@ -85,6 +176,51 @@ func (app *application) routes() http.Handler {
 		mux.Post("/", app.reportDocument)
 	})
 
+	// Routes for following educators and subject/grade pairs
+	mux.Route("/follow", func(mux chi.Router) {
+		mux.Use(func(next http.Handler) http.Handler {
+			return app.authRequired(next, "educator", "moderator", "admin")
+		})
+
+		mux.Get("/", app.listFollows)
+		mux.Post("/users/{id}", app.followUser)
+		mux.Delete("/users/{id}", app.unfollowUser)
+		mux.Post("/subjects", app.followSubject)
+		mux.Delete("/subjects", app.unfollowSubject)
+	})
+
+	// Route for the personalised feed of newly approved documents
+	mux.Route("/feed", func(mux chi.Router) {
+		mux.Use(func(next http.Handler) http.Handler {
+			return app.authRequired(next, "educator", "moderator", "admin")
+		})
+
+		mux.Get("/", app.feed)
+	})
+
+	// Routes for using Share2Teach as an LTI 1.3 tool from a learning
+	// management system such as Moodle, served only when a signing key is set
+	if app.LTIKey != nil {
+		mux.Route("/lti", func(mux chi.Router) {
+			mux.Get("/jwks", app.ltiKeySet)
+			mux.Get("/login", app.ltiLogin)
+			mux.Post("/login", app.ltiLogin)
+			mux.Post("/launch", app.ltiLaunch)
+			mux.Get("/deep-linking/{token}", app.getLTIDeepLink)
+			mux.Post("/deep-linking/{token}", app.completeLTIDeepLink)
+
+			mux.Group(func(mux chi.Router) {
+				mux.Use(func(next http.Handler) http.Handler {
+					return app.authRequired(next, "admin")
+				})
+
+				mux.Get("/platforms", app.listLTIPlatforms)
+				mux.Post("/platforms", app.registerLTIPlatform)
+				mux.Delete("/platforms/{id}", app.deleteLTIPlatform)
+			})
+		})
+	}
+
 	mux.Post("/request-reset-password", app.requestPasswordReset)
 
 	mux.Post("/confirm-reset-password", app.verifyPasswordReset)
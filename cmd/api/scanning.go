@@ -0,0 +1,199 @@
+package main
+
+import (
+	"backend/internal/models"
+	"backend/internal/repository/storagerepo"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// queueScan schedules the uploaded files of a document for a malware scan.
+// Documents already queued or being scanned are skipped. When the queue is
+// full the document stays pending and is picked up by the requeue job instead.
+func (app *application) queueScan(documentID primitive.ObjectID) {
+	if !app.scanJobs.add(documentID) {
+		return
+	}
+
+	select {
+	case app.scanQueue <- documentID:
+	default:
+		app.scanJobs.done(documentID)
+		log.Printf("Scan queue full, document %s will be scanned later", documentID.Hex())
+	}
+}
+
+// scanWorker scans queued documents one at a time.
+func (app *application) scanWorker() {
+	for documentID := range app.scanQueue {
+		if err := app.scanDocument(documentID); err != nil {
+			log.Printf("Error scanning document %s: %v", documentID.Hex(), err)
+		}
+		app.scanJobs.done(documentID)
+	}
+}
+
+// requeuePendingScans queues every document that still has a file waiting
+// for a scan, e.g. after a restart, a scanner outage or the upload of a version.
+func (app *application) requeuePendingScans() error {
+	documents, err := app.DB.FindDocumentsAwaitingScan()
+	if err != nil {
+		return err
+	}
+
+	for _, document := range documents {
+		app.queueScan(document.ID)
+	}
+
+	revisions, err := app.DB.FindRevisionsAwaitingScan()
+	if err != nil {
+		return err
+	}
+
+	for _, revision := range revisions {
+		app.queueScan(revision.DocumentID)
+	}
+
+	return nil
+}
+
+// scanDocument scans the files of a document that are still pending: the
+// original upload, whose verdict is recorded on the document, and any later
+// versions, whose verdict is recorded on the revision.
+func (app *application) scanDocument(documentID primitive.ObjectID) error {
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil {
+		return err
+	}
+
+	// Documents uploaded before scanning existed have no status yet
+	if document.ScanStatus == "pending" || document.ScanStatus == "" {
+		update, err := app.scanObject(document.ID.Hex())
+		if err != nil {
+			return err
+		}
+
+		err = app.DB.UpdateDocumentsByID(documentID, update)
+		if err != nil {
+			return err
+		}
+
+		app.queuePreviewIfChecked(documentID)
+	}
+
+	revisions, err := app.DB.GetRevisions(documentID)
+	if err != nil {
+		return err
+	}
+
+	for _, revision := range revisions {
+		if revision.Version <= 1 || (revision.ScanStatus != "" && revision.ScanStatus != "pending") {
+			continue
+		}
+
+		// A version whose upload is still allowed but has not arrived yet is left pending
+		if time.Since(revision.UploadedAt) < revisionUploadLifetime {
+			_, err := app.Storage.HeadObject("share2teach", revision.ObjectKey)
+			if errors.Is(err, storagerepo.ErrObjectNotFound) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		update, err := app.scanObject(revision.ObjectKey)
+		if err != nil {
+			return err
+		}
+
+		err = app.DB.UpdateRevision(documentID, revision.Version, update)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// scanObject streams an object through the malware scanner and returns the
+// update recording the verdict together with the ETag of the object that was
+// scanned. Scanner failures are returned so the file is retried later.
+func (app *application) scanObject(objectKey string) (bson.M, error) {
+	object, err := app.Storage.HeadObject("share2teach", objectKey)
+	if errors.Is(err, storagerepo.ErrObjectNotFound) {
+		// Nothing to scan, validation already reports the missing file
+		return bson.M{"$set": bson.M{"scan_status": "error"}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	body, err := app.Storage.ReadObject("share2teach", objectKey)
+	if errors.Is(err, storagerepo.ErrObjectNotFound) {
+		return bson.M{"$set": bson.M{"scan_status": "error"}}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	result, err := app.Scanner.Scan(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// An object replaced while it was read no longer matches this ETag, so
+	// it is scanned again before it is served
+	etag := strings.Trim(aws.ToString(object.ETag), `"`)
+
+	if !result.Clean {
+		log.Printf("Malware %q found in %s", result.Signature, objectKey)
+		return bson.M{"$set": bson.M{"scan_status": "infected", "scan_signature": result.Signature, "scanned_etag": etag}}, nil
+	}
+
+	return bson.M{"$set": bson.M{"scan_status": "clean", "scanned_etag": etag}}, nil
+}
+
+// errFileChanged is returned when a file was replaced after it was scanned.
+var errFileChanged = errors.New("document file has changed since it was checked and is being checked again")
+
+// checkScannedObject makes sure the object about to be served is the one
+// that was scanned, given its current metadata. A replaced object has its
+// checks run again.
+func (app *application) checkScannedObject(file *models.Revision, object *s3.HeadObjectOutput) error {
+	// Files scanned before ETags were recorded were uploaded with policies
+	// that have long expired, so they cannot have been replaced
+	if file.ScannedETag == "" {
+		return nil
+	}
+
+	if strings.Trim(aws.ToString(object.ETag), `"`) == file.ScannedETag {
+		return nil
+	}
+
+	log.Printf("File %s changed after it was scanned, checking it again", file.ObjectKey)
+
+	var err error
+	update := bson.M{"$set": bson.M{"validation_status": "pending", "scan_status": "pending"}}
+	if file.Version <= 1 {
+		err = app.DB.UpdateDocumentsByID(file.DocumentID, update)
+	} else {
+		err = app.DB.UpdateRevision(file.DocumentID, file.Version, update)
+	}
+	if err != nil {
+		return err
+	}
+
+	app.queueValidation(file.DocumentID)
+	app.queueScan(file.DocumentID)
+
+	return errFileChanged
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"backend/internal/models"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+func (app *application) listShareLinks(w http.ResponseWriter, r *http.Request) {
+	document := app.documentForOwnerOrAdmin(w, r)
+	if document == nil {
+		return
+	}
+
+	links, err := app.DB.GetShareLinksByDocument(document.ID)
+	if err != nil {
+		log.Printf("Error fetching share links: %v", err)
+		app.errorJSON(w, errors.New("could not fetch share links"), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusOK, links)
+	if err != nil {
+		return
+	}
+}
+
+// createShareLink creates a link through which people without an account can
+// download a document, whether or not it is published.
+func (app *application) createShareLink(w http.ResponseWriter, r *http.Request) {
+	document := app.documentForOwnerOrAdmin(w, r)
+	if document == nil {
+		return
+	}
+
+	userID, err := app.userIDFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	var payload struct {
+		ExpiresAt *time.Time `json:"expires_at"`
+		MaxUses   int        `json:"max_uses"`
+	}
+
+	err = app.readJSON(w, r, &payload)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusBadRequest)
+		return
+	}
+
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		app.errorJSON(w, errors.New("expires_at must be in the future"), http.StatusBadRequest)
+		return
+	}
+	if payload.MaxUses < 0 {
+		app.errorJSON(w, errors.New("max_uses must not be negative"), http.StatusBadRequest)
+		return
+	}
+
+	// The file is handed to people outside the platform, so it must have been checked first
+	if document.ValidationStatus == "failed" || document.ScanStatus != "clean" {
+		app.errorJSON(w, errors.New("only documents that have passed validation and the malware scan can be shared"), http.StatusConflict)
+		return
+	}
+	if document.Reported {
+		app.errorJSON(w, errors.New("reported documents cannot be shared until the report is resolved"), http.StatusConflict)
+		return
+	}
+
+	links, err := app.DB.GetShareLinksByDocument(document.ID)
+	if err != nil {
+		log.Printf("Error fetching share links: %v", err)
+		app.errorJSON(w, errors.New("could not create share link"), http.StatusInternalServerError)
+		return
+	}
+	if len(links) >= models.MaxShareLinksPerDocument {
+		app.errorJSON(w, fmt.Errorf("a document can have at most %d share links", models.MaxShareLinksPerDocument), http.StatusConflict)
+		return
+	}
+
+	token, err := models.GenerateShareToken()
+	if err != nil {
+		log.Printf("Error generating share token: %v", err)
+		app.errorJSON(w, errors.New("could not create share link"), http.StatusInternalServerError)
+		return
+	}
+
+	link := &models.ShareLink{
+		ID:         primitive.NewObjectID(),
+		Token:      token,
+		DocumentID: document.ID,
+		UserID:     userID,
+		ExpiresAt:  payload.ExpiresAt,
+		MaxUses:    payload.MaxUses,
+		CreatedAt:  time.Now(),
+	}
+
+	err = app.DB.CreateShareLink(link)
+	if err != nil {
+		log.Printf("Error creating share link: %v", err)
+		app.errorJSON(w, errors.New("could not create share link"), http.StatusInternalServerError)
+		return
+	}
+
+	err = app.writeJSON(w, http.StatusCreated, link)
+	if err != nil {
+		return
+	}
+}
+
+func (app *application) revokeShareLink(w http.ResponseWriter, r *http.Request) {
+	document := app.documentForOwnerOrAdmin(w, r)
+	if document == nil {
+		return
+	}
+
+	linkID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "linkID"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid share link ID"), http.StatusBadRequest)
+		return
+	}
+
+	ok, err := app.DB.RevokeShareLink(document.ID, linkID, time.Now())
+	if err != nil {
+		log.Printf("Error revoking share link: %v", err)
+		app.errorJSON(w, errors.New("could not revoke share link"), http.StatusInternalServerError)
+		return
+	}
+	if !ok {
+		app.errorJSON(w, errors.New("share link not found"), http.StatusNotFound)
+		return
+	}
+
+	response := map[string]string{
+		"message": "Share link revoked",
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
+
+// openShareLink counts a use of a share link and returns the metadata of its
+// document along with a presigned download URL. A use is only counted once
+// the document is known to be downloadable.
+func (app *application) openShareLink(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	link, err := app.DB.GetUsableShareLink(token, time.Now())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			// Expired, used up and revoked links look the same as unknown ones
+			app.errorJSON(w, errors.New("share link not found or no longer valid"), http.StatusNotFound)
+			return
+		}
+		log.Printf("Error fetching share link: %v", err)
+		app.errorJSON(w, errors.New("could not open share link"), http.StatusInternalServerError)
+		return
+	}
+
+	// Reported documents are not shared while the report is open
+	document, err := app.DB.GetDocumentByID(link.DocumentID)
+	if err != nil || document.DeletedAt != nil || document.ValidationStatus == "failed" || document.Reported {
+		app.errorJSON(w, errors.New("share link not found or no longer valid"), http.StatusNotFound)
+		return
+	}
+
+	file, err := app.downloadRevision(document, "", true)
+	if err != nil {
+		log.Printf("Error resolving file of document %s: %v", document.ID.Hex(), err)
+		app.errorJSON(w, errors.New("could not open share link"), http.StatusInternalServerError)
+		return
+	}
+
+	if file.ScanStatus != "clean" {
+		app.errorJSON(w, errors.New("share link not found or no longer valid"), http.StatusNotFound)
+		return
+	}
+
+	presignedRequest, err := app.presignDownload(document, file)
+	if errors.Is(err, errFileChanged) {
+		app.errorJSON(w, err, http.StatusConflict)
+		return
+	}
+	if err != nil {
+		app.errorJSON(w, fmt.Errorf("error generating presigned URL: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	// The link may have been used up or revoked since it was read
+	_, err = app.DB.UseShareLink(token, time.Now())
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			app.errorJSON(w, errors.New("share link not found or no longer valid"), http.StatusNotFound)
+			return
+		}
+		log.Printf("Error using share link: %v", err)
+		app.errorJSON(w, errors.New("could not open share link"), http.StatusInternalServerError)
+		return
+	}
+
+	app.recordDownload(w, r, document, downloadSourceShareLink)
+
+	// Only descriptive metadata is shown to people outside the platform
+	response := struct {
+		DocumentID       primitive.ObjectID `json:"document_id"`
+		Title            string             `json:"title"`
+		Description      string             `json:"description"`
+		Subject          string             `json:"subject"`
+		Grade            string             `json:"grade"`
+		Language         string             `json:"language"`
+		Licence          string             `json:"licence"`
+		Attribution      string             `json:"attribution,omitempty"`
+		OriginalFilename string             `json:"original_filename"`
+		ContentType      string             `json:"content_type"`
+		Size             int64              `json:"size"`
+		PageCount        int                `json:"page_count,omitempty"`
+		PresignedURL     string             `json:"presigned_url"`
+	}{
+		DocumentID:       document.ID,
+		Title:            document.Title,
+		Description:      document.Description,
+		Subject:          document.Subject,
+		Grade:            document.Grade,
+		Language:         document.Language,
+		Licence:          document.Licence,
+		Attribution:      document.Attribution,
+		OriginalFilename: document.OriginalFilename,
+		ContentType:      document.ContentType,
+		Size:             document.Size,
+		PageCount:        document.PageCount,
+		PresignedURL:     presignedRequest.URL,
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
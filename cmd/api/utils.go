@@ -1,10 +1,15 @@
 package main
 
 import (
+	"backend/internal/models"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io"
 	"net/http"
+	"strconv"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
 type JSONResponse struct {
@@ -68,4 +73,79 @@ func (app *application) errorJSON(w http.ResponseWriter, err error, status ...in
 	payload.Message = err.Error()
 
 	return app.writeJSON(w, statusCode, payload)
-}
\ No newline at end of file
+}
+
+// userIDFromRequest returns the ID of the authenticated user as an ObjectID.
+func (app *application) userIDFromRequest(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, error) {
+	userIDStr, err := app.auth.GetUserIDFromHeader(w, r)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("error extracting user ID from token: %v", err)
+	}
+
+	userID, err := primitive.ObjectIDFromHex(userIDStr)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("invalid UserID: %v", err)
+	}
+
+	return userID, nil
+}
+
+// userFromRequest returns the ID and role of the authenticated user.
+func (app *application) userFromRequest(w http.ResponseWriter, r *http.Request) (primitive.ObjectID, string, error) {
+	_, claims, err := app.auth.GetTokenFromHeaderAndVerify(w, r)
+	if err != nil {
+		return primitive.NilObjectID, "", fmt.Errorf("error verifying token: %v", err)
+	}
+
+	userID, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return primitive.NilObjectID, "", fmt.Errorf("invalid UserID: %v", err)
+	}
+
+	return userID, claims.Role, nil
+}
+
+// isStaff reports whether a role may see documents regardless of their
+// moderation state.
+func isStaff(role string) bool {
+	return role == "moderator" || role == "admin"
+}
+
+// isPublished reports whether a document is visible to everyone.
+func isPublished(document models.Document) bool {
+	return document.Moderated && document.ApprovalStatus == "approved" && !document.Reported && document.DeletedAt == nil
+}
+
+// canViewDocument applies the visibility rules of search to a single
+// document: everyone sees published documents, moderators and admins also see
+// those awaiting or denied moderation unless their file failed validation,
+// and owners always see their own uploads, including drafts.
+func canViewDocument(document models.Document, userID primitive.ObjectID, role string) bool {
+	switch {
+	case document.DeletedAt != nil:
+		return false
+	case isPublished(document):
+		return true
+	case !userID.IsZero() && document.UserID == userID:
+		return true
+	case document.ApprovalStatus == "draft":
+		return false
+	case isStaff(role):
+		return document.ValidationStatus != "failed"
+	default:
+		return false
+	}
+}
+
+// readLimit reads the "limit" query parameter, falling back to def when it is
+// missing and clamping it to max.
+func (app *application) readLimit(r *http.Request, def, max int) int {
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit <= 0 {
+		return def
+	}
+	if limit > max {
+		return max
+	}
+	return limit
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"backend/internal/extraction"
+	"backend/internal/models"
+	"backend/internal/repository/storagerepo"
+	"backend/internal/validation"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// queueValidation schedules the uploaded files of a document for validation.
+// Documents already queued or being validated are skipped. When the queue is
+// full the document stays pending and is picked up by the requeue job instead.
+func (app *application) queueValidation(documentID primitive.ObjectID) {
+	if !app.validationJobs.add(documentID) {
+		return
+	}
+
+	select {
+	case app.validationQueue <- documentID:
+	default:
+		app.validationJobs.done(documentID)
+		log.Printf("Validation queue full, document %s will be validated later", documentID.Hex())
+	}
+}
+
+// validationWorker validates queued documents one at a time.
+func (app *application) validationWorker() {
+	for documentID := range app.validationQueue {
+		if err := app.validateDocument(documentID); err != nil {
+			log.Printf("Error validating document %s: %v", documentID.Hex(), err)
+		}
+		app.validationJobs.done(documentID)
+	}
+}
+
+// requeuePendingValidations queues every document that still has a file
+// waiting for validation, e.g. after a restart or the upload of a version.
+func (app *application) requeuePendingValidations() error {
+	documents, err := app.DB.FindDocumentsByValidationStatus("pending")
+	if err != nil {
+		return err
+	}
+
+	for _, document := range documents {
+		app.queueValidation(document.ID)
+	}
+
+	revisions, err := app.DB.FindRevisionsAwaitingValidation()
+	if err != nil {
+		return err
+	}
+
+	for _, revision := range revisions {
+		app.queueValidation(revision.DocumentID)
+	}
+
+	return nil
+}
+
+// validateDocument validates the files of a document that are still pending:
+// the original upload, whose outcome is recorded on the document, and any
+// later versions, whose outcome is recorded on the revision.
+func (app *application) validateDocument(documentID primitive.ObjectID) error {
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil {
+		return err
+	}
+
+	if document.ValidationStatus == "pending" {
+		err = app.validateOriginal(document)
+		if err != nil {
+			return err
+		}
+	}
+
+	revisions, err := app.DB.GetRevisions(documentID)
+	if err != nil {
+		return err
+	}
+
+	for _, revision := range revisions {
+		if revision.Version <= 1 || (revision.ValidationStatus != "" && revision.ValidationStatus != "pending") {
+			continue
+		}
+
+		err = app.validateRevision(revision)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateOriginal streams the original upload of a document to a temporary
+// file, inspects its content and records the outcome on the document.
+func (app *application) validateOriginal(document *models.Document) error {
+	result, hash, err := app.inspectObject(document.ID.Hex(), document.ContentType)
+	if err != nil {
+		return err
+	}
+
+	status := "passed"
+	if !result.Valid {
+		status = "failed"
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"validation_status": status,
+			"validation_reason": result.Reason,
+			"detected_type":     result.DetectedType,
+		},
+	}
+	if hash != "" {
+		update["$set"].(bson.M)["sha256"] = hash
+	}
+
+	err = app.DB.UpdateDocumentsByID(document.ID, update)
+	if err != nil {
+		return err
+	}
+
+	// Files that passed validation can have their text indexed
+	if result.Valid && extraction.Supported(result.DetectedType) {
+		app.queueExtraction(document.ID)
+	}
+	if result.Valid {
+		app.queuePreviewIfChecked(document.ID)
+	}
+
+	return nil
+}
+
+// validateRevision inspects the file of a later version of a document and
+// records the outcome on the revision. A version whose upload is still
+// allowed but has not arrived yet is left pending.
+func (app *application) validateRevision(revision models.Revision) error {
+	if time.Since(revision.UploadedAt) < revisionUploadLifetime {
+		_, err := app.Storage.HeadObject("share2teach", revision.ObjectKey)
+		if errors.Is(err, storagerepo.ErrObjectNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	result, hash, err := app.inspectObject(revision.ObjectKey, revision.ContentType)
+	if err != nil {
+		return err
+	}
+
+	status := "passed"
+	if !result.Valid {
+		status = "failed"
+	}
+
+	update := bson.M{
+		"$set": bson.M{
+			"validation_status": status,
+			"validation_reason": result.Reason,
+			"detected_type":     result.DetectedType,
+		},
+	}
+	if hash != "" {
+		update["$set"].(bson.M)["sha256"] = hash
+	}
+
+	return app.DB.UpdateRevision(revision.DocumentID, revision.Version, update)
+}
+
+// errObjectTooLarge is returned when spooling objects over the size limit.
+var errObjectTooLarge = errors.New("file is too large")
+
+// spoolObject streams an object to a temporary file so it can be read at
+// random offsets. The caller must close and remove the file.
+func (app *application) spoolObject(objectKey string) (*os.File, int64, error) {
+	return app.spoolObjectUpTo(objectKey, maxDocumentSize)
+}
+
+// spoolObjectUpTo is spoolObject for objects of at most maxBytes bytes.
+func (app *application) spoolObjectUpTo(objectKey string, maxBytes int64) (*os.File, int64, error) {
+	body, err := app.Storage.ReadObject("share2teach", objectKey)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer body.Close()
+
+	tmp, err := os.CreateTemp("", "share2teach-*")
+	if err != nil {
+		return nil, 0, err
+	}
+
+	// Read one byte past the limit to tell an oversized file from one that is exactly at it
+	size, err := io.Copy(tmp, io.LimitReader(body, maxBytes+1))
+	if err == nil && size > maxBytes {
+		err = errObjectTooLarge
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, 0, err
+	}
+
+	return tmp, size, nil
+}
+
+// inspectObject downloads an object, runs it through file validation and
+// hashes its content with SHA-256. The hash is empty when the object could not
+// be read.
+func (app *application) inspectObject(objectKey, declaredType string) (validation.Result, string, error) {
+	tmp, size, err := app.spoolObject(objectKey)
+	if errors.Is(err, storagerepo.ErrObjectNotFound) {
+		return validation.Result{Reason: "file is missing from storage"}, "", nil
+	}
+	if errors.Is(err, errObjectTooLarge) {
+		return validation.Result{Reason: fmt.Sprintf("file exceeds the maximum size of %d bytes", maxDocumentSize)}, "", nil
+	}
+	if err != nil {
+		return validation.Result{}, "", err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+
+	hash, err := fileSHA256(tmp, size)
+	if err != nil {
+		return validation.Result{}, "", err
+	}
+
+	return validation.Inspect(tmp, size, declaredType), hash, nil
+}
+
+func (app *application) documentStatus(w http.ResponseWriter, r *http.Request) {
+	userID, role, err := app.userFromRequest(w, r)
+	if err != nil {
+		app.errorJSON(w, err, http.StatusUnauthorized)
+		return
+	}
+
+	documentID, err := primitive.ObjectIDFromHex(chi.URLParam(r, "id"))
+	if err != nil {
+		app.errorJSON(w, errors.New("invalid document ID"), http.StatusBadRequest)
+		return
+	}
+
+	document, err := app.DB.GetDocumentByID(documentID)
+	if err != nil {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return
+	}
+
+	if document.UserID != userID && !isStaff(role) {
+		app.errorJSON(w, errors.New("document not found"), http.StatusNotFound)
+		return
+	}
+
+	// Later versions are checked for copies the same way as the original upload
+	hashes := documentHashes(*document)
+	revisions, err := app.DB.GetRevisions(document.ID)
+	if err != nil {
+		log.Printf("Error fetching revisions of document %s: %v", document.ID.Hex(), err)
+	}
+	for _, revision := range revisions {
+		if revision.Version > 1 && revision.SHA256 != "" {
+			hashes[document.ID] = append(hashes[document.ID], revision.SHA256)
+		}
+	}
+
+	// Uploaders are warned about published copies of their file; staff see every copy
+	duplicates, err := app.findFileDuplicates(hashes, !isStaff(role))
+	if err != nil {
+		log.Printf("Error finding duplicates of document %s: %v", document.ID.Hex(), err)
+	}
+
+	response := struct {
+		DocumentID       primitive.ObjectID  `json:"document_id"`
+		ValidationStatus string              `json:"validation_status"`
+		ValidationReason string              `json:"validation_reason,omitempty"`
+		DetectedType     string              `json:"detected_type,omitempty"`
+		ScanStatus       string              `json:"scan_status"`
+		Moderated        bool                `json:"moderated"`
+		ApprovalStatus   string              `json:"approvalStatus"`
+		Duplicates       []duplicateDocument `json:"duplicates,omitempty"`
+	}{
+		DocumentID:       document.ID,
+		ValidationStatus: document.ValidationStatus,
+		ValidationReason: document.ValidationReason,
+		DetectedType:     document.DetectedType,
+		ScanStatus:       document.ScanStatus,
+		Moderated:        document.Moderated,
+		ApprovalStatus:   document.ApprovalStatus,
+		Duplicates:       duplicates[document.ID],
+	}
+
+	err = app.writeJSON(w, http.StatusOK, response)
+	if err != nil {
+		return
+	}
+}
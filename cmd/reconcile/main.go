@@ -0,0 +1,133 @@
+// Command reconcile compares the objects in the share2teach bucket with the
+// documents in MongoDB. It reports objects no document refers to and
+// documents whose files are missing, and deletes the orphaned objects when
+// run with -delete.
+package main
+
+import (
+	"backend/internal/reconcile"
+	"backend/internal/repository/dbrepo"
+	"backend/internal/repository/storagerepo"
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// deleteBatchSize is the most keys S3 accepts in one DeleteObjects request.
+const deleteBatchSize = 1000
+
+func main() {
+	if os.Getenv("RUNNING_IN_DOCKER") != "true" {
+		err := godotenv.Load(".env")
+		if err != nil {
+			log.Println("Error loading .env file")
+		}
+	}
+
+	var dsn, bucket string
+	var grace time.Duration
+	var deleteOrphans bool
+
+	flag.StringVar(&dsn, "dsn", os.Getenv("MONGODB_URI"), "MongoDB connection string")
+	flag.StringVar(&bucket, "bucket", "share2teach", "bucket to reconcile")
+	flag.DurationVar(&grace, "grace", 24*time.Hour, "ignore objects and references younger than this, as their uploads may still be in progress")
+	flag.BoolVar(&deleteOrphans, "delete", false, "delete the orphaned objects instead of only reporting them")
+	flag.Parse()
+
+	client, err := mongo.Connect(context.Background(), options.Client().ApplyURI(dsn))
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer func() {
+		if err := client.Disconnect(context.TODO()); err != nil {
+			log.Printf("Error disconnecting from MongoDB: %v", err)
+		}
+	}()
+
+	db := dbrepo.NewMongoDBRepo(client, "Share2Teach")
+
+	cfg, err := config.LoadDefaultConfig(context.TODO(), config.WithRegion(os.Getenv("AWS_REGION")))
+	if err != nil {
+		log.Fatalf("unable to load AWS SDK config, %v", err)
+	}
+	s3Client := s3.NewFromConfig(cfg)
+	storage := &storagerepo.StorageRepo{
+		S3Client:      s3Client,
+		PresignClient: s3.NewPresignClient(s3Client),
+	}
+
+	// Read the metadata before listing the bucket, so that a document created
+	// in between has its file listed rather than reported as missing
+	documents, err := db.GetAllDocuments()
+	if err != nil {
+		log.Fatalf("Error reading documents: %v", err)
+	}
+	revisions, err := db.GetAllRevisions()
+	if err != nil {
+		log.Fatalf("Error reading revisions: %v", err)
+	}
+	references := reconcile.DocumentReferences(documents, revisions)
+
+	// Archives of bulk imports are removed once the import has run
+	for _, status := range []string{"uploading", "queued", "processing"} {
+		imports, err := db.FindBulkImportsByStatus(status)
+		if err != nil {
+			log.Fatalf("Error reading bulk imports: %v", err)
+		}
+		for _, bulkImport := range imports {
+			references = append(references, reconcile.Reference{Key: bulkImport.ObjectKey, DocumentID: bulkImport.ID, Since: bulkImport.CreatedAt})
+		}
+	}
+
+	objects, err := storage.ListObjects(bucket)
+	if err != nil {
+		log.Fatalf("Error listing bucket %s: %v", bucket, err)
+	}
+
+	// Placeholders are shared by every document without a preview
+	report := reconcile.Compare(objects, references, []string{"previews/placeholders/"}, time.Now().Add(-grace))
+
+	for _, object := range report.OrphanObjects {
+		fmt.Printf("orphan object\t%s\t%d bytes\tlast modified %s\n",
+			aws.ToString(object.Key), aws.ToInt64(object.Size), aws.ToTime(object.LastModified).Format(time.RFC3339))
+	}
+	for _, missing := range report.MissingObjects {
+		fmt.Printf("missing object\t%s\tdocument %s\n", missing.Key, missing.DocumentID.Hex())
+	}
+	fmt.Printf("%d objects, %d documents, %d orphan objects, %d missing objects\n",
+		report.Objects, len(documents), len(report.OrphanObjects), len(report.MissingObjects))
+
+	if !deleteOrphans || len(report.OrphanObjects) == 0 {
+		return
+	}
+
+	var keys []string
+	for _, object := range report.OrphanObjects {
+		keys = append(keys, aws.ToString(object.Key))
+	}
+
+	deleted := 0
+	for start := 0; start < len(keys); start += deleteBatchSize {
+		end := start + deleteBatchSize
+		if end > len(keys) {
+			end = len(keys)
+		}
+
+		err := storage.DeleteObjects(bucket, keys[start:end])
+		if err != nil {
+			log.Fatalf("Error deleting orphan objects after %d were deleted: %v", deleted, err)
+		}
+		deleted += end - start
+	}
+	fmt.Printf("deleted %d orphan objects\n", deleted)
+}
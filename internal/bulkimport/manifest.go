@@ -0,0 +1,130 @@
+// Package bulkimport reads the manifest that describes the files of a bulk
+// upload archive.
+package bulkimport
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// ManifestName is the name of the manifest at the root of an archive.
+const ManifestName = "manifest.csv"
+
+// MaxEntries is the largest number of files a single archive can import.
+const MaxEntries = 500
+
+// requiredColumns must appear in the header row of every manifest.
+var requiredColumns = []string{"filename", "title", "subject", "grade"}
+
+// Entry is one row of a manifest.
+type Entry struct {
+	// Line is the line of the manifest the entry was read from
+	Line        int
+	Filename    string
+	Title       string
+	Subject     string
+	Grade       string
+	Tags        []string
+	Description string
+	Language    string
+	Licence     string
+}
+
+// ParseManifest reads a manifest. The first row names the columns; filename,
+// title, subject and grade are required, while tags (separated by
+// semicolons), description, language and licence are optional.
+func ParseManifest(r io.Reader) ([]Entry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, errors.New("manifest is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("could not read manifest: %v", err)
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		// Spreadsheet programs like to start UTF-8 files with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		columns[name] = i
+	}
+	for _, name := range requiredColumns {
+		if _, ok := columns[name]; !ok {
+			return nil, fmt.Errorf("manifest is missing the %s column", name)
+		}
+	}
+
+	var entries []Entry
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("could not read manifest: %v", err)
+		}
+
+		line, _ := reader.FieldPos(0)
+
+		field := func(name string) string {
+			i, ok := columns[name]
+			if !ok || i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+
+		entry := Entry{
+			Line:        line,
+			Filename:    field("filename"),
+			Title:       field("title"),
+			Subject:     field("subject"),
+			Grade:       field("grade"),
+			Description: field("description"),
+			Language:    field("language"),
+			Licence:     field("licence"),
+		}
+		if tags := field("tags"); tags != "" {
+			for _, tag := range strings.Split(tags, ";") {
+				entry.Tags = append(entry.Tags, strings.TrimSpace(tag))
+			}
+		}
+
+		// Skip blank lines left at the end by spreadsheet exports
+		if strings.TrimSpace(strings.Join(record, "")) == "" {
+			continue
+		}
+
+		entries = append(entries, entry)
+		if len(entries) > MaxEntries {
+			return nil, fmt.Errorf("manifest lists more than %d files", MaxEntries)
+		}
+	}
+
+	if len(entries) == 0 {
+		return nil, errors.New("manifest does not list any files")
+	}
+
+	return entries, nil
+}
+
+// FindFile returns the file of an archive that a manifest filename refers to.
+// Names are matched exactly, ignoring a leading "./".
+func FindFile(archive *zip.Reader, filename string) (*zip.File, bool) {
+	want := path.Clean(strings.TrimPrefix(strings.ReplaceAll(filename, "\\", "/"), "./"))
+	for _, file := range archive.File {
+		if !file.FileInfo().IsDir() && path.Clean(file.Name) == want {
+			return file, true
+		}
+	}
+	return nil, false
+}
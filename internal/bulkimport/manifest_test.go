@@ -0,0 +1,81 @@
+package bulkimport
+
+import (
+	"archive/zip"
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name     string
+		manifest string
+		want     []Entry
+		wantErr  bool
+	}{
+		{
+			name: "required and optional columns",
+			manifest: "\ufeffFilename,Title,Subject,Grade,Tags,Licence\n" +
+				"notes/cells.pdf,Cell structure,Life Sciences,10,biology; cells,CC-BY-4.0\n" +
+				",,,,,\n" +
+				"quiz.docx,\"Quiz, term 1\",Life Sciences,10,,\n",
+			want: []Entry{
+				{Line: 2, Filename: "notes/cells.pdf", Title: "Cell structure", Subject: "Life Sciences", Grade: "10", Tags: []string{"biology", "cells"}, Licence: "CC-BY-4.0"},
+				{Line: 4, Filename: "quiz.docx", Title: "Quiz, term 1", Subject: "Life Sciences", Grade: "10"},
+			},
+		},
+		{
+			name:     "short rows leave missing columns empty",
+			manifest: "filename,title,subject,grade,description\nslides.pptx,Forces,Physics,11\n",
+			want:     []Entry{{Line: 2, Filename: "slides.pptx", Title: "Forces", Subject: "Physics", Grade: "11"}},
+		},
+		{name: "missing column", manifest: "filename,title,subject\na.pdf,A,Maths\n", wantErr: true},
+		{name: "no rows", manifest: "filename,title,subject,grade\n", wantErr: true},
+		{name: "empty", manifest: "", wantErr: true},
+		{name: "too many rows", manifest: "filename,title,subject,grade\n" + strings.Repeat("a.pdf,A,Maths,8\n", MaxEntries+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseManifest(strings.NewReader(tt.manifest))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseManifest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindFile(t *testing.T) {
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range []string{"manifest.csv", "notes/", "notes/cells.pdf"} {
+		if _, err := w.Create(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for filename, want := range map[string]bool{
+		"notes/cells.pdf":    true,
+		"./notes/cells.pdf":  true,
+		`notes\cells.pdf`:    true,
+		"notes":              false,
+		"cells.pdf":          false,
+		"../notes/cells.pdf": false,
+	} {
+		if _, got := FindFile(archive, filename); got != want {
+			t.Errorf("FindFile(%q) found = %v, want %v", filename, got, want)
+		}
+	}
+}
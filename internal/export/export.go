@@ -0,0 +1,48 @@
+// Package export writes tabular data row by row as CSV, JSON Lines or Excel
+// workbooks, so large exports can be streamed without holding them in memory.
+package export
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+// Writer writes the rows of a table. Values are strings, booleans, integers,
+// floats or times; a zero time is written as an empty value.
+type Writer interface {
+	WriteRow(values []any) error
+	// Close finishes the output. It does not close the underlying writer.
+	Close() error
+}
+
+// Format describes an export format.
+type Format struct {
+	ContentType string
+	Extension   string
+	new         func(w io.Writer, header []string) (Writer, error)
+}
+
+// Formats lists the supported formats by name.
+var Formats = map[string]Format{
+	"csv":   {ContentType: "text/csv; charset=utf-8", Extension: "csv", new: NewCSVWriter},
+	"jsonl": {ContentType: "application/x-ndjson", Extension: "jsonl", new: NewJSONLinesWriter},
+	"xlsx":  {ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", Extension: "xlsx", new: NewXLSXWriter},
+}
+
+// NewWriter returns a writer for the named format whose columns are header.
+func NewWriter(format string, w io.Writer, header []string) (Writer, error) {
+	f, ok := Formats[format]
+	if !ok {
+		return nil, fmt.Errorf("unknown export format %q", format)
+	}
+	return f.new(w, header)
+}
+
+// formatTime renders times in UTC as RFC 3339.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
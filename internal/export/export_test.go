@@ -0,0 +1,114 @@
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+var (
+	header = []string{"title", "rating", "approved", "moderated_at"}
+	row    = []any{"=SUM(A1) & <b>", 4.5, true, time.Date(2024, 3, 1, 10, 0, 0, 0, time.UTC)}
+)
+
+func TestCSVWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter("csv", &buf, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "title,rating,approved,moderated_at\n'=SUM(A1) & <b>,4.5,true,2024-03-01T10:00:00Z\n"
+	if buf.String() != want {
+		t.Errorf("CSV = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestJSONLinesWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter("jsonl", &buf, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow([]any{"plain", 0.0, false, time.Time{}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"title":"=SUM(A1) \u0026 \u003cb\u003e","rating":4.5,"approved":true,"moderated_at":"2024-03-01T10:00:00Z"}` + "\n" +
+		`{"title":"plain","rating":0,"approved":false,"moderated_at":null}` + "\n"
+	if buf.String() != want {
+		t.Errorf("JSON Lines = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestXLSXWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter("xlsx", &buf, header)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow(row); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("workbook is not a ZIP archive: %v", err)
+	}
+
+	parts := map[string]string{}
+	for _, file := range archive.File {
+		rc, err := file.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		parts[file.Name] = string(content)
+
+		// Every part must be well-formed XML
+		decoder := xml.NewDecoder(bytes.NewReader(content))
+		for {
+			if _, err := decoder.Token(); err == io.EOF {
+				break
+			} else if err != nil {
+				t.Fatalf("%s is not well-formed: %v", file.Name, err)
+			}
+		}
+	}
+
+	sheet, ok := parts["xl/worksheets/sheet1.xml"]
+	if !ok {
+		t.Fatalf("workbook has parts %v, want a sheet", parts)
+	}
+	for _, want := range []string{"<t xml:space=\"preserve\">title</t>", "=SUM(A1) &amp; &lt;b&gt;", "<v>4.5</v>", `<c t="b"><v>1</v></c>`, "2024-03-01T10:00:00Z"} {
+		if !strings.Contains(sheet, want) {
+			t.Errorf("sheet does not contain %q:\n%s", want, sheet)
+		}
+	}
+}
+
+func TestNewWriterUnknownFormat(t *testing.T) {
+	if _, err := NewWriter("pdf", io.Discard, header); err == nil {
+		t.Error("NewWriter() error = nil, want an error for an unknown format")
+	}
+}
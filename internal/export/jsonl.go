@@ -0,0 +1,62 @@
+package export
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+type jsonLinesWriter struct {
+	w      *bufio.Writer
+	header [][]byte
+}
+
+// NewJSONLinesWriter writes every row as a JSON object on its own line, keyed
+// by the header and in header order.
+func NewJSONLinesWriter(w io.Writer, header []string) (Writer, error) {
+	jw := &jsonLinesWriter{w: bufio.NewWriter(w)}
+	for _, name := range header {
+		key, err := json.Marshal(name)
+		if err != nil {
+			return nil, err
+		}
+		jw.header = append(jw.header, key)
+	}
+	return jw, nil
+}
+
+func (j *jsonLinesWriter) WriteRow(values []any) error {
+	if len(values) != len(j.header) {
+		return fmt.Errorf("row has %d values for %d columns", len(values), len(j.header))
+	}
+
+	j.w.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			j.w.WriteByte(',')
+		}
+		j.w.Write(j.header[i])
+		j.w.WriteByte(':')
+
+		if t, ok := value.(time.Time); ok {
+			if t.IsZero() {
+				value = nil
+			} else {
+				value = formatTime(t)
+			}
+		}
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		j.w.Write(encoded)
+	}
+	_, err := j.w.WriteString("}\n")
+	return err
+}
+
+func (j *jsonLinesWriter) Close() error {
+	return j.w.Flush()
+}
@@ -0,0 +1,132 @@
+// Package extraction pulls plain text out of uploaded documents so that
+// their content can be indexed for search.
+package extraction
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	TypePDF  = "application/pdf"
+	TypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	TypePPTX = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+)
+
+// maxInflatedSize caps how much the compressed streams or archive parts of
+// one file may expand to in total, so a small upload cannot decompress to
+// gigabytes.
+const maxInflatedSize = 64 << 20
+
+// MaxTextSize is the most text kept for a document, in bytes. Text beyond it
+// is dropped.
+const MaxTextSize = 1 << 20
+
+// ErrUnsupportedType is returned for files text cannot be extracted from.
+var ErrUnsupportedType = errors.New("text extraction is not supported for this file type")
+
+// ErrTooLarge is returned for files that decompress to more than can be
+// extracted.
+var ErrTooLarge = errors.New("file decompresses to more data than can be extracted")
+
+// Text is the content extracted from a document.
+type Text struct {
+	// Content is the normalized text of the document.
+	Content string
+	// Pages is the number of pages of a PDF or Word document.
+	Pages int
+	// Slides is the number of slides of a presentation.
+	Slides int
+}
+
+// Supported reports whether text can be extracted from files of contentType.
+func Supported(contentType string) bool {
+	switch contentType {
+	case TypePDF, TypeDOCX, TypePPTX:
+		return true
+	}
+	return false
+}
+
+// Extract reads the text of the document in r.
+func Extract(r io.ReaderAt, size int64, contentType string) (Text, error) {
+	var text Text
+	var err error
+
+	switch contentType {
+	case TypePDF:
+		text, err = extractPDF(r, size)
+	case TypeDOCX:
+		text, err = extractDOCX(r, size)
+	case TypePPTX:
+		text, err = extractPPTX(r, size)
+	default:
+		return Text{}, ErrUnsupportedType
+	}
+	if err != nil {
+		return Text{}, err
+	}
+
+	text.Content = Truncate(Normalize(text.Content), MaxTextSize)
+	return text, nil
+}
+
+// budget is how many more bytes the streams or parts of a file may inflate
+// to. It is shared by all of them, so many small ones cannot add up to more
+// than a single large one.
+type budget struct {
+	left int64
+}
+
+func newBudget() *budget {
+	return &budget{left: maxInflatedSize}
+}
+
+// read reads r to the end, failing with ErrTooLarge once the budget is spent.
+// Data read before any other error is returned along with it.
+func (b *budget) read(r io.Reader) ([]byte, error) {
+	// Read one byte past the budget to tell an oversized stream from one that is exactly at it
+	out, err := io.ReadAll(io.LimitReader(r, b.left+1))
+	if int64(len(out)) > b.left {
+		b.left = 0
+		return nil, ErrTooLarge
+	}
+	b.left -= int64(len(out))
+	return out, err
+}
+
+// Truncate shortens s to at most n bytes without splitting a character.
+func Truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
+
+// Normalize collapses runs of whitespace and drops control characters so
+// that text from different formats is stored the same way.
+func Normalize(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			space = true
+		case unicode.IsControl(r) || r == unicode.ReplacementChar:
+			continue
+		default:
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
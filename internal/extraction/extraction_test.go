@@ -0,0 +1,163 @@
+package extraction
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func buildZip(t *testing.T, parts map[string]string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for name, content := range parts {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func buildPDF(t *testing.T) []byte {
+	t.Helper()
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write([]byte("BT /F1 12 Tf 72 712 Td [(Photo) -20 (synthesis)] TJ T* (in \\(green\\) plants) Tj ET"))
+	_ = zw.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString("1 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >> endobj\n")
+	pdf.WriteString("3 0 obj << /Type /Page /Parent 1 0 R >> endobj\n")
+	pdf.WriteString("4 0 obj << /Type /Page /Parent 1 0 R >> endobj\n")
+	pdf.WriteString(fmt.Sprintf("5 0 obj << /Length %d /Filter /FlateDecode >> stream\n", compressed.Len()))
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream endobj\n")
+	pdf.WriteString("6 0 obj << /Length 30 >> stream\nBT (Term 2 Worksheet) Tj ET\nendstream endobj\n")
+	pdf.WriteString("startxref\n0\n%%EOF\n")
+	return pdf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	docx := buildZip(t, map[string]string{
+		"word/document.xml": `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>Term 2</w:t></w:r><w:r><w:t xml:space="preserve"> Worksheet</w:t></w:r></w:p><w:p><w:r><w:t>Photosynthesis</w:t></w:r></w:p></w:body></w:document>`,
+		"docProps/app.xml":  `<Properties><Pages>3</Pages></Properties>`,
+	})
+	pptx := buildZip(t, map[string]string{
+		"ppt/slides/slide2.xml":  `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Chlorophyll</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/slide1.xml":  `<p:sld xmlns:p="p" xmlns:a="a"><a:p><a:r><a:t>Photosynthesis</a:t></a:r></a:p></p:sld>`,
+		"ppt/slides/_rels/x.xml": `<Relationships/>`,
+	})
+	pdf := buildPDF(t)
+
+	tests := []struct {
+		name        string
+		content     []byte
+		contentType string
+		want        Text
+		wantErr     bool
+	}{
+		{name: "docx", content: docx, contentType: TypeDOCX, want: Text{Content: "Term 2 Worksheet Photosynthesis", Pages: 3}},
+		{name: "pptx", content: pptx, contentType: TypePPTX, want: Text{Content: "Photosynthesis Chlorophyll", Slides: 2}},
+		{name: "pdf", content: pdf, contentType: TypePDF, want: Text{Content: "Photosynthesis in (green) plants Term 2 Worksheet", Pages: 2}},
+		{name: "image", content: []byte("\x89PNG"), contentType: "image/png", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(bytes.NewReader(tt.content), int64(len(tt.content)), tt.contentType)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Extract() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractDecompressionBomb(t *testing.T) {
+	zeros := make([]byte, 1<<20)
+
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	for written := 0; written <= maxInflatedSize; written += len(zeros) {
+		_, _ = zw.Write(zeros)
+	}
+	_ = zw.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	pdf.WriteString(fmt.Sprintf("1 0 obj << /Length %d /Filter /FlateDecode >> stream\n", compressed.Len()))
+	pdf.Write(compressed.Bytes())
+	pdf.WriteString("\nendstream endobj\nstartxref\n0\n%%EOF\n")
+
+	var archive bytes.Buffer
+	w := zip.NewWriter(&archive)
+	f, err := w.Create("word/document.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for written := 0; written <= maxInflatedSize; written += len(zeros) {
+		_, _ = f.Write(zeros)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for contentType, data := range map[string][]byte{TypePDF: pdf.Bytes(), TypeDOCX: archive.Bytes()} {
+		_, err := Extract(bytes.NewReader(data), int64(len(data)), contentType)
+		if !errors.Is(err, ErrTooLarge) {
+			t.Errorf("Extract(%s) error = %v, want ErrTooLarge", contentType, err)
+		}
+	}
+}
+
+func TestExtractManySmallStreams(t *testing.T) {
+	// Each stream stays far below the limit, together they exceed it
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write(make([]byte, 1<<20))
+	_ = zw.Close()
+
+	var pdf bytes.Buffer
+	pdf.WriteString("%PDF-1.4\n")
+	for i := 0; i <= maxInflatedSize>>20; i++ {
+		pdf.WriteString(fmt.Sprintf("%d 0 obj << /Length %d /Filter /FlateDecode >> stream\n", i+1, compressed.Len()))
+		pdf.Write(compressed.Bytes())
+		pdf.WriteString("\nendstream endobj\n")
+	}
+	pdf.WriteString("startxref\n0\n%%EOF\n")
+
+	_, err := Extract(bytes.NewReader(pdf.Bytes()), int64(pdf.Len()), TypePDF)
+	if !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Extract() error = %v, want ErrTooLarge", err)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{s: "short", n: 10, want: "short"},
+		{s: "photosynthesis", n: 5, want: "photo"},
+		// "é" takes two bytes and is not split
+		{s: "café", n: 4, want: "caf"},
+	}
+	for _, tt := range tests {
+		if got := Truncate(tt.s, tt.n); got != tt.want {
+			t.Errorf("Truncate(%q, %d) = %q, want %q", tt.s, tt.n, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package extraction
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// extractDOCX reads the paragraphs of the main Word document part.
+func extractDOCX(r io.ReaderAt, size int64) (Text, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return Text{}, err
+	}
+
+	inflated := newBudget()
+
+	content, err := readPartText(archive, inflated, "word/document.xml", "t", "p")
+	if err != nil {
+		return Text{}, err
+	}
+
+	// Word stores the page count it last rendered in the extended properties
+	pages := 0
+	if raw, err := readPart(archive, inflated, "docProps/app.xml"); err == nil {
+		var props struct {
+			Pages int `xml:"Pages"`
+		}
+		if xml.Unmarshal(raw, &props) == nil {
+			pages = props.Pages
+		}
+	}
+
+	return Text{Content: content, Pages: pages}, nil
+}
+
+// extractPPTX reads the text of every slide in presentation order.
+func extractPPTX(r io.ReaderAt, size int64) (Text, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return Text{}, err
+	}
+
+	type slide struct {
+		number int
+		name   string
+	}
+
+	var slides []slide
+	for _, file := range archive.File {
+		name := file.Name
+		if !strings.HasPrefix(name, "ppt/slides/slide") || !strings.HasSuffix(name, ".xml") {
+			continue
+		}
+		number, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "ppt/slides/slide"), ".xml"))
+		if err != nil {
+			continue
+		}
+		slides = append(slides, slide{number: number, name: name})
+	}
+
+	sort.Slice(slides, func(i, j int) bool { return slides[i].number < slides[j].number })
+
+	inflated := newBudget()
+
+	var b strings.Builder
+	for _, s := range slides {
+		// Text past the limit would be dropped anyway
+		if b.Len() >= MaxTextSize {
+			break
+		}
+
+		content, err := readPartText(archive, inflated, s.name, "t", "p")
+		if err != nil {
+			return Text{}, err
+		}
+		b.WriteString(content)
+		b.WriteString("\n")
+	}
+
+	return Text{Content: b.String(), Slides: len(slides)}, nil
+}
+
+// readPart returns the raw content of a part of the archive, counting it
+// against the inflated budget.
+func readPart(archive *zip.Reader, inflated *budget, name string) ([]byte, error) {
+	for _, file := range archive.File {
+		if file.Name != name {
+			continue
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return inflated.read(rc)
+	}
+	return nil, fmt.Errorf("part %s not found", name)
+}
+
+// readPartText collects the character data of every textElement in an XML
+// part, ending a line at each paragraphElement.
+func readPartText(archive *zip.Reader, inflated *budget, name, textElement, paragraphElement string) (string, error) {
+	raw, err := readPart(archive, inflated, name)
+	if err != nil {
+		return "", err
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(raw))
+
+	var b strings.Builder
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", fmt.Errorf("could not parse %s: %v", name, err)
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if t.Name.Local == textElement {
+				inText = true
+			}
+		case xml.EndElement:
+			if t.Name.Local == textElement {
+				inText = false
+			}
+			if t.Name.Local == paragraphElement {
+				b.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				b.Write(t)
+			}
+		}
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,229 @@
+package extraction
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	pdfPageType  = regexp.MustCompile(`/Type\s*/Page\b`)
+	pdfPageCount = regexp.MustCompile(`/Type\s*/Pages\b[^>]*?/Count\s+(\d+)|/Count\s+(\d+)[^>]*?/Type\s*/Pages\b`)
+	pdfStream    = regexp.MustCompile(`(?s)<<(.*?)>>\s*stream\r?\n`)
+)
+
+// extractPDF is a best-effort extractor for the text shown by PDF content
+// streams. It understands uncompressed and Flate-compressed streams and
+// literal strings drawn with the Tj, TJ, ' and " operators, which covers the
+// worksheets and notes teachers typically export from word processors.
+func extractPDF(r io.ReaderAt, size int64) (Text, error) {
+	raw, err := io.ReadAll(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return Text{}, err
+	}
+
+	inflated := newBudget()
+
+	var b strings.Builder
+	for _, loc := range pdfStream.FindAllSubmatchIndex(raw, -1) {
+		// Text past the limit would be dropped anyway
+		if b.Len() >= MaxTextSize {
+			break
+		}
+
+		dict := raw[loc[2]:loc[3]]
+		start := loc[1]
+		end := bytes.Index(raw[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		data := raw[start : start+end]
+
+		// Images, fonts and other binary streams hold no text
+		if bytes.Contains(dict, []byte("/Subtype")) || bytes.Contains(dict, []byte("/Length1")) {
+			continue
+		}
+
+		if bytes.Contains(dict, []byte("/FlateDecode")) {
+			out, err := inflate(data, inflated)
+			if errors.Is(err, ErrTooLarge) {
+				return Text{}, err
+			}
+			if err != nil {
+				continue
+			}
+			data = out
+		} else if bytes.Contains(dict, []byte("/Filter")) {
+			// Other encodings are not supported
+			continue
+		}
+
+		b.WriteString(contentStreamText(data))
+	}
+
+	return Text{Content: b.String(), Pages: countPDFPages(raw)}, nil
+}
+
+// countPDFPages counts the page objects of a PDF, falling back to the page
+// tree count when the page objects are hidden in compressed object streams.
+func countPDFPages(raw []byte) int {
+	pages := len(pdfPageType.FindAll(raw, -1))
+	if pages > 0 {
+		return pages
+	}
+
+	for _, match := range pdfPageCount.FindAllSubmatch(raw, -1) {
+		count := match[1]
+		if len(count) == 0 {
+			count = match[2]
+		}
+		if n, err := strconv.Atoi(string(count)); err == nil && n > pages {
+			pages = n
+		}
+	}
+
+	return pages
+}
+
+func inflate(data []byte, inflated *budget) ([]byte, error) {
+	zr, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+
+	// Truncated streams still yield the text decoded so far
+	out, err := inflated.read(zr)
+	if len(out) > 0 {
+		return out, nil
+	}
+	return nil, err
+}
+
+// contentStreamText collects the literal strings drawn inside the text
+// objects of a content stream.
+func contentStreamText(data []byte) string {
+	var b strings.Builder
+	var pending []string
+	inText := false
+
+	for i := 0; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '(':
+			s, next := readLiteralString(data, i)
+			if inText {
+				pending = append(pending, s)
+			}
+			i = next
+		case c == '%':
+			// Comment until the end of the line
+			for i < len(data) && data[i] != '\n' && data[i] != '\r' {
+				i++
+			}
+		case isPDFRegular(c):
+			j := i
+			for j < len(data) && isPDFRegular(data[j]) {
+				j++
+			}
+			op := string(data[i:j])
+			i = j - 1
+
+			// Numbers are operands, such as the kerning inside a TJ array
+			if isPDFNumber(op) {
+				continue
+			}
+
+			switch op {
+			case "BT":
+				inText = true
+			case "ET":
+				inText = false
+				b.WriteString("\n")
+			case "Tj", "TJ":
+				b.WriteString(strings.Join(pending, ""))
+				b.WriteString(" ")
+			case "'", "\"", "T*", "Td", "TD":
+				b.WriteString(strings.Join(pending, ""))
+				b.WriteString("\n")
+			}
+			pending = pending[:0]
+		}
+	}
+
+	return b.String()
+}
+
+// isPDFRegular reports whether c can be part of an operator or operand.
+func isPDFRegular(c byte) bool {
+	switch c {
+	case ' ', '\t', '\r', '\n', '\f', 0, '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return false
+	}
+	return true
+}
+
+func isPDFNumber(token string) bool {
+	_, err := strconv.ParseFloat(token, 64)
+	return err == nil
+}
+
+// readLiteralString decodes the PDF literal string starting at data[start],
+// which must be '('. It returns the string and the index of its closing ')'.
+func readLiteralString(data []byte, start int) (string, int) {
+	var b bytes.Buffer
+	depth := 0
+
+	for i := start; i < len(data); i++ {
+		c := data[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				b.WriteByte(c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return b.String(), i
+			}
+			b.WriteByte(c)
+		case '\\':
+			i++
+			if i >= len(data) {
+				return b.String(), i
+			}
+			switch e := data[i]; e {
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// Line continuation
+			default:
+				if e >= '0' && e <= '7' {
+					j := i
+					for j < len(data) && j < i+3 && data[j] >= '0' && data[j] <= '7' {
+						j++
+					}
+					n, _ := strconv.ParseUint(string(data[i:j]), 8, 8)
+					b.WriteByte(byte(n))
+					i = j - 1
+				} else {
+					b.WriteByte(e)
+				}
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return b.String(), len(data)
+}
@@ -0,0 +1,71 @@
+package lti
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// deepLinkingResponseLifetime is how long the platform may take to accept
+// a Deep Linking response.
+const deepLinkingResponseLifetime = 5 * time.Minute
+
+// ContentItem is a link returned to the platform through Deep Linking.
+type ContentItem struct {
+	Type   string            `json:"type"`
+	Title  string            `json:"title,omitempty"`
+	Text   string            `json:"text,omitempty"`
+	URL    string            `json:"url,omitempty"`
+	Custom map[string]string `json:"custom,omitempty"`
+}
+
+// DeepLinkingReturn is what the tool knows about a Deep Linking request once
+// the launch has been validated.
+type DeepLinkingReturn struct {
+	// Issuer and ClientID come from the platform's registration
+	Issuer       string
+	ClientID     string
+	DeploymentID string
+	// Data is passed back to the platform unchanged
+	Data string
+}
+
+// SignDeepLinkingResponse returns the JWT that hands items back to the
+// platform. It is posted to the return URL of the request as the form field
+// JWT.
+func SignDeepLinkingResponse(key *rsa.PrivateKey, request DeepLinkingReturn, items []ContentItem) (string, error) {
+	nonce := make([]byte, 16)
+	_, err := rand.Read(nonce)
+	if err != nil {
+		return "", err
+	}
+
+	if items == nil {
+		// No items tells the platform nothing was selected
+		items = []ContentItem{}
+	}
+
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":             request.ClientID,
+		"aud":             request.Issuer,
+		"iat":             now.Unix(),
+		"exp":             now.Add(deepLinkingResponseLifetime).Unix(),
+		"nonce":           base64.RawURLEncoding.EncodeToString(nonce),
+		ClaimMessageType:  DeepLinkingResponse,
+		ClaimVersion:      Version,
+		ClaimDeploymentID: request.DeploymentID,
+		ClaimContentItems: items,
+	}
+	if request.Data != "" {
+		claims[ClaimData] = request.Data
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = KeyID(&key.PublicKey)
+
+	return token.SignedString(key)
+}
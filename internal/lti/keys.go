@@ -0,0 +1,214 @@
+package lti
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// JWK is an RSA public key in JSON Web Key format.
+type JWK struct {
+	KeyType   string `json:"kty"`
+	Use       string `json:"use,omitempty"`
+	Algorithm string `json:"alg,omitempty"`
+	KeyID     string `json:"kid,omitempty"`
+	Modulus   string `json:"n"`
+	Exponent  string `json:"e"`
+}
+
+// KeySet is a JSON Web Key Set.
+type KeySet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// NewJWK describes an RSA public key used to sign with RS256.
+func NewJWK(key *rsa.PublicKey) JWK {
+	return JWK{
+		KeyType:   "RSA",
+		Use:       "sig",
+		Algorithm: "RS256",
+		KeyID:     KeyID(key),
+		Modulus:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		Exponent:  base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+// KeyID returns the RFC 7638 thumbprint of an RSA public key, which stays
+// the same for as long as the key does.
+func KeyID(key *rsa.PublicKey) string {
+	e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+	n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+	sum := sha256.Sum256([]byte(`{"e":"` + e + `","kty":"RSA","n":"` + n + `"}`))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// PublicKey decodes the RSA public key of a JWK.
+func (k JWK) PublicKey() (*rsa.PublicKey, error) {
+	if k.KeyType != "RSA" {
+		return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+	}
+
+	n, err := base64.RawURLEncoding.DecodeString(k.Modulus)
+	if err != nil {
+		return nil, errors.New("invalid modulus")
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.Exponent)
+	if err != nil || len(e) == 0 || len(e) > 4 {
+		return nil, errors.New("invalid exponent")
+	}
+
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+}
+
+// LoadPrivateKey reads an RSA private key from a PEM file in PKCS #1 or
+// PKCS #8 form.
+func LoadPrivateKey(path string) (*rsa.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("not an RSA private key")
+	}
+	return key, nil
+}
+
+// GenerateKey returns a new key for signing messages to platforms.
+func GenerateKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, 2048)
+}
+
+const (
+	// keySetLifetime is how long a fetched key set is used before it is fetched again.
+	keySetLifetime = time.Hour
+	// keySetRefetchInterval limits refetching a key set for an unknown key ID,
+	// so that forged tokens cannot make the tool hammer a platform.
+	keySetRefetchInterval = time.Minute
+	// maxKeySetSize is the largest key set document read from a platform.
+	maxKeySetSize = 1 << 20
+)
+
+type cachedKeySet struct {
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+// KeySets fetches and caches the key sets platforms publish. It is safe for
+// concurrent use.
+type KeySets struct {
+	client *http.Client
+
+	mu   sync.Mutex
+	sets map[string]*cachedKeySet
+}
+
+// NewKeySets returns an empty cache that fetches key sets with client.
+func NewKeySets(client *http.Client) *KeySets {
+	return &KeySets{client: client, sets: map[string]*cachedKeySet{}}
+}
+
+// Keyfunc returns a function that looks up the key that signed a token in
+// the key set published at url.
+func (k *KeySets) Keyfunc(url string) jwt.Keyfunc {
+	return func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return k.key(url, kid)
+	}
+}
+
+// key returns the key with ID kid from the key set at url, fetching the set
+// when it is stale or does not hold the key yet. Without a key ID a set of
+// a single key is used.
+func (k *KeySets) key(url, kid string) (*rsa.PublicKey, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	set := k.sets[url]
+	now := time.Now()
+	stale := set == nil || now.Sub(set.fetchedAt) > keySetLifetime
+	missing := set != nil && lookupKey(set.keys, kid) == nil && now.Sub(set.fetchedAt) > keySetRefetchInterval
+	if stale || missing {
+		keys, err := k.fetch(url)
+		if err != nil {
+			return nil, err
+		}
+		set = &cachedKeySet{keys: keys, fetchedAt: now}
+		k.sets[url] = set
+	}
+
+	key := lookupKey(set.keys, kid)
+	if key == nil {
+		return nil, fmt.Errorf("no key %q in the platform's key set", kid)
+	}
+	return key, nil
+}
+
+func lookupKey(keys map[string]*rsa.PublicKey, kid string) *rsa.PublicKey {
+	if kid == "" && len(keys) == 1 {
+		for _, key := range keys {
+			return key
+		}
+	}
+	return keys[kid]
+}
+
+// fetch downloads a key set and decodes its RSA signing keys.
+func (k *KeySets) fetch(url string) (map[string]*rsa.PublicKey, error) {
+	resp, err := k.client.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("fetching key set: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetching key set: unexpected status %s", resp.Status)
+	}
+
+	var set KeySet
+	err = json.NewDecoder(io.LimitReader(resp.Body, maxKeySetSize)).Decode(&set)
+	if err != nil {
+		return nil, fmt.Errorf("decoding key set: %w", err)
+	}
+
+	keys := map[string]*rsa.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.KeyType != "RSA" || (jwk.Use != "" && jwk.Use != "sig") {
+			continue
+		}
+		key, err := jwk.PublicKey()
+		if err != nil {
+			continue
+		}
+		keys[jwk.KeyID] = key
+	}
+
+	return keys, nil
+}
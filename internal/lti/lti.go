@@ -0,0 +1,187 @@
+// Package lti implements the tool side of LTI 1.3: validating the id_token
+// of a launch against the platform's published keys and signing the Deep
+// Linking response that returns selected content to the platform.
+package lti
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Version is the LTI version this tool supports.
+const Version = "1.3.0"
+
+// Message types.
+const (
+	ResourceLinkRequest  = "LtiResourceLinkRequest"
+	DeepLinkingRequest   = "LtiDeepLinkingRequest"
+	DeepLinkingResponse  = "LtiDeepLinkingResponse"
+	ResourceLinkItemType = "ltiResourceLink"
+)
+
+// Claim names defined by the LTI and Deep Linking specifications.
+const (
+	ClaimMessageType         = "https://purl.imsglobal.org/spec/lti/claim/message_type"
+	ClaimVersion             = "https://purl.imsglobal.org/spec/lti/claim/version"
+	ClaimDeploymentID        = "https://purl.imsglobal.org/spec/lti/claim/deployment_id"
+	ClaimTargetLinkURI       = "https://purl.imsglobal.org/spec/lti/claim/target_link_uri"
+	ClaimRoles               = "https://purl.imsglobal.org/spec/lti/claim/roles"
+	ClaimResourceLink        = "https://purl.imsglobal.org/spec/lti/claim/resource_link"
+	ClaimContext             = "https://purl.imsglobal.org/spec/lti/claim/context"
+	ClaimCustom              = "https://purl.imsglobal.org/spec/lti/claim/custom"
+	ClaimDeepLinkingSettings = "https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings"
+	ClaimContentItems        = "https://purl.imsglobal.org/spec/lti-dl/claim/content_items"
+	ClaimData                = "https://purl.imsglobal.org/spec/lti-dl/claim/data"
+)
+
+// clockSkew is how far the clocks of a platform and this tool may disagree.
+const clockSkew = time.Minute
+
+// ErrInvalidLaunch is returned for launches that must be refused.
+var ErrInvalidLaunch = errors.New("invalid LTI launch")
+
+// ResourceLink identifies the placement of a link in the platform.
+type ResourceLink struct {
+	ID    string `json:"id"`
+	Title string `json:"title,omitempty"`
+}
+
+// Context is the course a launch comes from.
+type Context struct {
+	ID    string `json:"id"`
+	Label string `json:"label,omitempty"`
+	Title string `json:"title,omitempty"`
+}
+
+// DeepLinkingSettings tells the tool where and what content may be returned.
+type DeepLinkingSettings struct {
+	ReturnURL      string   `json:"deep_link_return_url"`
+	AcceptTypes    []string `json:"accept_types"`
+	AcceptMultiple bool     `json:"accept_multiple,omitempty"`
+	Title          string   `json:"title,omitempty"`
+	Text           string   `json:"text,omitempty"`
+	Data           string   `json:"data,omitempty"`
+}
+
+// Accepts reports whether the platform accepts content items of itemType.
+func (s *DeepLinkingSettings) Accepts(itemType string) bool {
+	for _, accepted := range s.AcceptTypes {
+		if accepted == itemType {
+			return true
+		}
+	}
+	return false
+}
+
+// LaunchClaims are the claims of a launch id_token.
+type LaunchClaims struct {
+	jwt.RegisteredClaims
+	AuthorizedParty string `json:"azp,omitempty"`
+	Nonce           string `json:"nonce"`
+	Name            string `json:"name,omitempty"`
+	Email           string `json:"email,omitempty"`
+
+	MessageType         string               `json:"https://purl.imsglobal.org/spec/lti/claim/message_type"`
+	Version             string               `json:"https://purl.imsglobal.org/spec/lti/claim/version"`
+	DeploymentID        string               `json:"https://purl.imsglobal.org/spec/lti/claim/deployment_id"`
+	TargetLinkURI       string               `json:"https://purl.imsglobal.org/spec/lti/claim/target_link_uri,omitempty"`
+	Roles               []string             `json:"https://purl.imsglobal.org/spec/lti/claim/roles"`
+	ResourceLink        *ResourceLink        `json:"https://purl.imsglobal.org/spec/lti/claim/resource_link,omitempty"`
+	Context             *Context             `json:"https://purl.imsglobal.org/spec/lti/claim/context,omitempty"`
+	Custom              map[string]any       `json:"https://purl.imsglobal.org/spec/lti/claim/custom,omitempty"`
+	DeepLinkingSettings *DeepLinkingSettings `json:"https://purl.imsglobal.org/spec/lti-dl/claim/deep_linking_settings,omitempty"`
+}
+
+// CustomString returns the custom parameter name if the platform sent it as
+// a string.
+func (c *LaunchClaims) CustomString(name string) string {
+	value, _ := c.Custom[name].(string)
+	return value
+}
+
+// instructorRoles are the roles allowed to choose content for a course.
+var instructorRoles = map[string]bool{
+	"http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor":            true,
+	"http://purl.imsglobal.org/vocab/lis/v2/membership#ContentDeveloper":      true,
+	"http://purl.imsglobal.org/vocab/lis/v2/membership#Administrator":         true,
+	"http://purl.imsglobal.org/vocab/lis/v2/institution/person#Administrator": true,
+	"http://purl.imsglobal.org/vocab/lis/v2/system/person#Administrator":      true,
+}
+
+// IsInstructor reports whether the user launching teaches or administers
+// the course.
+func (c *LaunchClaims) IsInstructor() bool {
+	for _, role := range c.Roles {
+		if instructorRoles[role] {
+			return true
+		}
+	}
+	return false
+}
+
+// Registration is what a tool knows about a platform it was registered with.
+type Registration struct {
+	Issuer        string
+	ClientID      string
+	DeploymentIDs []string
+}
+
+// ParseLaunch verifies the signature of a launch id_token with keys and
+// checks that it was issued for registration with the given nonce.
+func ParseLaunch(idToken string, keys jwt.Keyfunc, registration Registration, nonce string) (*LaunchClaims, error) {
+	claims := &LaunchClaims{}
+
+	// Times are checked below, allowing for clock skew
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{"RS256"}), jwt.WithoutClaimsValidation())
+	_, err := parser.ParseWithClaims(idToken, claims, keys)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidLaunch, err)
+	}
+
+	now := time.Now()
+	switch {
+	case claims.ExpiresAt == nil || now.After(claims.ExpiresAt.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: the id_token has expired", ErrInvalidLaunch)
+	case claims.IssuedAt != nil && claims.IssuedAt.After(now.Add(clockSkew)):
+		return nil, fmt.Errorf("%w: the id_token was issued in the future", ErrInvalidLaunch)
+	case claims.Issuer != registration.Issuer:
+		return nil, fmt.Errorf("%w: unexpected issuer", ErrInvalidLaunch)
+	case !claims.VerifyAudience(registration.ClientID, true):
+		return nil, fmt.Errorf("%w: the id_token is not for this tool", ErrInvalidLaunch)
+	case (len(claims.Audience) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != registration.ClientID:
+		return nil, fmt.Errorf("%w: the authorized party is not this tool", ErrInvalidLaunch)
+	case nonce == "" || claims.Nonce != nonce:
+		return nil, fmt.Errorf("%w: the nonce does not match", ErrInvalidLaunch)
+	case claims.Version != Version:
+		return nil, fmt.Errorf("%w: unsupported LTI version %q", ErrInvalidLaunch, claims.Version)
+	case !contains(registration.DeploymentIDs, claims.DeploymentID):
+		return nil, fmt.Errorf("%w: unknown deployment %q", ErrInvalidLaunch, claims.DeploymentID)
+	}
+
+	switch claims.MessageType {
+	case ResourceLinkRequest:
+		if claims.ResourceLink == nil || claims.ResourceLink.ID == "" {
+			return nil, fmt.Errorf("%w: the resource link is missing", ErrInvalidLaunch)
+		}
+	case DeepLinkingRequest:
+		if claims.DeepLinkingSettings == nil || claims.DeepLinkingSettings.ReturnURL == "" {
+			return nil, fmt.Errorf("%w: the deep linking settings are missing", ErrInvalidLaunch)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unsupported message type %q", ErrInvalidLaunch, claims.MessageType)
+	}
+
+	return claims, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,255 @@
+package lti_test
+
+import (
+	"backend/internal/lti"
+	"backend/internal/lti/ltitest"
+	"encoding/json"
+	"errors"
+	"html"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func newPlatform(t *testing.T) *ltitest.Platform {
+	t.Helper()
+	platform, err := ltitest.NewPlatform("share2teach-tool", "deployment-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(platform.Close)
+	return platform
+}
+
+func TestParseLaunch(t *testing.T) {
+	platform := newPlatform(t)
+	keys := lti.NewKeySets(http.DefaultClient)
+
+	t.Run("resource link", func(t *testing.T) {
+		idToken, err := platform.IDToken("nonce-1", lti.ResourceLinkRequest, map[string]any{
+			lti.ClaimCustom: map[string]string{"document_id": "65f1c0ffee0000000000abcd"},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claims, err := lti.ParseLaunch(idToken, keys.Keyfunc(platform.KeySetURL()), platform.Registration(), "nonce-1")
+		if err != nil {
+			t.Fatalf("ParseLaunch() error = %v", err)
+		}
+		if claims.ResourceLink.ID != "link-1" || claims.CustomString("document_id") != "65f1c0ffee0000000000abcd" {
+			t.Errorf("ParseLaunch() = %+v", claims)
+		}
+		if !claims.IsInstructor() {
+			t.Errorf("IsInstructor() = false, want true for %v", claims.Roles)
+		}
+	})
+
+	t.Run("deep linking", func(t *testing.T) {
+		idToken, err := platform.IDToken("nonce-2", lti.DeepLinkingRequest, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		claims, err := lti.ParseLaunch(idToken, keys.Keyfunc(platform.KeySetURL()), platform.Registration(), "nonce-2")
+		if err != nil {
+			t.Fatalf("ParseLaunch() error = %v", err)
+		}
+		settings := claims.DeepLinkingSettings
+		if settings.ReturnURL != platform.DeepLinkReturnURL() || !settings.AcceptMultiple || !settings.Accepts(lti.ResourceLinkItemType) {
+			t.Errorf("ParseLaunch() deep linking settings = %+v", settings)
+		}
+	})
+
+	other, err := ltitest.NewPlatform("share2teach-tool", "deployment-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+
+	tests := []struct {
+		name        string
+		extra       map[string]any
+		nonce       string
+		messageType string
+		sign        func() (string, error)
+	}{
+		{name: "wrong nonce", nonce: "replayed"},
+		{name: "other audience", extra: map[string]any{"aud": "another-tool", "azp": nil}},
+		{name: "other authorized party", extra: map[string]any{"aud": []string{"share2teach-tool", "another-tool"}, "azp": "another-tool"}},
+		{name: "other issuer", extra: map[string]any{"iss": "https://lms.example.com"}},
+		{name: "unknown deployment", extra: map[string]any{lti.ClaimDeploymentID: "deployment-2"}},
+		{name: "expired", extra: map[string]any{"exp": time.Now().Add(-time.Hour).Unix()}},
+		{name: "no expiry", extra: map[string]any{"exp": nil}},
+		{name: "issued in the future", extra: map[string]any{"iat": time.Now().Add(time.Hour).Unix()}},
+		{name: "other version", extra: map[string]any{lti.ClaimVersion: "1.1"}},
+		{name: "missing resource link", extra: map[string]any{lti.ClaimResourceLink: nil}},
+		{name: "missing deep linking settings", messageType: lti.DeepLinkingRequest, extra: map[string]any{lti.ClaimDeepLinkingSettings: nil}},
+		{name: "unsupported message type", messageType: "LtiSubmissionReviewRequest"},
+		{name: "signed by another platform", sign: func() (string, error) {
+			return other.IDToken("nonce", lti.ResourceLinkRequest, map[string]any{"iss": platform.Issuer()})
+		}},
+		{name: "signed with a shared secret", sign: func() (string, error) {
+			token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"iss": platform.Issuer(), "aud": "share2teach-tool", "nonce": "nonce"})
+			return token.SignedString([]byte("secret"))
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			nonce := "nonce"
+			messageType := lti.ResourceLinkRequest
+			if tt.messageType != "" {
+				messageType = tt.messageType
+			}
+
+			var idToken string
+			var err error
+			if tt.sign != nil {
+				idToken, err = tt.sign()
+			} else {
+				idToken, err = platform.IDToken(nonce, messageType, tt.extra)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			expected := nonce
+			if tt.nonce != "" {
+				expected = tt.nonce
+			}
+
+			_, err = lti.ParseLaunch(idToken, keys.Keyfunc(platform.KeySetURL()), platform.Registration(), expected)
+			if !errors.Is(err, lti.ErrInvalidLaunch) {
+				t.Errorf("ParseLaunch() error = %v, want ErrInvalidLaunch", err)
+			}
+		})
+	}
+}
+
+func TestPlatformLoginFlow(t *testing.T) {
+	platform := newPlatform(t)
+
+	values := url.Values{
+		"scope":            {"openid"},
+		"response_type":    {"id_token"},
+		"response_mode":    {"form_post"},
+		"prompt":           {"none"},
+		"client_id":        {platform.ClientID},
+		"redirect_uri":     {"https://tool.example.com/lti/launch"},
+		"state":            {"state-1"},
+		"nonce":            {"nonce-1"},
+		"login_hint":       {"teacher-1"},
+		"lti_message_hint": {"deep_linking"},
+	}
+
+	resp, err := http.Get(platform.AuthLoginURL() + "?" + values.Encode())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("authorization status = %d: %s", resp.StatusCode, body)
+	}
+
+	field := func(name string) string {
+		match := regexp.MustCompile(`name="` + name + `" value="([^"]*)"`).FindSubmatch(body)
+		if match == nil {
+			t.Fatalf("launch form has no %s:\n%s", name, body)
+		}
+		return html.UnescapeString(string(match[1]))
+	}
+
+	if field("state") != "state-1" {
+		t.Errorf("state = %q, want state-1", field("state"))
+	}
+
+	keys := lti.NewKeySets(http.DefaultClient)
+	claims, err := lti.ParseLaunch(field("id_token"), keys.Keyfunc(platform.KeySetURL()), platform.Registration(), "nonce-1")
+	if err != nil {
+		t.Fatalf("ParseLaunch() error = %v", err)
+	}
+	if claims.MessageType != lti.DeepLinkingRequest {
+		t.Errorf("message type = %q, want a Deep Linking request", claims.MessageType)
+	}
+}
+
+func TestSignDeepLinkingResponse(t *testing.T) {
+	platform := newPlatform(t)
+
+	toolKey, err := lti.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	toolKeySet := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewEncoder(w).Encode(lti.KeySet{Keys: []lti.JWK{lti.NewJWK(&toolKey.PublicKey)}})
+	}))
+	defer toolKeySet.Close()
+	platform.ToolKeySetURL = toolKeySet.URL
+
+	items := []lti.ContentItem{{
+		Type:   lti.ResourceLinkItemType,
+		Title:  "Fractions",
+		URL:    "https://tool.example.com/lti/launch",
+		Custom: map[string]string{"document_id": "65f1c0ffee0000000000abcd"},
+	}}
+	response, err := lti.SignDeepLinkingResponse(toolKey, lti.DeepLinkingReturn{
+		Issuer:       platform.Issuer(),
+		ClientID:     platform.ClientID,
+		DeploymentID: platform.DeploymentID,
+		Data:         "opaque-platform-data",
+	}, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.PostForm(platform.DeepLinkReturnURL(), url.Values{"JWT": {response}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("platform refused the response: %s", body)
+	}
+
+	received := platform.Received()
+	if len(received) != 1 {
+		t.Fatalf("platform received %d responses, want 1", len(received))
+	}
+	if received[0][lti.ClaimData] != "opaque-platform-data" {
+		t.Errorf("data = %v, want it passed back", received[0][lti.ClaimData])
+	}
+	contentItems, _ := received[0][lti.ClaimContentItems].([]any)
+	if len(contentItems) != 1 {
+		t.Fatalf("content items = %v, want 1", received[0][lti.ClaimContentItems])
+	}
+	if item := contentItems[0].(map[string]any); item["title"] != "Fractions" || item["type"] != lti.ResourceLinkItemType {
+		t.Errorf("content item = %v", item)
+	}
+}
+
+func TestJWKRoundTrip(t *testing.T) {
+	key, err := lti.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	jwk := lti.NewJWK(&key.PublicKey)
+	public, err := jwk.PublicKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !public.Equal(&key.PublicKey) {
+		t.Error("PublicKey() does not match the encoded key")
+	}
+	if jwk.KeyID != lti.KeyID(public) {
+		t.Errorf("KeyID() = %q, want the thumbprint %q", lti.KeyID(public), jwk.KeyID)
+	}
+}
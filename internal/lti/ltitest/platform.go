@@ -0,0 +1,251 @@
+// Package ltitest runs a mock LTI 1.3 platform, standing in for a learning
+// management system such as Moodle in tests and local development.
+package ltitest
+
+import (
+	"backend/internal/lti"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+)
+
+// Platform is a mock platform serving its key set, an OIDC authorization
+// endpoint and a Deep Linking return endpoint over HTTP.
+type Platform struct {
+	ClientID     string
+	DeploymentID string
+	Key          *rsa.PrivateKey
+	// ToolKeySetURL is where the tool publishes the keys that Deep Linking
+	// responses are verified with
+	ToolKeySetURL string
+
+	server   *httptest.Server
+	toolKeys *lti.KeySets
+
+	mu       sync.Mutex
+	received []jwt.MapClaims
+}
+
+// NewPlatform starts a platform that has registered a tool as clientID in
+// deploymentID. Close stops it.
+func NewPlatform(clientID, deploymentID string) (*Platform, error) {
+	key, err := lti.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Platform{
+		ClientID:     clientID,
+		DeploymentID: deploymentID,
+		Key:          key,
+		toolKeys:     lti.NewKeySets(http.DefaultClient),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/jwks", p.serveKeySet)
+	mux.HandleFunc("/auth", p.authorize)
+	mux.HandleFunc("/deep-linking/return", p.receiveDeepLinkingResponse)
+	p.server = httptest.NewServer(mux)
+
+	return p, nil
+}
+
+// Close shuts the platform down.
+func (p *Platform) Close() {
+	p.server.Close()
+}
+
+// Issuer is the issuer identifier of the platform.
+func (p *Platform) Issuer() string {
+	return p.server.URL
+}
+
+// KeySetURL is where the platform publishes its public keys.
+func (p *Platform) KeySetURL() string {
+	return p.server.URL + "/jwks"
+}
+
+// AuthLoginURL is the OIDC authorization endpoint of the platform.
+func (p *Platform) AuthLoginURL() string {
+	return p.server.URL + "/auth"
+}
+
+// DeepLinkReturnURL is where the platform accepts Deep Linking responses.
+func (p *Platform) DeepLinkReturnURL() string {
+	return p.server.URL + "/deep-linking/return"
+}
+
+// Registration describes the platform as the tool registers it.
+func (p *Platform) Registration() lti.Registration {
+	return lti.Registration{Issuer: p.Issuer(), ClientID: p.ClientID, DeploymentIDs: []string{p.DeploymentID}}
+}
+
+// IDToken signs a launch id_token of messageType for a teacher. Claims in
+// extra are added to, or with a nil value removed from, the defaults.
+func (p *Platform) IDToken(nonce, messageType string, extra map[string]any) (string, error) {
+	now := time.Now()
+	claims := jwt.MapClaims{
+		"iss":                 p.Issuer(),
+		"aud":                 p.ClientID,
+		"azp":                 p.ClientID,
+		"sub":                 "teacher-1",
+		"name":                "Thandi Mokoena",
+		"iat":                 now.Unix(),
+		"exp":                 now.Add(5 * time.Minute).Unix(),
+		"nonce":               nonce,
+		lti.ClaimMessageType:  messageType,
+		lti.ClaimVersion:      lti.Version,
+		lti.ClaimDeploymentID: p.DeploymentID,
+		lti.ClaimRoles:        []string{"http://purl.imsglobal.org/vocab/lis/v2/membership#Instructor"},
+		lti.ClaimContext:      map[string]any{"id": "course-1", "label": "MATH10", "title": "Mathematics Grade 10"},
+	}
+
+	switch messageType {
+	case lti.ResourceLinkRequest:
+		claims[lti.ClaimResourceLink] = map[string]any{"id": "link-1", "title": "Week 1 reading"}
+	case lti.DeepLinkingRequest:
+		claims[lti.ClaimDeepLinkingSettings] = map[string]any{
+			"deep_link_return_url": p.DeepLinkReturnURL(),
+			"accept_types":         []string{lti.ResourceLinkItemType},
+			"accept_multiple":      true,
+			"data":                 "opaque-platform-data",
+		}
+	}
+
+	for name, value := range extra {
+		if value == nil {
+			delete(claims, name)
+			continue
+		}
+		claims[name] = value
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = lti.KeyID(&p.Key.PublicKey)
+	return token.SignedString(p.Key)
+}
+
+func (p *Platform) serveKeySet(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(lti.KeySet{Keys: []lti.JWK{lti.NewJWK(&p.Key.PublicKey)}})
+}
+
+var launchForm = template.Must(template.New("launch").Parse(`<!DOCTYPE html>
+<html><body onload="document.forms[0].submit()">
+<form method="post" action="{{.RedirectURI}}">
+<input type="hidden" name="id_token" value="{{.IDToken}}">
+<input type="hidden" name="state" value="{{.State}}">
+</form>
+</body></html>
+`))
+
+// authorize answers the authentication request of an OIDC login with a form
+// that posts the launch to the tool. An lti_message_hint of "deep_linking"
+// asks for a Deep Linking launch; "document:<id>" launches a resource link
+// whose custom document_id is id.
+func (p *Platform) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	for name, want := range map[string]string{
+		"scope":         "openid",
+		"response_type": "id_token",
+		"response_mode": "form_post",
+		"prompt":        "none",
+		"client_id":     p.ClientID,
+	} {
+		if r.Form.Get(name) != want {
+			http.Error(w, "invalid "+name, http.StatusBadRequest)
+			return
+		}
+	}
+	for _, name := range []string{"redirect_uri", "state", "nonce", "login_hint"} {
+		if r.Form.Get(name) == "" {
+			http.Error(w, "missing "+name, http.StatusBadRequest)
+			return
+		}
+	}
+
+	messageType := lti.ResourceLinkRequest
+	var extra map[string]any
+	hint := r.Form.Get("lti_message_hint")
+	if hint == "deep_linking" {
+		messageType = lti.DeepLinkingRequest
+	} else if documentID, ok := strings.CutPrefix(hint, "document:"); ok {
+		extra = map[string]any{lti.ClaimCustom: map[string]string{"document_id": documentID}}
+	}
+
+	idToken, err := p.IDToken(r.Form.Get("nonce"), messageType, extra)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = launchForm.Execute(w, map[string]string{
+		"RedirectURI": r.Form.Get("redirect_uri"),
+		"IDToken":     idToken,
+		"State":       r.Form.Get("state"),
+	})
+}
+
+// receiveDeepLinkingResponse verifies a Deep Linking response against the
+// tool's key set and keeps its claims.
+func (p *Platform) receiveDeepLinkingResponse(w http.ResponseWriter, r *http.Request) {
+	claims, err := p.VerifyDeepLinkingResponse(r.FormValue("JWT"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	p.mu.Lock()
+	p.received = append(p.received, claims)
+	p.mu.Unlock()
+
+	w.WriteHeader(http.StatusOK)
+}
+
+// VerifyDeepLinkingResponse checks the signature and addressing of a Deep
+// Linking response and returns its claims.
+func (p *Platform) VerifyDeepLinkingResponse(response string) (jwt.MapClaims, error) {
+	if p.ToolKeySetURL == "" {
+		return nil, errors.New("the tool's key set URL is not configured")
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(response, claims, p.toolKeys.Keyfunc(p.ToolKeySetURL), jwt.WithValidMethods([]string{"RS256"}))
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case claims["iss"] != p.ClientID:
+		return nil, errors.New("the response was not issued by the tool")
+	case !claims.VerifyAudience(p.Issuer(), true):
+		return nil, errors.New("the response is not addressed to this platform")
+	case claims[lti.ClaimMessageType] != lti.DeepLinkingResponse:
+		return nil, errors.New("not a Deep Linking response")
+	case claims[lti.ClaimDeploymentID] != p.DeploymentID:
+		return nil, errors.New("unknown deployment")
+	}
+
+	return claims, nil
+}
+
+// Received returns the claims of the Deep Linking responses posted to the
+// platform so far.
+func (p *Platform) Received() []jwt.MapClaims {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]jwt.MapClaims(nil), p.received...)
+}
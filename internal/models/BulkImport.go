@@ -0,0 +1,37 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// BulkImport tracks a ZIP archive of documents uploaded in one go and the
+// outcome for each file listed in its manifest.
+type BulkImport struct {
+	ID     primitive.ObjectID `json:"_id" bson:"_id"`
+	UserID primitive.ObjectID `json:"user_id" bson:"user_id"`
+	// Status is "uploading", "queued", "processing", "completed" or "failed"
+	Status    string `json:"status" bson:"status"`
+	ObjectKey string `json:"-" bson:"object_key"`
+	// Language and Licence apply to files whose manifest row leaves them out
+	Language string `json:"language" bson:"language"`
+	Licence  string `json:"licence" bson:"licence"`
+	// Submit sends imported documents for review instead of saving drafts
+	Submit      bool               `json:"submit" bson:"submit"`
+	Error       string             `json:"error,omitempty" bson:"error,omitempty"`
+	Results     []BulkImportResult `json:"results" bson:"results"`
+	CreatedAt   time.Time          `json:"created_at" bson:"created_at"`
+	ExpiresAt   time.Time          `json:"-" bson:"expires_at"`
+	CompletedAt time.Time          `json:"completed_at,omitempty" bson:"completed_at,omitempty"`
+}
+
+// BulkImportResult is the outcome of importing one file of a bulk import.
+type BulkImportResult struct {
+	Line     int    `json:"line" bson:"line"`
+	Filename string `json:"filename" bson:"filename"`
+	// Status is "imported" or "failed"
+	Status     string             `json:"status" bson:"status"`
+	DocumentID primitive.ObjectID `json:"document_id,omitempty" bson:"document_id,omitempty"`
+	Error      string             `json:"error,omitempty" bson:"error,omitempty"`
+}
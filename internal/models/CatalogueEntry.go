@@ -0,0 +1,10 @@
+package models
+
+// CatalogueEntry is a document joined with its rating and the name of its
+// uploader, as listed in catalogue exports.
+type CatalogueEntry struct {
+	Document      `bson:",inline"`
+	UploaderName  string  `bson:"uploader_name"`
+	AverageRating float64 `bson:"average_rating"`
+	TimesRated    int     `bson:"times_rated"`
+}
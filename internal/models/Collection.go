@@ -0,0 +1,22 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxCollectionDocuments is the largest number of documents a collection can hold.
+const MaxCollectionDocuments = 100
+
+// Collection is a curated, ordered pack of documents put together by a user.
+type Collection struct {
+	ID          primitive.ObjectID   `json:"_id" bson:"_id"`
+	UserID      primitive.ObjectID   `json:"user_id" bson:"user_id"`
+	Title       string               `json:"title" bson:"title"`
+	Description string               `json:"description" bson:"description"`
+	DocumentIDs []primitive.ObjectID `json:"document_ids" bson:"document_ids"`
+	Public      bool                 `json:"public" bson:"public"`
+	CreatedAt   time.Time            `json:"created_at" bson:"created_at"`
+	UpdatedAt   time.Time            `json:"updated_at" bson:"updated_at"`
+}
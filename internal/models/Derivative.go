@@ -0,0 +1,64 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// MaxLineageDepth bounds how many generations of originals are followed when
+// tracing where a derivative work came from.
+const MaxLineageDepth = 20
+
+// CheckDerivativeLicence reports whether a derivative of a document shared
+// under parentLicence may be shared under licence. NoDerivatives licences
+// forbid remixes, ShareAlike ones require the same licence and NonCommercial
+// ones must stay non-commercial. Originals without a known licence are all
+// rights reserved and cannot be adapted at all.
+func CheckDerivativeLicence(parentLicence, licence string) error {
+	if _, ok := Licences[parentLicence]; !ok {
+		return errors.New("the original has no Creative Commons licence, so derivative works are not allowed")
+	}
+	if strings.Contains(parentLicence, "-ND-") {
+		return fmt.Errorf("the original is licensed under %s, which does not allow derivative works", parentLicence)
+	}
+	if strings.Contains(parentLicence, "-SA-") && licence != parentLicence {
+		return fmt.Errorf("the original is licensed under %s, so derivative works must use the same licence", parentLicence)
+	}
+	if strings.Contains(parentLicence, "-NC-") && !strings.Contains(licence, "-NC-") {
+		return fmt.Errorf("the original is licensed under %s, so derivative works must be non-commercial", parentLicence)
+	}
+	return nil
+}
+
+// LicenceLabel returns the short name of a licence as Creative Commons
+// writes it, e.g. "CC BY-SA 4.0" for "CC-BY-SA-4.0".
+func LicenceLabel(licence string) string {
+	if licence == "CC0-1.0" {
+		return "CC0 1.0"
+	}
+
+	parts := strings.Split(licence, "-")
+	if len(parts) < 3 || parts[0] != "CC" {
+		return licence
+	}
+	return fmt.Sprintf("CC %s %s", strings.Join(parts[1:len(parts)-1], "-"), parts[len(parts)-1])
+}
+
+// Attribution builds the credit line shown on a derivative of parent. The
+// author is left out when unknown.
+func Attribution(parent Document, author string) string {
+	credit := fmt.Sprintf("Based on %q", parent.Title)
+	if author != "" {
+		credit += " by " + author
+	}
+
+	switch parent.Licence {
+	case "":
+		return credit + "."
+	case "CC0-1.0":
+		return credit + ", dedicated to the public domain under CC0 1.0."
+	default:
+		return fmt.Sprintf("%s, licensed under %s.", credit, LicenceLabel(parent.Licence))
+	}
+}
@@ -0,0 +1,61 @@
+package models
+
+import "testing"
+
+func TestCheckDerivativeLicence(t *testing.T) {
+	tests := []struct {
+		parent, licence string
+		wantErr         bool
+	}{
+		{parent: "CC0-1.0", licence: "CC-BY-NC-ND-4.0"},
+		{parent: "CC-BY-4.0", licence: "CC-BY-SA-4.0"},
+		{parent: "CC-BY-ND-4.0", licence: "CC-BY-ND-4.0", wantErr: true},
+		{parent: "CC-BY-NC-ND-4.0", licence: "CC-BY-NC-4.0", wantErr: true},
+		{parent: "CC-BY-SA-4.0", licence: "CC-BY-SA-4.0"},
+		{parent: "CC-BY-SA-4.0", licence: "CC-BY-4.0", wantErr: true},
+		{parent: "CC-BY-NC-4.0", licence: "CC-BY-NC-SA-4.0"},
+		{parent: "CC-BY-NC-4.0", licence: "CC-BY-4.0", wantErr: true},
+		{parent: "", licence: "CC-BY-4.0", wantErr: true},
+		{parent: "GPL-3.0", licence: "CC-BY-4.0", wantErr: true},
+	}
+	for _, tt := range tests {
+		err := CheckDerivativeLicence(tt.parent, tt.licence)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("CheckDerivativeLicence(%q, %q) error = %v, wantErr %v", tt.parent, tt.licence, err, tt.wantErr)
+		}
+	}
+}
+
+func TestAttribution(t *testing.T) {
+	tests := []struct {
+		name   string
+		parent Document
+		author string
+		want   string
+	}{
+		{
+			name:   "licensed",
+			parent: Document{Title: "Fractions worksheet", Licence: "CC-BY-SA-4.0"},
+			author: "Thandi Mokoena",
+			want:   `Based on "Fractions worksheet" by Thandi Mokoena, licensed under CC BY-SA 4.0.`,
+		},
+		{
+			name:   "public domain",
+			parent: Document{Title: "Map", Licence: "CC0-1.0"},
+			author: "Sam",
+			want:   `Based on "Map" by Sam, dedicated to the public domain under CC0 1.0.`,
+		},
+		{
+			name:   "unknown author and licence",
+			parent: Document{Title: "Old notes"},
+			want:   `Based on "Old notes".`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Attribution(tt.parent, tt.author); got != tt.want {
+				t.Errorf("Attribution() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
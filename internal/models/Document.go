@@ -16,4 +16,40 @@ type Document struct {
     Reported       bool               `json:"reported" bson:"reported"`
     RatingID       primitive.ObjectID `json:"rating_id" bson:"rating_id"`
     ApprovalStatus string             `json:"approvalStatus" bson:"approvalStatus"` 
+
+    // Metadata describing the document and how often it is downloaded
+    Description      string   `json:"description" bson:"description"`
+    Tags             []string `json:"tags" bson:"tags"`
+    Language         string   `json:"language" bson:"language"`
+    Licence          string   `json:"licence" bson:"licence"`
+    OriginalFilename string   `json:"original_filename" bson:"original_filename"`
+    PageCount        int      `json:"page_count,omitempty" bson:"page_count,omitempty"`
+    DownloadCount    int      `json:"download_count" bson:"download_count"`
+
+    // ModeratedAt is when the document was last approved or denied
+    ModeratedAt    time.Time `json:"moderated_at" bson:"moderated_at,omitempty"`
+    CurrentVersion int       `json:"current_version" bson:"current_version"`
+    LatestVersion  int       `json:"-" bson:"latest_version,omitempty"`
+
+    // Results of the checks on the original upload
+    Size             int64  `json:"size" bson:"size"`
+    ContentType      string `json:"content_type" bson:"content_type"`
+    ETag             string `json:"etag" bson:"etag"`
+    SHA256           string `json:"sha256,omitempty" bson:"sha256,omitempty"`
+    ValidationStatus string `json:"validation_status" bson:"validation_status"`
+    ValidationReason string `json:"validation_reason,omitempty" bson:"validation_reason,omitempty"`
+    DetectedType     string `json:"detected_type,omitempty" bson:"detected_type,omitempty"`
+    ScanStatus       string `json:"scan_status" bson:"scan_status"`
+    ScanSignature    string `json:"scan_signature,omitempty" bson:"scan_signature,omitempty"`
+    ScannedETag      string `json:"-" bson:"scanned_etag,omitempty"`
+    ThumbnailKey     string `json:"-" bson:"thumbnail_key,omitempty"`
+    PreviewKey       string `json:"-" bson:"preview_key,omitempty"`
+
+    // DerivedFrom is the document this one adapts, credited by Attribution
+    DerivedFrom *primitive.ObjectID `json:"derived_from,omitempty" bson:"derived_from,omitempty"`
+    Attribution string              `json:"attribution,omitempty" bson:"attribution,omitempty"`
+
+    // DeletedAt and DeletedBy are set while the document is in the trash
+    DeletedAt *time.Time          `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+    DeletedBy *primitive.ObjectID `json:"deleted_by,omitempty" bson:"deleted_by,omitempty"`
 }
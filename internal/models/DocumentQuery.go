@@ -0,0 +1,27 @@
+package models
+
+import "go.mongodb.org/mongo-driver/bson/primitive"
+
+// DocumentQuery holds the search and filter parameters for finding documents.
+// Empty fields do not restrict the results.
+type DocumentQuery struct {
+	// Text is matched against the title, description and tags
+	Text        string
+	Title       string
+	Subject     string
+	Grade       string
+	Tags        []string
+	Language    string
+	Licence     string
+	ContentType string
+	// Filename is matched against the original filename
+	Filename string
+	// Ranges are inclusive, zero leaves that end open
+	MinSize  int64
+	MaxSize  int64
+	MinPages int
+	MaxPages int
+	// TextMatches are the documents whose extracted text matches Text. The
+	// repository fills them in, they are not read from the request.
+	TextMatches []primitive.ObjectID
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DocumentText is the plain text extracted from a document's file, stored
+// separately from the metadata so search results stay small.
+type DocumentText struct {
+	DocumentID  primitive.ObjectID `json:"document_id" bson:"_id"`
+	Content     string             `json:"content" bson:"content"`
+	Pages       int                `json:"pages,omitempty" bson:"pages,omitempty"`
+	Slides      int                `json:"slides,omitempty" bson:"slides,omitempty"`
+	ObjectKey   string             `json:"-" bson:"object_key"`
+	ETag        string             `json:"-" bson:"etag"`
+	ExtractedAt time.Time          `json:"extracted_at" bson:"extracted_at"`
+}
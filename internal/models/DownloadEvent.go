@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// DownloadEvent records that a download URL was issued for a document.
+type DownloadEvent struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	DocumentID primitive.ObjectID `json:"document_id" bson:"document_id"`
+	// UserID is empty for anonymous downloads
+	UserID primitive.ObjectID `json:"user_id,omitempty" bson:"user_id,omitempty"`
+	// Subject is copied from the document so popularity can be grouped without a join
+	Subject      string    `json:"subject" bson:"subject"`
+	Source       string    `json:"source" bson:"source"`
+	Client       string    `json:"client" bson:"client"`
+	DownloadedAt time.Time `json:"downloaded_at" bson:"downloaded_at"`
+}
+
+// PopularDocument is a document with the number of downloads in a period.
+type PopularDocument struct {
+	DocumentID primitive.ObjectID `json:"document_id" bson:"document_id"`
+	Downloads  int                `json:"downloads" bson:"downloads"`
+}
+
+// SubjectPopularity lists the most downloaded documents of a subject.
+type SubjectPopularity struct {
+	Subject   string            `json:"subject" bson:"_id"`
+	Documents []PopularDocument `json:"documents" bson:"documents"`
+}
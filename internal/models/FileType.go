@@ -0,0 +1,82 @@
+package models
+
+import (
+	"path/filepath"
+	"strings"
+	"unicode"
+)
+
+// AllowedContentTypes maps the file extensions accepted for documents to
+// their MIME types.
+var AllowedContentTypes = map[string]string{
+	".pdf":  "application/pdf",
+	".docx": "application/vnd.openxmlformats-officedocument.wordprocessingml.document",
+	".pptx": "application/vnd.openxmlformats-officedocument.presentationml.presentation",
+	".xlsx": "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	".png":  "image/png",
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// ExtensionForContentType returns the preferred file extension for an allowed
+// MIME type, or an empty string for unknown types.
+func ExtensionForContentType(contentType string) string {
+	if contentType == "image/jpeg" {
+		return ".jpg"
+	}
+	for ext, allowed := range AllowedContentTypes {
+		if allowed == contentType {
+			return ext
+		}
+	}
+	return ""
+}
+
+// maxFilenameLength bounds the length of download file names, in characters.
+const maxFilenameLength = 100
+
+// DownloadFilename builds a safe file name for serving a file. The name is
+// based on the original upload name or, failing that, the title, and always
+// ends in the extension of the content type so it opens in the right program.
+func DownloadFilename(originalFilename, title, contentType string) string {
+	ext := ExtensionForContentType(contentType)
+
+	name := filepath.Base(strings.ReplaceAll(originalFilename, "\\", "/"))
+	if name == "." || name == "/" {
+		name = ""
+	}
+	name = strings.TrimSuffix(name, filepath.Ext(name))
+	if strings.TrimSpace(name) == "" {
+		name = title
+	}
+
+	// Drop control characters and anything with a meaning in headers or paths
+	name = strings.Map(func(r rune) rune {
+		switch {
+		case unicode.IsControl(r), strings.ContainsRune(`"\/:*?<>|;`, r):
+			return -1
+		case unicode.IsSpace(r):
+			return ' '
+		}
+		return r
+	}, name)
+	name = strings.Trim(strings.Join(strings.Fields(name), " "), ". ")
+
+	if runes := []rune(name); len(runes) > maxFilenameLength {
+		name = strings.TrimSpace(string(runes[:maxFilenameLength]))
+	}
+	if name == "" {
+		name = "document"
+	}
+
+	return name + ext
+}
+
+// ContentTypeForFilename returns the allowed MIME type for a file name, or
+// false when the extension is not accepted.
+func ContentTypeForFilename(filename string) (string, bool) {
+	contentType, ok := AllowedContentTypes[strings.ToLower(filepath.Ext(filename))]
+	return contentType, ok
+}
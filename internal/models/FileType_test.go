@@ -0,0 +1,38 @@
+package models
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDownloadFilename(t *testing.T) {
+	tests := []struct {
+		name             string
+		originalFilename string
+		title            string
+		contentType      string
+		want             string
+	}{
+		{name: "original name", originalFilename: "Photosynthesis notes.pdf", title: "Notes", contentType: typePDF, want: "Photosynthesis notes.pdf"},
+		{name: "extension follows content type", originalFilename: "worksheet.PDF", contentType: "image/jpeg", want: "worksheet.jpg"},
+		{name: "falls back to title", title: "Grade 10: Algebra / Term 1", contentType: typePDF, want: "Grade 10 Algebra Term 1.pdf"},
+		{name: "strips paths", originalFilename: `C:\Users\teacher\..\quiz.docx`, contentType: AllowedContentTypes[".docx"], want: "quiz.docx"},
+		{name: "strips header injection", originalFilename: "a\"; filename=evil.exe\r\n.pdf", contentType: typePDF, want: "a filename=evil.exe.pdf"},
+		{name: "keeps unicode", originalFilename: "Izibalo zeBanga 10.pdf", contentType: typePDF, want: "Izibalo zeBanga 10.pdf"},
+		{name: "nothing usable", originalFilename: "...", title: "  ", contentType: typePDF, want: "document.pdf"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DownloadFilename(tt.originalFilename, tt.title, tt.contentType); got != tt.want {
+				t.Errorf("DownloadFilename() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	long := DownloadFilename(strings.Repeat("ä", 300)+".pdf", "", typePDF)
+	if len([]rune(long)) != maxFilenameLength+len(".pdf") {
+		t.Errorf("DownloadFilename() kept %d characters", len([]rune(long)))
+	}
+}
+
+const typePDF = "application/pdf"
@@ -0,0 +1,18 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Follow is a subscription held by a user, either on another educator
+// (FolloweeID set) or on a subject/grade pair (Subject and Grade set).
+type Follow struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	FollowerID primitive.ObjectID `json:"follower_id" bson:"follower_id"`
+	FolloweeID primitive.ObjectID `json:"followee_id,omitempty" bson:"followee_id,omitempty"`
+	Subject    string             `json:"subject,omitempty" bson:"subject,omitempty"`
+	Grade      string             `json:"grade,omitempty" bson:"grade,omitempty"`
+	CreatedAt  time.Time          `json:"created_at" bson:"created_at"`
+}
@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// HarvestQuery selects published documents for metadata harvesting, in the
+// order they were approved. Empty fields do not restrict the results.
+type HarvestQuery struct {
+	// From and Before bound the approval time; Before is exclusive
+	From   time.Time
+	Before time.Time
+	// Subjects and Grades restrict the results to documents with one of the
+	// given values
+	Subjects []string
+	Grades   []string
+	// AfterModeratedAt and AfterID continue after the last document of the
+	// previous page
+	AfterModeratedAt time.Time
+	AfterID          primitive.ObjectID
+	Limit            int
+}
+
+// SubjectsAndGrades lists the distinct subjects and grades of published
+// documents.
+type SubjectsAndGrades struct {
+	Subjects []string `bson:"subjects"`
+	Grades   []string `bson:"grades"`
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// LTIPlatform is a learning management system registered to launch
+// Share2Teach as an LTI 1.3 tool.
+type LTIPlatform struct {
+	ID            primitive.ObjectID `json:"_id" bson:"_id"`
+	Name          string             `json:"name" bson:"name"`
+	Issuer        string             `json:"issuer" bson:"issuer"`
+	ClientID      string             `json:"client_id" bson:"client_id"`
+	DeploymentIDs []string           `json:"deployment_ids" bson:"deployment_ids"`
+	// AuthLoginURL is the platform's OIDC authorization endpoint
+	AuthLoginURL string `json:"auth_login_url" bson:"auth_login_url"`
+	// KeySetURL is where the platform publishes the keys it signs launches with
+	KeySetURL string    `json:"key_set_url" bson:"key_set_url"`
+	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+}
+
+// Validate checks that a registration has everything a launch needs.
+func (p *LTIPlatform) Validate() error {
+	switch {
+	case p.Name == "":
+		return errors.New("name must be provided")
+	case !isHTTPURL(p.Issuer):
+		return errors.New("issuer must be an http or https URL")
+	case p.ClientID == "":
+		return errors.New("client_id must be provided")
+	case len(p.DeploymentIDs) == 0:
+		return errors.New("at least one deployment ID must be provided")
+	case !isHTTPURL(p.AuthLoginURL):
+		return errors.New("auth_login_url must be an http or https URL")
+	case !isHTTPURL(p.KeySetURL):
+		return errors.New("key_set_url must be an http or https URL")
+	}
+
+	for _, deploymentID := range p.DeploymentIDs {
+		if deploymentID == "" {
+			return errors.New("deployment IDs must not be empty")
+		}
+	}
+
+	return nil
+}
+
+func isHTTPURL(value string) bool {
+	u, err := url.Parse(value)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+// LTILogin remembers an OIDC login a platform started until its launch
+// arrives. It can only be used once.
+type LTILogin struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	State      string             `bson:"state"`
+	Nonce      string             `bson:"nonce"`
+	PlatformID primitive.ObjectID `bson:"platform_id"`
+	ExpiresAt  time.Time          `bson:"expires_at"`
+}
+
+// LTIDeepLink is a Deep Linking request waiting for a teacher to choose the
+// documents to add to their course. It can only be completed once.
+type LTIDeepLink struct {
+	ID             primitive.ObjectID `json:"-" bson:"_id"`
+	Token          string             `json:"-" bson:"token"`
+	PlatformID     primitive.ObjectID `json:"-" bson:"platform_id"`
+	DeploymentID   string             `json:"-" bson:"deployment_id"`
+	ReturnURL      string             `json:"-" bson:"return_url"`
+	Data           string             `json:"-" bson:"data,omitempty"`
+	AcceptMultiple bool               `json:"accept_multiple" bson:"accept_multiple"`
+	CourseTitle    string             `json:"course_title,omitempty" bson:"course_title,omitempty"`
+	ExpiresAt      time.Time          `json:"expires_at" bson:"expires_at"`
+}
+
+// GenerateLTIToken returns a random value for the state and nonce of an LTI
+// login or a Deep Linking request, safe to put in a URL.
+func GenerateLTIToken() (string, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
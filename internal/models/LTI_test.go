@@ -0,0 +1,38 @@
+package models
+
+import "testing"
+
+func TestLTIPlatform_Validate(t *testing.T) {
+	valid := func() LTIPlatform {
+		return LTIPlatform{
+			Name:          "School Moodle",
+			Issuer:        "https://moodle.example.com",
+			ClientID:      "abc123",
+			DeploymentIDs: []string{"1"},
+			AuthLoginURL:  "https://moodle.example.com/mod/lti/auth.php",
+			KeySetURL:     "https://moodle.example.com/mod/lti/certs.php",
+		}
+	}
+
+	tests := []struct {
+		name    string
+		change  func(p *LTIPlatform)
+		wantErr bool
+	}{
+		{name: "valid", change: func(p *LTIPlatform) {}},
+		{name: "missing client ID", change: func(p *LTIPlatform) { p.ClientID = "" }, wantErr: true},
+		{name: "no deployments", change: func(p *LTIPlatform) { p.DeploymentIDs = nil }, wantErr: true},
+		{name: "empty deployment", change: func(p *LTIPlatform) { p.DeploymentIDs = []string{"1", ""} }, wantErr: true},
+		{name: "relative key set URL", change: func(p *LTIPlatform) { p.KeySetURL = "/mod/lti/certs.php" }, wantErr: true},
+		{name: "issuer is not a URL", change: func(p *LTIPlatform) { p.Issuer = "moodle" }, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			platform := valid()
+			tt.change(&platform)
+			if err := platform.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
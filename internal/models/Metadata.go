@@ -0,0 +1,99 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// MaxDescriptionLength is the longest description accepted for a document.
+	MaxDescriptionLength = 2000
+	// MaxTags is the largest number of tags a document can carry.
+	MaxTags = 10
+	// MaxTagLength is the longest tag accepted.
+	MaxTagLength = 30
+)
+
+// Licences maps the Creative Commons licences documents can be shared under
+// to their full names.
+var Licences = map[string]string{
+	"CC0-1.0":         "CC0 1.0 Universal",
+	"CC-BY-4.0":       "Creative Commons Attribution 4.0",
+	"CC-BY-SA-4.0":    "Creative Commons Attribution-ShareAlike 4.0",
+	"CC-BY-NC-4.0":    "Creative Commons Attribution-NonCommercial 4.0",
+	"CC-BY-NC-SA-4.0": "Creative Commons Attribution-NonCommercial-ShareAlike 4.0",
+	"CC-BY-ND-4.0":    "Creative Commons Attribution-NoDerivatives 4.0",
+	"CC-BY-NC-ND-4.0": "Creative Commons Attribution-NonCommercial-NoDerivatives 4.0",
+}
+
+// Languages maps the codes of the official South African languages of
+// instruction to their names.
+var Languages = map[string]string{
+	"af":  "Afrikaans",
+	"en":  "English",
+	"nr":  "isiNdebele",
+	"xh":  "isiXhosa",
+	"zu":  "isiZulu",
+	"nso": "Sepedi",
+	"st":  "Sesotho",
+	"tn":  "Setswana",
+	"ss":  "siSwati",
+	"ve":  "Tshivenda",
+	"ts":  "Xitsonga",
+}
+
+// NormalizeLicence returns the canonical identifier of a licence, accepting
+// any letter case.
+func NormalizeLicence(licence string) (string, error) {
+	normalized := strings.ToUpper(strings.TrimSpace(licence))
+	if normalized == "CC0-1.0" || normalized == "CC0" {
+		return "CC0-1.0", nil
+	}
+	if _, ok := Licences[normalized]; !ok {
+		return "", fmt.Errorf("unknown licence %q", licence)
+	}
+	return normalized, nil
+}
+
+// NormalizeLanguage returns the code of a language of instruction.
+func NormalizeLanguage(language string) (string, error) {
+	normalized := strings.ToLower(strings.TrimSpace(language))
+	if _, ok := Languages[normalized]; !ok {
+		return "", fmt.Errorf("unknown language %q", language)
+	}
+	return normalized, nil
+}
+
+// NormalizeTags lower-cases and trims tags and drops duplicates, so that
+// "Algebra" and "algebra " are the same tag.
+func NormalizeTags(tags []string) ([]string, error) {
+	normalized := []string{}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		tag = strings.Join(strings.Fields(strings.ToLower(tag)), " ")
+		if tag == "" || seen[tag] {
+			continue
+		}
+		if len(tag) > MaxTagLength {
+			return nil, fmt.Errorf("tag %q is longer than %d characters", tag, MaxTagLength)
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+
+	if len(normalized) > MaxTags {
+		return nil, fmt.Errorf("a document can have at most %d tags", MaxTags)
+	}
+
+	return normalized, nil
+}
+
+// NormalizeDescription trims a description and checks its length.
+func NormalizeDescription(description string) (string, error) {
+	description = strings.TrimSpace(description)
+	if len(description) > MaxDescriptionLength {
+		return "", errors.New("description is too long")
+	}
+	return description, nil
+}
@@ -0,0 +1,56 @@
+package models
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		want    []string
+		wantErr bool
+	}{
+		{name: "lower-cases and deduplicates", tags: []string{"Algebra", " algebra ", "Term  2", ""}, want: []string{"algebra", "term 2"}},
+		{name: "no tags", tags: nil, want: []string{}},
+		{name: "too long", tags: []string{strings.Repeat("a", MaxTagLength+1)}, wantErr: true},
+		{name: "too many", tags: strings.Split("a,b,c,d,e,f,g,h,i,j,k", ","), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NormalizeTags(tt.tags)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NormalizeTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NormalizeTags() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeLicence(t *testing.T) {
+	tests := map[string]string{
+		"cc-by-sa-4.0": "CC-BY-SA-4.0",
+		"CC0":          "CC0-1.0",
+		"cc0-1.0":      "CC0-1.0",
+		"GPL-3.0":      "",
+	}
+	for licence, want := range tests {
+		got, err := NormalizeLicence(licence)
+		if (err != nil) != (want == "") || got != want {
+			t.Errorf("NormalizeLicence(%q) = %q, %v, want %q", licence, got, err, want)
+		}
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	if got, err := NormalizeLanguage(" ZU "); err != nil || got != "zu" {
+		t.Errorf("NormalizeLanguage() = %q, %v, want zu", got, err)
+	}
+	if _, err := NormalizeLanguage("fr"); err == nil {
+		t.Error("NormalizeLanguage() accepted a language that is not taught in")
+	}
+}
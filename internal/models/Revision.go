@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Revision is one uploaded version of a document. Every revision is stored
+// under its own object key so earlier versions remain downloadable.
+type Revision struct {
+	ID             primitive.ObjectID `json:"_id" bson:"_id"`
+	DocumentID     primitive.ObjectID `json:"document_id" bson:"document_id"`
+	Version        int                `json:"version" bson:"version"`
+	ObjectKey      string             `json:"-" bson:"object_key"`
+	UploadedBy     primitive.ObjectID `json:"uploaded_by" bson:"uploaded_by"`
+	UploadedAt     time.Time          `json:"uploaded_at" bson:"uploaded_at"`
+	Changelog      string             `json:"changelog" bson:"changelog"`
+	ApprovalStatus string             `json:"approvalStatus" bson:"approvalStatus"`
+	ContentType    string             `json:"content_type,omitempty" bson:"content_type,omitempty"`
+
+	// The file of version 1 is checked as part of the document, so these are
+	// only set on later versions
+	ValidationStatus string `json:"validation_status,omitempty" bson:"validation_status,omitempty"`
+	ValidationReason string `json:"validation_reason,omitempty" bson:"validation_reason,omitempty"`
+	DetectedType     string `json:"detected_type,omitempty" bson:"detected_type,omitempty"`
+	SHA256           string `json:"sha256,omitempty" bson:"sha256,omitempty"`
+	ScanStatus       string `json:"scan_status,omitempty" bson:"scan_status,omitempty"`
+	ScanSignature    string `json:"scan_signature,omitempty" bson:"scan_signature,omitempty"`
+	// ScannedETag identifies the exact object that was scanned
+	ScannedETag string `json:"-" bson:"scanned_etag,omitempty"`
+}
@@ -0,0 +1,52 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MaxShareLinksPerDocument is the largest number of share links a document can have.
+const MaxShareLinksPerDocument = 50
+
+// ShareLink gives anyone holding its token access to a document that may not
+// be published, until it expires, runs out of uses or is revoked.
+type ShareLink struct {
+	ID         primitive.ObjectID `json:"_id" bson:"_id"`
+	Token      string             `json:"token" bson:"token"`
+	DocumentID primitive.ObjectID `json:"document_id" bson:"document_id"`
+	UserID     primitive.ObjectID `json:"user_id" bson:"user_id"`
+	// ExpiresAt is unset for links that do not expire
+	ExpiresAt *time.Time `json:"expires_at,omitempty" bson:"expires_at,omitempty"`
+	// MaxUses is zero for links that can be used any number of times
+	MaxUses   int        `json:"max_uses,omitempty" bson:"max_uses"`
+	Uses      int        `json:"uses" bson:"uses"`
+	CreatedAt time.Time  `json:"created_at" bson:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+// Usable reports whether the link still grants access at time now.
+func (l *ShareLink) Usable(now time.Time) bool {
+	switch {
+	case l.RevokedAt != nil:
+		return false
+	case l.ExpiresAt != nil && !now.Before(*l.ExpiresAt):
+		return false
+	case l.MaxUses > 0 && l.Uses >= l.MaxUses:
+		return false
+	default:
+		return true
+	}
+}
+
+// GenerateShareToken returns a random token that is safe to put in a URL.
+func GenerateShareToken() (string, error) {
+	key := make([]byte, 32)
+	_, err := rand.Read(key)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(key), nil
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestShareLink_Usable(t *testing.T) {
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Minute)
+
+	tests := []struct {
+		name string
+		link ShareLink
+		want bool
+	}{
+		{name: "unrestricted", link: ShareLink{Uses: 100}, want: true},
+		{name: "before expiry", link: ShareLink{ExpiresAt: &future}, want: true},
+		{name: "expired", link: ShareLink{ExpiresAt: &past}, want: false},
+		{name: "uses left", link: ShareLink{MaxUses: 3, Uses: 2}, want: true},
+		{name: "used up", link: ShareLink{MaxUses: 3, Uses: 3}, want: false},
+		{name: "revoked", link: ShareLink{RevokedAt: &past}, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.link.Usable(now); got != tt.want {
+				t.Errorf("Usable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateShareToken(t *testing.T) {
+	a, err := GenerateShareToken()
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := GenerateShareToken()
+	if a == b || len(a) != 43 {
+		t.Errorf("GenerateShareToken() = %q, %q, want distinct 43 character tokens", a, b)
+	}
+}
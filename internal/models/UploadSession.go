@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UploadSession records a document ID handed out for an upload, so that only
+// the user it was issued to can attach metadata to it before it expires.
+type UploadSession struct {
+	DocumentID  primitive.ObjectID `json:"document_id" bson:"_id"`
+	UserID      primitive.ObjectID `json:"user_id" bson:"user_id"`
+	Filename    string             `json:"filename" bson:"filename"`
+	ContentType string             `json:"content_type" bson:"content_type"`
+	// MultipartUploadID is set when the file is uploaded in parts
+	MultipartUploadID string    `json:"-" bson:"multipart_upload_id,omitempty"`
+	CreatedAt         time.Time `json:"created_at" bson:"created_at"`
+	ExpiresAt         time.Time `json:"expires_at" bson:"expires_at"`
+}
@@ -0,0 +1,42 @@
+package oaipmh
+
+// DublinCoreFormat describes the oai_dc format every provider must support.
+var DublinCoreFormat = MetadataFormat{
+	MetadataPrefix:    "oai_dc",
+	Schema:            "http://www.openarchives.org/OAI/2.0/oai_dc.xsd",
+	MetadataNamespace: "http://www.openarchives.org/OAI/2.0/oai_dc/",
+}
+
+// DublinCore is a record in unqualified Dublin Core. Every element is
+// optional and may repeat.
+type DublinCore struct {
+	XmlnsOAIDC     string `xml:"xmlns:oai_dc,attr"`
+	XmlnsDC        string `xml:"xmlns:dc,attr"`
+	XmlnsXsi       string `xml:"xmlns:xsi,attr"`
+	SchemaLocation string `xml:"xsi:schemaLocation,attr"`
+
+	Title       []string `xml:"dc:title"`
+	Creator     []string `xml:"dc:creator"`
+	Subject     []string `xml:"dc:subject"`
+	Description []string `xml:"dc:description"`
+	Publisher   []string `xml:"dc:publisher"`
+	Date        []string `xml:"dc:date"`
+	Type        []string `xml:"dc:type"`
+	Format      []string `xml:"dc:format"`
+	Identifier  []string `xml:"dc:identifier"`
+	Source      []string `xml:"dc:source"`
+	Language    []string `xml:"dc:language"`
+	Relation    []string `xml:"dc:relation"`
+	Coverage    []string `xml:"dc:coverage"`
+	Rights      []string `xml:"dc:rights"`
+}
+
+// NewDublinCore returns an empty oai_dc record with its namespaces declared.
+func NewDublinCore() *DublinCore {
+	return &DublinCore{
+		XmlnsOAIDC:     DublinCoreFormat.MetadataNamespace,
+		XmlnsDC:        "http://purl.org/dc/elements/1.1/",
+		XmlnsXsi:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: DublinCoreFormat.MetadataNamespace + " " + DublinCoreFormat.Schema,
+	}
+}
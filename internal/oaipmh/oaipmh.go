@@ -0,0 +1,242 @@
+// Package oaipmh implements the protocol side of an OAI-PMH 2.0 data
+// provider: validating requests, paging with resumption tokens and the XML
+// responses. Which records exist is left to the caller.
+package oaipmh
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+const (
+	// Namespace is the XML namespace of OAI-PMH 2.0 responses.
+	Namespace = "http://www.openarchives.org/OAI/2.0/"
+	// DatestampLayout formats datestamps at the granularity of seconds.
+	DatestampLayout = "2006-01-02T15:04:05Z"
+	// Granularity is the finest datestamp granularity this provider supports.
+	Granularity = "YYYY-MM-DDThh:mm:ssZ"
+
+	dayLayout = "2006-01-02"
+)
+
+// Error codes defined by the protocol.
+const (
+	BadArgument             = "badArgument"
+	BadResumptionToken      = "badResumptionToken"
+	BadVerb                 = "badVerb"
+	CannotDisseminateFormat = "cannotDisseminateFormat"
+	IDDoesNotExist          = "idDoesNotExist"
+	NoMetadataFormats       = "noMetadataFormats"
+	NoRecordsMatch          = "noRecordsMatch"
+	NoSetHierarchy          = "noSetHierarchy"
+)
+
+// Error is an OAI-PMH error condition, reported in the body of a successful
+// HTTP response.
+type Error struct {
+	Code    string `xml:"code,attr"`
+	Message string `xml:",chardata"`
+}
+
+func (e *Error) Error() string {
+	return e.Code + ": " + e.Message
+}
+
+// verbArguments lists the arguments each verb accepts and whether they are
+// required. A resumption token may only be given on its own.
+var verbArguments = map[string]map[string]bool{
+	"Identify":            {},
+	"ListMetadataFormats": {"identifier": false},
+	"ListSets":            {"resumptionToken": false},
+	"GetRecord":           {"identifier": true, "metadataPrefix": true},
+	"ListIdentifiers":     {"metadataPrefix": true, "from": false, "until": false, "set": false, "resumptionToken": false},
+	"ListRecords":         {"metadataPrefix": true, "from": false, "until": false, "set": false, "resumptionToken": false},
+}
+
+// Request is a validated OAI-PMH request. When it continues a list through a
+// resumption token, the arguments of the original request are restored from
+// the token.
+type Request struct {
+	Verb           string
+	Identifier     string
+	MetadataPrefix string
+	Set            string
+	// From and Before bound the datestamps of the records selected; Before is
+	// exclusive and zero times do not restrict the selection
+	From   time.Time
+	Before time.Time
+
+	// Resumed is true when the request continues a list
+	Resumed bool
+	// Cursor is the number of records returned by earlier pages of the list
+	Cursor int
+	// LastDatestamp and LastID identify the last record of the previous page
+	LastDatestamp time.Time
+	LastID        string
+}
+
+// ParseRequest validates the arguments of a request. The error it returns
+// is always a protocol error.
+func ParseRequest(values url.Values) (Request, *Error) {
+	verb := values.Get("verb")
+	allowed, ok := verbArguments[verb]
+	if !ok || len(values["verb"]) > 1 {
+		return Request{}, &Error{Code: BadVerb, Message: "illegal or missing verb"}
+	}
+
+	for name, value := range values {
+		if name == "verb" {
+			continue
+		}
+		if _, ok := allowed[name]; !ok {
+			return Request{}, &Error{Code: BadArgument, Message: "illegal argument " + name}
+		}
+		if len(value) > 1 {
+			return Request{}, &Error{Code: BadArgument, Message: "repeated argument " + name}
+		}
+	}
+
+	if values.Has("resumptionToken") {
+		if len(values) > 2 {
+			return Request{}, &Error{Code: BadArgument, Message: "resumptionToken is an exclusive argument"}
+		}
+		if verb == "ListSets" {
+			return Request{}, &Error{Code: BadResumptionToken, Message: "the list of sets is complete"}
+		}
+		return decodeToken(verb, values.Get("resumptionToken"))
+	}
+
+	for name, required := range allowed {
+		if required && values.Get(name) == "" {
+			return Request{}, &Error{Code: BadArgument, Message: "missing argument " + name}
+		}
+	}
+
+	request := Request{
+		Verb:           verb,
+		Identifier:     values.Get("identifier"),
+		MetadataPrefix: values.Get("metadataPrefix"),
+		Set:            values.Get("set"),
+	}
+
+	var fromLayout, untilLayout string
+	var err *Error
+	if from := values.Get("from"); from != "" {
+		request.From, fromLayout, err = parseDatestamp("from", from)
+		if err != nil {
+			return Request{}, err
+		}
+	}
+	if until := values.Get("until"); until != "" {
+		var untilTime time.Time
+		untilTime, untilLayout, err = parseDatestamp("until", until)
+		if err != nil {
+			return Request{}, err
+		}
+
+		// until is inclusive at its own granularity
+		if untilLayout == dayLayout {
+			request.Before = untilTime.AddDate(0, 0, 1)
+		} else {
+			request.Before = untilTime.Add(time.Second)
+		}
+	}
+
+	if fromLayout != "" && untilLayout != "" {
+		if fromLayout != untilLayout {
+			return Request{}, &Error{Code: BadArgument, Message: "from and until must have the same granularity"}
+		}
+		if !request.From.Before(request.Before) {
+			return Request{}, &Error{Code: BadArgument, Message: "from must not be later than until"}
+		}
+	}
+
+	return request, nil
+}
+
+// parseDatestamp parses a UTC datestamp given as a day or to the second and
+// reports which layout it used.
+func parseDatestamp(name, value string) (time.Time, string, *Error) {
+	for _, layout := range []string{dayLayout, DatestampLayout} {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, layout, nil
+		}
+	}
+	return time.Time{}, "", &Error{Code: BadArgument, Message: "illegal datestamp " + name}
+}
+
+// Datestamp formats t at the granularity of this provider.
+func Datestamp(t time.Time) string {
+	return t.UTC().Format(DatestampLayout)
+}
+
+// resumptionToken is the state of a list carried between pages.
+type resumptionToken struct {
+	MetadataPrefix string    `json:"m"`
+	Set            string    `json:"s,omitempty"`
+	From           time.Time `json:"f"`
+	Before         time.Time `json:"b"`
+	Cursor         int       `json:"c"`
+	LastDatestamp  time.Time `json:"t"`
+	LastID         string    `json:"i"`
+}
+
+// NextToken returns the resumption token for the page of the list that
+// follows the record identified by lastDatestamp and lastID. returned is the
+// number of records on the current page.
+func (r Request) NextToken(returned int, lastDatestamp time.Time, lastID string) string {
+	token := resumptionToken{
+		MetadataPrefix: r.MetadataPrefix,
+		Set:            r.Set,
+		From:           r.From,
+		Before:         r.Before,
+		Cursor:         r.Cursor + returned,
+		LastDatestamp:  lastDatestamp,
+		LastID:         lastID,
+	}
+
+	raw, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// decodeToken restores the request that a resumption token continues.
+func decodeToken(verb, value string) (Request, *Error) {
+	invalid := &Error{Code: BadResumptionToken, Message: "the resumption token is invalid"}
+
+	raw, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return Request{}, invalid
+	}
+
+	var token resumptionToken
+	if err := json.Unmarshal(raw, &token); err != nil || token.MetadataPrefix == "" || token.LastID == "" || token.Cursor < 0 {
+		return Request{}, invalid
+	}
+
+	return Request{
+		Verb:           verb,
+		MetadataPrefix: token.MetadataPrefix,
+		Set:            token.Set,
+		From:           token.From,
+		Before:         token.Before,
+		Resumed:        true,
+		Cursor:         token.Cursor,
+		LastDatestamp:  token.LastDatestamp,
+		LastID:         token.LastID,
+	}, nil
+}
+
+var setSpecSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// SetSpec returns the spec of the set named name below the set parent, for
+// example "subject:life-sciences" for parent "subject" and name
+// "Life Sciences".
+func SetSpec(parent, name string) string {
+	slug := strings.Trim(setSpecSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	return parent + ":" + slug
+}
@@ -0,0 +1,160 @@
+package oaipmh
+
+import (
+	"bytes"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		query    string
+		wantCode string
+	}{
+		{name: "identify", query: "verb=Identify"},
+		{name: "missing verb", query: "", wantCode: BadVerb},
+		{name: "unknown verb", query: "verb=ListEverything", wantCode: BadVerb},
+		{name: "repeated verb", query: "verb=Identify&verb=Identify", wantCode: BadVerb},
+		{name: "illegal argument", query: "verb=Identify&set=maths", wantCode: BadArgument},
+		{name: "repeated argument", query: "verb=ListRecords&metadataPrefix=oai_dc&set=a&set=b", wantCode: BadArgument},
+		{name: "missing metadata prefix", query: "verb=ListRecords", wantCode: BadArgument},
+		{name: "missing identifier", query: "verb=GetRecord&metadataPrefix=oai_dc", wantCode: BadArgument},
+		{name: "list with range", query: "verb=ListIdentifiers&metadataPrefix=oai_dc&from=2024-01-01&until=2024-01-31"},
+		{name: "illegal datestamp", query: "verb=ListRecords&metadataPrefix=oai_dc&from=01/01/2024", wantCode: BadArgument},
+		{name: "mixed granularity", query: "verb=ListRecords&metadataPrefix=oai_dc&from=2024-01-01&until=2024-01-31T00:00:00Z", wantCode: BadArgument},
+		{name: "from after until", query: "verb=ListRecords&metadataPrefix=oai_dc&from=2024-02-01&until=2024-01-31", wantCode: BadArgument},
+		{name: "token is exclusive", query: "verb=ListRecords&metadataPrefix=oai_dc&resumptionToken=abc", wantCode: BadArgument},
+		{name: "invalid token", query: "verb=ListRecords&resumptionToken=abc", wantCode: BadResumptionToken},
+		{name: "sets are never paged", query: "verb=ListSets&resumptionToken=abc", wantCode: BadResumptionToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, oaiErr := ParseRequest(values)
+			code := ""
+			if oaiErr != nil {
+				code = oaiErr.Code
+			}
+			if code != tt.wantCode {
+				t.Errorf("ParseRequest(%q) error = %v, want %q", tt.query, oaiErr, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestParseRequest_UntilIsInclusive(t *testing.T) {
+	request, oaiErr := ParseRequest(url.Values{"verb": {"ListRecords"}, "metadataPrefix": {"oai_dc"}, "until": {"2024-01-31"}})
+	if oaiErr != nil {
+		t.Fatal(oaiErr)
+	}
+	if want := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC); !request.Before.Equal(want) {
+		t.Errorf("Before = %v, want %v", request.Before, want)
+	}
+
+	request, oaiErr = ParseRequest(url.Values{"verb": {"ListRecords"}, "metadataPrefix": {"oai_dc"}, "until": {"2024-01-31T10:00:00Z"}})
+	if oaiErr != nil {
+		t.Fatal(oaiErr)
+	}
+	if want := time.Date(2024, 1, 31, 10, 0, 1, 0, time.UTC); !request.Before.Equal(want) {
+		t.Errorf("Before = %v, want %v", request.Before, want)
+	}
+}
+
+func TestResumptionToken(t *testing.T) {
+	first, oaiErr := ParseRequest(url.Values{"verb": {"ListRecords"}, "metadataPrefix": {"oai_dc"}, "set": {"subject:maths"}, "from": {"2024-01-01"}})
+	if oaiErr != nil {
+		t.Fatal(oaiErr)
+	}
+
+	last := time.Date(2024, 3, 1, 12, 0, 0, 500000000, time.UTC)
+	token := first.NextToken(100, last, "65f1c0ffee0000000000abcd")
+
+	next, oaiErr := ParseRequest(url.Values{"verb": {"ListRecords"}, "resumptionToken": {token}})
+	if oaiErr != nil {
+		t.Fatal(oaiErr)
+	}
+
+	if !next.Resumed || next.Cursor != 100 || next.MetadataPrefix != "oai_dc" || next.Set != "subject:maths" {
+		t.Errorf("resumed request = %+v", next)
+	}
+	if !next.From.Equal(first.From) || !next.LastDatestamp.Equal(last) || next.LastID != "65f1c0ffee0000000000abcd" {
+		t.Errorf("resumed request = %+v, want the position after the last record", next)
+	}
+
+	if third := next.NextToken(100, last, "65f1c0ffee0000000000abce"); strings.Contains(third, "=") {
+		t.Errorf("NextToken() = %q, want it safe in a URL", third)
+	}
+}
+
+func TestSetSpec(t *testing.T) {
+	tests := map[string]string{
+		"Life Sciences":           "subject:life-sciences",
+		"  Maths & Literacy!  ":   "subject:maths-literacy",
+		"Afrikaans Huistaal (HL)": "subject:afrikaans-huistaal-hl",
+	}
+	for name, want := range tests {
+		if got := SetSpec("subject", name); got != want {
+			t.Errorf("SetSpec(%q) = %q, want %q", name, got, want)
+		}
+	}
+}
+
+func TestResponse_Write(t *testing.T) {
+	values := url.Values{"verb": {"GetRecord"}, "identifier": {"oai:example.com:1"}, "metadataPrefix": {"oai_dc"}}
+	response := NewResponse("https://api.example.com/oai", values, nil)
+
+	dc := NewDublinCore()
+	dc.Title = []string{"Fractions <basics>"}
+	dc.Subject = []string{"Maths", "Grade 4"}
+	response.GetRecord = &GetRecord{Record: Record{
+		Header:   Header{Identifier: "oai:example.com:1", Datestamp: "2024-03-01T12:00:00Z", SetSpecs: []string{"subject:maths"}},
+		Metadata: Metadata{DublinCore: dc},
+	}}
+
+	var buf bytes.Buffer
+	if err := response.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	for _, want := range []string{
+		`<OAI-PMH xmlns="http://www.openarchives.org/OAI/2.0/" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance"`,
+		`<request verb="GetRecord" identifier="oai:example.com:1" metadataPrefix="oai_dc">https://api.example.com/oai</request>`,
+		`<setSpec>subject:maths</setSpec>`,
+		`<oai_dc:dc xmlns:oai_dc="http://www.openarchives.org/OAI/2.0/oai_dc/" xmlns:dc="http://purl.org/dc/elements/1.1/"`,
+		`<dc:title>Fractions &lt;basics&gt;</dc:title>`,
+		`<dc:subject>Grade 4</dc:subject>`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("response does not contain %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<error") {
+		t.Errorf("response contains an error:\n%s", out)
+	}
+}
+
+func TestResponse_ErrorsHideInvalidArguments(t *testing.T) {
+	values := url.Values{"verb": {"Identify"}, "bogus": {"1"}}
+	_, oaiErr := ParseRequest(values)
+
+	var buf bytes.Buffer
+	if err := NewResponse("https://api.example.com/oai", values, oaiErr).Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, `<request>https://api.example.com/oai</request>`) {
+		t.Errorf("request was echoed after a badArgument error:\n%s", out)
+	}
+	if !strings.Contains(out, `<error code="badArgument">`) {
+		t.Errorf("response does not report badArgument:\n%s", out)
+	}
+}
@@ -0,0 +1,169 @@
+package oaipmh
+
+import (
+	"encoding/xml"
+	"io"
+	"net/url"
+	"time"
+)
+
+// Response is the root element of every OAI-PMH response. Exactly one of the
+// verb elements is set unless Errors is not empty.
+type Response struct {
+	XMLName        xml.Name       `xml:"OAI-PMH"`
+	Xmlns          string         `xml:"xmlns,attr"`
+	XmlnsXsi       string         `xml:"xmlns:xsi,attr"`
+	SchemaLocation string         `xml:"xsi:schemaLocation,attr"`
+	ResponseDate   string         `xml:"responseDate"`
+	Request        RequestElement `xml:"request"`
+	Errors         []*Error       `xml:"error,omitempty"`
+
+	Identify            *Identify            `xml:"Identify,omitempty"`
+	ListMetadataFormats *ListMetadataFormats `xml:"ListMetadataFormats,omitempty"`
+	ListSets            *ListSets            `xml:"ListSets,omitempty"`
+	GetRecord           *GetRecord           `xml:"GetRecord,omitempty"`
+	ListIdentifiers     *ListIdentifiers     `xml:"ListIdentifiers,omitempty"`
+	ListRecords         *ListRecords         `xml:"ListRecords,omitempty"`
+}
+
+// RequestElement echoes the request a response answers.
+type RequestElement struct {
+	BaseURL         string `xml:",chardata"`
+	Verb            string `xml:"verb,attr,omitempty"`
+	Identifier      string `xml:"identifier,attr,omitempty"`
+	MetadataPrefix  string `xml:"metadataPrefix,attr,omitempty"`
+	From            string `xml:"from,attr,omitempty"`
+	Until           string `xml:"until,attr,omitempty"`
+	Set             string `xml:"set,attr,omitempty"`
+	ResumptionToken string `xml:"resumptionToken,attr,omitempty"`
+}
+
+// NewResponse starts the response to a request made to baseURL with the
+// given arguments. The arguments are only echoed when they are valid, as the
+// protocol requires.
+func NewResponse(baseURL string, values url.Values, err *Error) *Response {
+	response := &Response{
+		Xmlns:          Namespace,
+		XmlnsXsi:       "http://www.w3.org/2001/XMLSchema-instance",
+		SchemaLocation: Namespace + " http://www.openarchives.org/OAI/2.0/OAI-PMH.xsd",
+		ResponseDate:   Datestamp(time.Now()),
+		Request:        RequestElement{BaseURL: baseURL},
+	}
+
+	if err != nil {
+		response.Errors = []*Error{err}
+		if err.Code == BadVerb || err.Code == BadArgument {
+			return response
+		}
+	}
+
+	response.Request.Verb = values.Get("verb")
+	response.Request.Identifier = values.Get("identifier")
+	response.Request.MetadataPrefix = values.Get("metadataPrefix")
+	response.Request.From = values.Get("from")
+	response.Request.Until = values.Get("until")
+	response.Request.Set = values.Get("set")
+	response.Request.ResumptionToken = values.Get("resumptionToken")
+
+	return response
+}
+
+// Fail replaces the content of the response with a protocol error.
+func (r *Response) Fail(code, message string) {
+	*r = Response{
+		Xmlns:          r.Xmlns,
+		XmlnsXsi:       r.XmlnsXsi,
+		SchemaLocation: r.SchemaLocation,
+		ResponseDate:   r.ResponseDate,
+		Request:        r.Request,
+		Errors:         []*Error{{Code: code, Message: message}},
+	}
+}
+
+// Write encodes the response as an XML document.
+func (r *Response) Write(w io.Writer) error {
+	_, err := io.WriteString(w, xml.Header)
+	if err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	return encoder.Encode(r)
+}
+
+// Identify describes the repository.
+type Identify struct {
+	RepositoryName    string `xml:"repositoryName"`
+	BaseURL           string `xml:"baseURL"`
+	ProtocolVersion   string `xml:"protocolVersion"`
+	AdminEmail        string `xml:"adminEmail"`
+	EarliestDatestamp string `xml:"earliestDatestamp"`
+	DeletedRecord     string `xml:"deletedRecord"`
+	Granularity       string `xml:"granularity"`
+}
+
+// MetadataFormat describes a format records can be disseminated in.
+type MetadataFormat struct {
+	MetadataPrefix    string `xml:"metadataPrefix"`
+	Schema            string `xml:"schema"`
+	MetadataNamespace string `xml:"metadataNamespace"`
+}
+
+// ListMetadataFormats lists the formats records can be disseminated in.
+type ListMetadataFormats struct {
+	MetadataFormats []MetadataFormat `xml:"metadataFormat"`
+}
+
+// Set is a group of records harvesters can select.
+type Set struct {
+	SetSpec string `xml:"setSpec"`
+	SetName string `xml:"setName"`
+}
+
+// ListSets lists the sets of the repository.
+type ListSets struct {
+	Sets []Set `xml:"set"`
+}
+
+// Header identifies a record.
+type Header struct {
+	Identifier string   `xml:"identifier"`
+	Datestamp  string   `xml:"datestamp"`
+	SetSpecs   []string `xml:"setSpec"`
+}
+
+// Record is a header with the metadata of the record.
+type Record struct {
+	Header   Header   `xml:"header"`
+	Metadata Metadata `xml:"metadata"`
+}
+
+// Metadata wraps the record in the requested metadata format.
+type Metadata struct {
+	DublinCore *DublinCore `xml:"oai_dc:dc"`
+}
+
+// GetRecord holds a single record.
+type GetRecord struct {
+	Record Record `xml:"record"`
+}
+
+// ResumptionToken continues an incomplete list. An empty token marks the
+// last page of a list that needed more than one.
+type ResumptionToken struct {
+	Token  string `xml:",chardata"`
+	Cursor int    `xml:"cursor,attr"`
+}
+
+// ListIdentifiers holds a page of record headers.
+type ListIdentifiers struct {
+	Headers         []Header         `xml:"header"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
+
+// ListRecords holds a page of records.
+type ListRecords struct {
+	Records         []Record         `xml:"record"`
+	ResumptionToken *ResumptionToken `xml:"resumptionToken,omitempty"`
+}
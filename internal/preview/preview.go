@@ -0,0 +1,244 @@
+// Package preview renders thumbnails and low-resolution previews of uploaded
+// documents so teachers can judge a resource without downloading it.
+package preview
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// ThumbnailSize bounds the width and height of thumbnails shown in search results.
+	ThumbnailSize = 200
+	// PreviewSize bounds the width and height of first page previews.
+	PreviewSize = 800
+)
+
+// renderTimeout bounds how long pdftoppm may take for one page, so a PDF
+// crafted to hang it cannot hold up the previews of every other document.
+const renderTimeout = 30 * time.Second
+
+// maxPixels bounds the size of images that are decoded. The header of a
+// small file can declare dimensions that take gigabytes to decode.
+const maxPixels = 50_000_000
+
+// ErrUnsupported is returned for files no preview can be rendered for.
+var ErrUnsupported = errors.New("previews are not supported for this file type")
+
+// ErrTooLarge is returned for images with more pixels than are decoded.
+var ErrTooLarge = errors.New("image is too large to preview")
+
+// ErrTimeout is returned for PDFs whose first page took too long to render.
+var ErrTimeout = errors.New("rendering took too long")
+
+// Render returns the first page of a PDF or an image as a picture. The file
+// must be on disk because PDFs are rasterised by poppler's pdftoppm.
+func Render(path, contentType string) (image.Image, error) {
+	switch {
+	case contentType == "application/pdf":
+		return renderPDF(path)
+	case contentType == "image/png", contentType == "image/jpeg", contentType == "image/gif":
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+
+		return decodeImage(f)
+	default:
+		return nil, ErrUnsupported
+	}
+}
+
+// decodeImage decodes an image after checking from its header that it is not
+// too large to hold in memory.
+func decodeImage(r io.ReadSeeker) (image.Image, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return nil, err
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > maxPixels {
+		return nil, fmt.Errorf("%w: %dx%d pixels", ErrTooLarge, config.Width, config.Height)
+	}
+
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// renderPDF rasterises the first page of a PDF at a resolution just large
+// enough for the preview.
+func renderPDF(path string) (image.Image, error) {
+	dir, err := os.MkdirTemp("", "share2teach-preview-*")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	ctx, cancel := context.WithTimeout(context.Background(), renderTimeout)
+	defer cancel()
+
+	out := filepath.Join(dir, "page")
+	cmd := exec.CommandContext(ctx, "pdftoppm", "-f", "1", "-l", "1", "-singlefile", "-png",
+		"-scale-to", fmt.Sprint(PreviewSize), path, out)
+	// Stop waiting for the output once it was killed
+	cmd.WaitDelay = time.Second
+	output, err := cmd.CombinedOutput()
+	if ctx.Err() != nil {
+		return nil, ErrTimeout
+	}
+	if err != nil {
+		return nil, fmt.Errorf("pdftoppm failed: %v: %s", err, strings.TrimSpace(string(output)))
+	}
+
+	f, err := os.Open(out + ".png")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return png.Decode(f)
+}
+
+// Scale shrinks img to fit within max×max pixels, averaging the source pixels
+// covered by each destination pixel. Images that already fit are returned as is.
+func Scale(img image.Image, max int) image.Image {
+	bounds := img.Bounds()
+	w, h := bounds.Dx(), bounds.Dy()
+	if w <= max && h <= max {
+		return img
+	}
+
+	dw, dh := max, h*max/w
+	if h > w {
+		dw, dh = w*max/h, max
+	}
+	if dw < 1 {
+		dw = 1
+	}
+	if dh < 1 {
+		dh = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		y0, y1 := bounds.Min.Y+y*h/dh, bounds.Min.Y+(y+1)*h/dh
+		for x := 0; x < dw; x++ {
+			x0, x1 := bounds.Min.X+x*w/dw, bounds.Min.X+(x+1)*w/dw
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a = r+uint64(cr), g+uint64(cg), b+uint64(cb), a+uint64(ca)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: uint16(a / n)})
+		}
+	}
+
+	return dst
+}
+
+// EncodeJPEG writes img as a JPEG suitable for previews. Transparent areas
+// are flattened onto white.
+func EncodeJPEG(w io.Writer, img image.Image) error {
+	bounds := img.Bounds()
+	flat := image.NewRGBA(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
+			r, g, b, a := img.At(x, y).RGBA()
+			// Composite over white
+			bg := 0xffff - a
+			flat.Set(x, y, color.RGBA64{R: uint16(r + bg), G: uint16(g + bg), B: uint16(b + bg), A: 0xffff})
+		}
+	}
+
+	return jpeg.Encode(w, flat, &jpeg.Options{Quality: 80})
+}
+
+// PlaceholderKind groups content types into the families that share a
+// placeholder image.
+func PlaceholderKind(contentType string) string {
+	switch {
+	case contentType == "application/pdf":
+		return "pdf"
+	case strings.Contains(contentType, "wordprocessingml"):
+		return "document"
+	case strings.Contains(contentType, "presentationml"):
+		return "presentation"
+	case strings.Contains(contentType, "spreadsheetml"):
+		return "spreadsheet"
+	case strings.HasPrefix(contentType, "image/"):
+		return "image"
+	default:
+		return "file"
+	}
+}
+
+// PlaceholderKinds lists every kind returned by PlaceholderKind.
+var PlaceholderKinds = []string{"pdf", "document", "presentation", "spreadsheet", "image", "file"}
+
+// placeholderColors are the colours Office and PDF readers use for each family.
+var placeholderColors = map[string]color.RGBA{
+	"pdf":          {R: 0xd9, G: 0x3a, B: 0x2b, A: 0xff},
+	"document":     {R: 0x2b, G: 0x57, B: 0x9a, A: 0xff},
+	"presentation": {R: 0xd2, G: 0x47, B: 0x26, A: 0xff},
+	"spreadsheet":  {R: 0x21, G: 0x73, B: 0x46, A: 0xff},
+	"image":        {R: 0x6b, G: 0x5b, B: 0x95, A: 0xff},
+	"file":         {R: 0x75, G: 0x75, B: 0x75, A: 0xff},
+}
+
+// Placeholder draws a page-shaped PNG in the colour of a placeholder kind, used
+// when no real preview can be rendered.
+func Placeholder(kind string) ([]byte, error) {
+	accent, ok := placeholderColors[kind]
+	if !ok {
+		accent = placeholderColors["file"]
+	}
+
+	const w, h = ThumbnailSize * 3 / 4, ThumbnailSize
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{R: 0xf5, G: 0xf5, B: 0xf5, A: 0xff}
+			switch {
+			case x == 0 || y == 0 || x == w-1 || y == h-1:
+				c = accent
+			case y >= h*2/3:
+				// Coloured band at the bottom of the page
+				c = accent
+			case y > h/8 && y < h/2 && (y/8)%2 == 0 && x > w/8 && x < w*7/8:
+				// Grey lines suggesting text
+				c = color.RGBA{R: 0xcc, G: 0xcc, B: 0xcc, A: 0xff}
+			}
+			img.Set(x, y, c)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
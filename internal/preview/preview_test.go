@@ -0,0 +1,113 @@
+package preview
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScale(t *testing.T) {
+	tests := []struct {
+		name          string
+		width, height int
+		max           int
+		wantW, wantH  int
+	}{
+		{name: "landscape", width: 1000, height: 500, max: 200, wantW: 200, wantH: 100},
+		{name: "portrait", width: 595, height: 842, max: 200, wantW: 141, wantH: 200},
+		{name: "already fits", width: 120, height: 80, max: 200, wantW: 120, wantH: 80},
+		{name: "thin strip", width: 5000, height: 2, max: 200, wantW: 200, wantH: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src := image.NewRGBA(image.Rect(0, 0, tt.width, tt.height))
+			got := Scale(src, tt.max).Bounds()
+			if got.Dx() != tt.wantW || got.Dy() != tt.wantH {
+				t.Errorf("Scale() = %dx%d, want %dx%d", got.Dx(), got.Dy(), tt.wantW, tt.wantH)
+			}
+		})
+	}
+}
+
+func TestScaleAveragesPixels(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 2; y++ {
+			c := color.RGBA{A: 0xff}
+			if x%2 == 0 {
+				c = color.RGBA{R: 0xff, G: 0xff, B: 0xff, A: 0xff}
+			}
+			src.Set(x, y, c)
+		}
+	}
+
+	r, _, _, _ := Scale(src, 2).At(0, 0).RGBA()
+	if r>>8 < 0x70 || r>>8 > 0x90 {
+		t.Errorf("Scale() red = %#x, want a grey around 0x80", r>>8)
+	}
+}
+
+func TestPlaceholder(t *testing.T) {
+	for _, kind := range append(PlaceholderKinds, "unknown") {
+		content, err := Placeholder(kind)
+		if err != nil {
+			t.Fatalf("Placeholder(%q) error = %v", kind, err)
+		}
+		if _, err := png.Decode(bytes.NewReader(content)); err != nil {
+			t.Errorf("Placeholder(%q) is not a PNG: %v", kind, err)
+		}
+	}
+}
+
+func TestPlaceholderKind(t *testing.T) {
+	tests := map[string]string{
+		"application/pdf": "pdf",
+		"application/vnd.openxmlformats-officedocument.wordprocessingml.document":   "document",
+		"application/vnd.openxmlformats-officedocument.presentationml.presentation": "presentation",
+		"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         "spreadsheet",
+		"image/webp": "image",
+		"":           "file",
+	}
+	for contentType, want := range tests {
+		if got := PlaceholderKind(contentType); got != want {
+			t.Errorf("PlaceholderKind(%q) = %q, want %q", contentType, got, want)
+		}
+	}
+}
+
+func TestRenderUnsupported(t *testing.T) {
+	if _, err := Render("/nonexistent", "application/zip"); err != ErrUnsupported {
+		t.Errorf("Render() error = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestRenderRejectsHugeImages(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+
+	// Declare 60000×60000 pixels in the IHDR chunk, which follows the
+	// 8 byte signature, and fix up its checksum
+	data := buf.Bytes()
+	ihdr := data[8+8 : 8+8+13]
+	binary.BigEndian.PutUint32(ihdr[0:4], 60000)
+	binary.BigEndian.PutUint32(ihdr[4:8], 60000)
+	binary.BigEndian.PutUint32(data[8+8+13:], crc32.ChecksumIEEE(data[8+4:8+8+13]))
+
+	path := filepath.Join(t.TempDir(), "huge.png")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := Render(path, "image/png"); !errors.Is(err, ErrTooLarge) {
+		t.Errorf("Render() error = %v, want ErrTooLarge", err)
+	}
+}
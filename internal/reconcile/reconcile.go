@@ -0,0 +1,129 @@
+// Package reconcile compares the objects in the storage bucket with the
+// metadata that refers to them, to find files nothing points at and records
+// whose files are gone.
+package reconcile
+
+import (
+	"backend/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Reference is an object key the metadata expects to find in the bucket.
+type Reference struct {
+	Key        string
+	DocumentID primitive.ObjectID
+	// Required references are document files. Previews are optional because
+	// search falls back to a placeholder and they can be rendered again.
+	Required bool
+	// Since is when the reference was made. Files referenced after the cutoff
+	// of a comparison may still be on their way to the bucket.
+	Since time.Time
+}
+
+// MissingObject is a required reference whose object is not in the bucket.
+type MissingObject struct {
+	DocumentID primitive.ObjectID
+	Key        string
+}
+
+// Report is the outcome of a comparison.
+type Report struct {
+	// Objects is the number of objects in the bucket
+	Objects int
+	// OrphanObjects are objects no metadata refers to
+	OrphanObjects []types.Object
+	// MissingObjects are document files that are not in the bucket
+	MissingObjects []MissingObject
+}
+
+// DocumentReferences lists the objects that documents and their revisions
+// refer to. Documents in the trash still own their files until they are purged.
+func DocumentReferences(documents []models.Document, revisions []models.Revision) []Reference {
+	var references []Reference
+	for _, document := range documents {
+		// The original upload is stored under the bare document ID. The
+		// creation time comes from the ID because created_at is not in UTC.
+		references = append(references, Reference{
+			Key:        document.ID.Hex(),
+			DocumentID: document.ID,
+			Required:   true,
+			Since:      document.ID.Timestamp(),
+		})
+
+		for _, key := range []string{document.ThumbnailKey, document.PreviewKey} {
+			if key != "" {
+				references = append(references, Reference{Key: key, DocumentID: document.ID, Since: document.ID.Timestamp()})
+			}
+		}
+	}
+
+	for _, revision := range revisions {
+		references = append(references, Reference{
+			Key:        revision.ObjectKey,
+			DocumentID: revision.DocumentID,
+			Required:   true,
+			Since:      revision.UploadedAt,
+		})
+	}
+
+	return references
+}
+
+// Compare matches the objects in a bucket against the references to them.
+// Nothing younger than cutoff is reported, so uploads in progress are left
+// alone. Keys under one of ignoredPrefixes are never orphans.
+func Compare(objects []types.Object, references []Reference, ignoredPrefixes []string, cutoff time.Time) Report {
+	report := Report{Objects: len(objects)}
+
+	stored := map[string]bool{}
+	for _, object := range objects {
+		stored[aws.ToString(object.Key)] = true
+	}
+
+	referenced := map[string]bool{}
+	reported := map[string]bool{}
+	for _, reference := range references {
+		referenced[reference.Key] = true
+
+		if !reference.Required || stored[reference.Key] || reported[reference.Key] || reference.Since.After(cutoff) {
+			continue
+		}
+		reported[reference.Key] = true
+		report.MissingObjects = append(report.MissingObjects, MissingObject{DocumentID: reference.DocumentID, Key: reference.Key})
+	}
+
+	for _, object := range objects {
+		key := aws.ToString(object.Key)
+		if referenced[key] || hasAnyPrefix(key, ignoredPrefixes) {
+			continue
+		}
+		if object.LastModified == nil || object.LastModified.After(cutoff) {
+			continue
+		}
+		report.OrphanObjects = append(report.OrphanObjects, object)
+	}
+
+	sort.Slice(report.OrphanObjects, func(i, j int) bool {
+		return aws.ToString(report.OrphanObjects[i].Key) < aws.ToString(report.OrphanObjects[j].Key)
+	})
+	sort.Slice(report.MissingObjects, func(i, j int) bool {
+		return report.MissingObjects[i].Key < report.MissingObjects[j].Key
+	})
+
+	return report
+}
+
+func hasAnyPrefix(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
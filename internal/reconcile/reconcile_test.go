@@ -0,0 +1,61 @@
+package reconcile
+
+import (
+	"backend/internal/models"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func object(key string, lastModified time.Time) types.Object {
+	return types.Object{Key: aws.String(key), LastModified: aws.Time(lastModified)}
+}
+
+func TestCompare(t *testing.T) {
+	now := time.Now()
+	old := now.Add(-48 * time.Hour)
+	cutoff := now.Add(-24 * time.Hour)
+
+	document := models.Document{
+		ID:           primitive.NewObjectIDFromTimestamp(old),
+		ThumbnailKey: "previews/a/thumbnail.jpg",
+	}
+	missing := models.Document{ID: primitive.NewObjectIDFromTimestamp(old)}
+	uploading := models.Document{ID: primitive.NewObjectIDFromTimestamp(now)}
+	revisions := []models.Revision{
+		{DocumentID: document.ID, Version: 1, ObjectKey: document.ID.Hex(), UploadedAt: old},
+		{DocumentID: document.ID, Version: 2, ObjectKey: document.ID.Hex() + "/v2", UploadedAt: old},
+	}
+
+	objects := []types.Object{
+		object(document.ID.Hex(), old),
+		object("previews/placeholders/pdf.png", old),
+		object("stray", old),
+		object("fresh", now),
+	}
+
+	references := DocumentReferences([]models.Document{document, missing, uploading}, revisions)
+	report := Compare(objects, references, []string{"previews/placeholders/"}, cutoff)
+
+	if report.Objects != len(objects) {
+		t.Errorf("Compare() Objects = %d, want %d", report.Objects, len(objects))
+	}
+
+	if len(report.OrphanObjects) != 1 || aws.ToString(report.OrphanObjects[0].Key) != "stray" {
+		t.Errorf("Compare() OrphanObjects = %v, want only the old unreferenced object", report.OrphanObjects)
+	}
+
+	// The missing preview is optional and the recent upload may still arrive
+	want := map[string]bool{missing.ID.Hex(): true, document.ID.Hex() + "/v2": true}
+	if len(report.MissingObjects) != len(want) {
+		t.Fatalf("Compare() MissingObjects = %v, want %v", report.MissingObjects, want)
+	}
+	for _, m := range report.MissingObjects {
+		if !want[m.Key] {
+			t.Errorf("Compare() reported %q as missing", m.Key)
+		}
+	}
+}
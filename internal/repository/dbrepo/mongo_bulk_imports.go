@@ -0,0 +1,125 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateBulkImport inserts a new bulk import.
+func (m *MongoDBRepo) CreateBulkImport(bulkImport *models.BulkImport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.bulkImportsCollection.InsertOne(ctx, bulkImport)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetBulkImport retrieves a bulk import by its ID.
+func (m *MongoDBRepo) GetBulkImport(id primitive.ObjectID) (*models.BulkImport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var bulkImport models.BulkImport
+	err := m.bulkImportsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&bulkImport)
+	if err != nil {
+		return nil, err
+	}
+
+	return &bulkImport, nil
+}
+
+// GetBulkImportsByUser returns the bulk imports of a user, newest first.
+func (m *MongoDBRepo) GetBulkImportsByUser(userID primitive.ObjectID) ([]models.BulkImport, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	return m.findBulkImports(bson.M{"user_id": userID}, opts)
+}
+
+// FindBulkImportsByStatus returns the bulk imports in the given state.
+func (m *MongoDBRepo) FindBulkImportsByStatus(status string) ([]models.BulkImport, error) {
+	return m.findBulkImports(bson.M{"status": status})
+}
+
+func (m *MongoDBRepo) findBulkImports(filter bson.M, opts ...*options.FindOptions) ([]models.BulkImport, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	cursor, err := m.bulkImportsCollection.Find(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	bulkImports := []models.BulkImport{}
+
+	for cursor.Next(ctx) {
+		var bulkImport models.BulkImport
+		if err := cursor.Decode(&bulkImport); err != nil {
+			return nil, err
+		}
+		bulkImports = append(bulkImports, bulkImport)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return bulkImports, nil
+}
+
+// SetBulkImportStatus moves a bulk import from one state to another. It
+// reports false when the import was not in the expected state, so two
+// requests cannot start the same import.
+func (m *MongoDBRepo) SetBulkImportStatus(id primitive.ObjectID, from, to string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.bulkImportsCollection.UpdateOne(ctx, bson.M{"_id": id, "status": from}, bson.M{"$set": bson.M{"status": to}})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// FinishBulkImport records the final state and per-file results of a bulk import.
+func (m *MongoDBRepo) FinishBulkImport(bulkImport *models.BulkImport) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"status":       bulkImport.Status,
+			"error":        bulkImport.Error,
+			"results":      bulkImport.Results,
+			"completed_at": bulkImport.CompletedAt,
+		},
+	}
+
+	_, err := m.bulkImportsCollection.UpdateOne(ctx, bson.M{"_id": bulkImport.ID}, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteBulkImport removes a bulk import.
+func (m *MongoDBRepo) DeleteBulkImport(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.bulkImportsCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,61 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// exportTimeout bounds a catalogue export, which is read as fast as the
+// client downloads it.
+const exportTimeout = 15 * time.Minute
+
+// ExportCatalogue calls fn with every document matching the query, as seen by
+// moderators, joined with its rating and uploader. Documents are read from a
+// cursor one at a time, so exports of any size use little memory.
+func (m *MongoDBRepo) ExportCatalogue(query models.DocumentQuery, fn func(entry *models.CatalogueEntry) error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), exportTimeout)
+	defer cancel()
+
+	cursor, err := m.metadataCollection.Aggregate(ctx, cataloguePipeline(m.withTextMatches(ctx, query)))
+	if err != nil {
+		return err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var entry models.CatalogueEntry
+		if err := cursor.Decode(&entry); err != nil {
+			return err
+		}
+		if err := fn(&entry); err != nil {
+			return err
+		}
+	}
+
+	return cursor.Err()
+}
+
+// cataloguePipeline filters documents like the moderator search and looks up
+// their rating and the name of their uploader.
+func cataloguePipeline(query models.DocumentQuery) []bson.M {
+	return []bson.M{
+		{"$match": documentFilter(query, true)},
+		{"$sort": bson.M{"_id": 1}},
+		{"$lookup": bson.M{"from": "ratings", "localField": "rating_id", "foreignField": "_id", "as": "rating"}},
+		{"$lookup": bson.M{"from": "user_info", "localField": "user_id", "foreignField": "_id", "as": "uploader"}},
+		{"$addFields": bson.M{
+			"average_rating": bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$rating.average_rating", 0}}, 0}},
+			"times_rated":    bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$rating.times_rated", 0}}, 0}},
+			"uploader_name": bson.M{"$trim": bson.M{"input": bson.M{"$concat": bson.A{
+				bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$uploader.first_name", 0}}, ""}},
+				" ",
+				bson.M{"$ifNull": bson.A{bson.M{"$arrayElemAt": bson.A{"$uploader.last_name", 0}}, ""}},
+			}}}},
+		}},
+		// Only the uploader's name leaves the database
+		{"$project": bson.M{"rating": 0, "uploader": 0}},
+	}
+}
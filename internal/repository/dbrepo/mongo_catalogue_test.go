@@ -0,0 +1,23 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func Test_cataloguePipeline(t *testing.T) {
+	pipeline := cataloguePipeline(models.DocumentQuery{Subject: "maths"})
+
+	match, ok := pipeline[0]["$match"].(bson.M)
+	if !ok || !reflect.DeepEqual(match, documentFilter(models.DocumentQuery{Subject: "maths"}, true)) {
+		t.Errorf("cataloguePipeline() starts with %v, want the moderator search filter", pipeline[0])
+	}
+
+	last := pipeline[len(pipeline)-1]["$project"].(bson.M)
+	if last["uploader"] != 0 {
+		t.Errorf("cataloguePipeline() ends with %v, want the uploader record removed", last)
+	}
+}
@@ -0,0 +1,139 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateCollection inserts a new collection.
+func (m *MongoDBRepo) CreateCollection(collection *models.Collection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.collectionsCollection.InsertOne(ctx, collection)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetCollection retrieves a collection by its ID.
+func (m *MongoDBRepo) GetCollection(id primitive.ObjectID) (*models.Collection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var collection models.Collection
+	err := m.collectionsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&collection)
+	if err != nil {
+		return nil, err
+	}
+
+	return &collection, nil
+}
+
+// GetCollectionsByUser returns the collections owned by a user, most recently
+// updated first.
+func (m *MongoDBRepo) GetCollectionsByUser(userID primitive.ObjectID) ([]models.Collection, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "updated_at", Value: -1}})
+	cursor, err := m.collectionsCollection.Find(ctx, bson.M{"user_id": userID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	collections := []models.Collection{}
+
+	for cursor.Next(ctx) {
+		var collection models.Collection
+		if err := cursor.Decode(&collection); err != nil {
+			return nil, err
+		}
+		collections = append(collections, collection)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return collections, nil
+}
+
+// UpdateCollection stores the editable fields of a collection.
+func (m *MongoDBRepo) UpdateCollection(collection *models.Collection) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	update := bson.M{
+		"$set": bson.M{
+			"title":        collection.Title,
+			"description":  collection.Description,
+			"document_ids": collection.DocumentIDs,
+			"public":       collection.Public,
+			"updated_at":   collection.UpdatedAt,
+		},
+	}
+
+	_, err := m.collectionsCollection.UpdateOne(ctx, bson.M{"_id": collection.ID}, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// DeleteCollection removes a collection. The documents in it are not touched.
+func (m *MongoDBRepo) DeleteCollection(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.collectionsCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetDocumentsByIDs returns the documents with the given IDs in the order of
+// ids. IDs of documents that no longer exist are skipped.
+func (m *MongoDBRepo) GetDocumentsByIDs(ids []primitive.ObjectID) ([]models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	cursor, err := m.metadataCollection.Find(ctx, bson.M{"_id": bson.M{"$in": ids}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	found := map[primitive.ObjectID]models.Document{}
+
+	for cursor.Next(ctx) {
+		var doc models.Document
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		found[doc.ID] = doc
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	documents := []models.Document{}
+	for _, id := range ids {
+		if doc, ok := found[id]; ok {
+			documents = append(documents, doc)
+		}
+	}
+
+	return documents, nil
+}
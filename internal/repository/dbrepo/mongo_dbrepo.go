@@ -5,6 +5,7 @@ import (
 	"backend/pkg/db"
 	"context"
 	"log"
+	"regexp"
 	"strings"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 
 type MongoDBRepo struct {
 	//database           db.Database
+	runInTransaction        transactionRunner
 	userInfoCollection      db.Collection
 	passwordResetCollection db.Collection
 	metadataCollection      db.Collection
@@ -22,11 +24,23 @@ type MongoDBRepo struct {
 	faqsCollection          db.Collection
 	moderateCollection      db.Collection
 	reportsCollection       db.Collection
+	followsCollection       db.Collection
+	revisionsCollection     db.Collection
+	uploadSessionCollection db.Collection
+	documentTextCollection  db.Collection
+	collectionsCollection   db.Collection
+	downloadsCollection     db.Collection
+	bulkImportsCollection   db.Collection
+	shareLinksCollection    db.Collection
+	ltiPlatformsCollection  db.Collection
+	ltiLoginsCollection     db.Collection
+	ltiDeepLinksCollection  db.Collection
 }
 
 func NewMongoDBRepo(client *mongo.Client, databaseName string) *MongoDBRepo {
 	database := client.Database(databaseName)
 	return &MongoDBRepo{
+		runInTransaction:        sessionTransactions(client),
 		userInfoCollection:      database.Collection("user_info"),
 		passwordResetCollection: database.Collection("password_reset"),
 		metadataCollection:      database.Collection("metadata"),
@@ -34,11 +48,43 @@ func NewMongoDBRepo(client *mongo.Client, databaseName string) *MongoDBRepo {
 		faqsCollection:          database.Collection("faqs"),
 		moderateCollection:      database.Collection("moderate"),
 		reportsCollection:       database.Collection("reports"),
+		followsCollection:       database.Collection("follows"),
+		revisionsCollection:     database.Collection("revisions"),
+		uploadSessionCollection: database.Collection("upload_sessions"),
+		documentTextCollection:  database.Collection("document_text"),
+		collectionsCollection:   database.Collection("collections"),
+		downloadsCollection:     database.Collection("downloads"),
+		bulkImportsCollection:   database.Collection("bulk_imports"),
+		shareLinksCollection:    database.Collection("share_links"),
+		ltiPlatformsCollection:  database.Collection("lti_platforms"),
+		ltiLoginsCollection:     database.Collection("lti_logins"),
+		ltiDeepLinksCollection:  database.Collection("lti_deep_links"),
 	}
 }
 
 const dbTimeout = time.Second * 3
 
+// transactionRunner runs fn in a transaction, passing it the context its
+// operations must use to take part in it.
+type transactionRunner func(ctx context.Context, fn func(ctx context.Context) error) error
+
+// sessionTransactions runs transactions in sessions of client, retrying them
+// when the server reports a transient error.
+func sessionTransactions(client *mongo.Client) transactionRunner {
+	return func(ctx context.Context, fn func(ctx context.Context) error) error {
+		session, err := client.StartSession()
+		if err != nil {
+			return err
+		}
+		defer session.EndSession(ctx)
+
+		_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+			return nil, fn(sc)
+		})
+		return err
+	}
+}
+
 func (m *MongoDBRepo) GetUserByEmail(email string) (*models.User, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
@@ -111,61 +157,100 @@ func (m *MongoDBRepo) CreateDocumentRating(initialRating *models.Rating) error {
 	return nil
 }
 
-func (m *MongoDBRepo) FindDocuments(title, subject, grade string, correctRole bool) ([]models.Document, error) {
+func (m *MongoDBRepo) FindDocuments(query models.DocumentQuery, correctRole bool) ([]models.Document, error) {
 
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel() // ensures that the context is canceled after the function returns
 
 	collection := m.metadataCollection
 
-	// Creates a filter for the query that only searches for the given parameters
-	filter := bson.M{}
+	filter := documentFilter(m.withTextMatches(ctx, query), correctRole)
+
+	// Cursor that loops through the DB to find the matching documents
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	// Slice that will hold all documents that match the filter
+	var documents []models.Document
 
-	if title != "" {
-		filter["title"] = bson.M{"$regex": primitive.Regex{Pattern: title, Options: "i"}}
+	// Loops through the filter and adds the documents to the slice
+	for cursor.Next(ctx) {
+		var doc models.Document
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+
+		documents = append(documents, doc)
 	}
-	if subject != "" {
-		filter["subject"] = bson.M{"$regex": primitive.Regex{Pattern: subject, Options: "i"}}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
 	}
-	if grade != "" {
-		normalizedGrade := strings.ToLower(strings.TrimSpace(grade))
-		normalizedGrade = strings.ReplaceAll(normalizedGrade, " ", "")
-		normalizedGrade = strings.TrimPrefix(normalizedGrade, "grade")
 
-		filter["$or"] = []bson.M{
-			{"grade": normalizedGrade},
-			{"grade": bson.M{"$regex": primitive.Regex{Pattern: normalizedGrade, Options: "i"}}},
+	return documents, nil
+}
+
+// FindDocumentsByValidationStatus returns the documents whose file validation is in the given state.
+func (m *MongoDBRepo) FindDocumentsByValidationStatus(status string) ([]models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.metadataCollection
+
+	cursor, err := collection.Find(ctx, bson.M{"validation_status": status})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var documents []models.Document
+
+	for cursor.Next(ctx) {
+		var doc models.Document
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
 		}
+		documents = append(documents, doc)
 	}
 
-	if correctRole == true {
-		// Admin or moderator role, allow viewing of all documents except denied
-		filter["moderated"] = bson.M{"$in": []bool{true, false}}
-		//filter["approvalStatus"] = bson.M{"$ne": "denied"}
-	} else {
-		// Regular user, only show approved documents
-		filter["moderated"] = true
-		filter["reported"] = false
-		filter["approvalStatus"] = "approved"
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+// FindDocumentsAwaitingScan returns the documents whose file has not been
+// scanned for malware yet, including those uploaded before scanning existed.
+func (m *MongoDBRepo) FindDocumentsAwaitingScan() ([]models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.metadataCollection
+
+	filter := bson.M{
+		"$or": []bson.M{
+			{"scan_status": "pending"},
+			{"scan_status": bson.M{"$exists": false}},
+		},
 	}
 
-	// Cursor that loops through the DB to find the matching documents
 	cursor, err := collection.Find(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
 	defer cursor.Close(ctx)
 
-	// Slice that will hold all documents that match the filter
 	var documents []models.Document
 
-	// Loops through the filter and adds the documents to the slice
 	for cursor.Next(ctx) {
 		var doc models.Document
 		if err := cursor.Decode(&doc); err != nil {
 			return nil, err
 		}
-
 		documents = append(documents, doc)
 	}
 
@@ -176,6 +261,117 @@ func (m *MongoDBRepo) FindDocuments(title, subject, grade string, correctRole bo
 	return documents, nil
 }
 
+// documentFilter creates a filter for the query that only searches for the
+// given parameters and hides the documents the caller may not see.
+func documentFilter(query models.DocumentQuery, correctRole bool) bson.M {
+	filter := bson.M{}
+
+	// Conditions that need their own $or are combined with $and
+	var conditions []bson.M
+
+	if query.Text != "" {
+		pattern := primitive.Regex{Pattern: regexp.QuoteMeta(query.Text), Options: "i"}
+		text := []bson.M{
+			{"title": bson.M{"$regex": pattern}},
+			{"description": bson.M{"$regex": pattern}},
+			{"tags": bson.M{"$regex": pattern}},
+		}
+		if len(query.TextMatches) > 0 {
+			// Documents whose extracted text matches
+			text = append(text, bson.M{"_id": bson.M{"$in": query.TextMatches}})
+		}
+		conditions = append(conditions, bson.M{"$or": text})
+	}
+	if query.Title != "" {
+		filter["title"] = bson.M{"$regex": primitive.Regex{Pattern: query.Title, Options: "i"}}
+	}
+	if query.Subject != "" {
+		filter["subject"] = bson.M{"$regex": primitive.Regex{Pattern: query.Subject, Options: "i"}}
+	}
+	if query.Grade != "" {
+		normalizedGrade := normalizeGrade(query.Grade)
+
+		conditions = append(conditions, bson.M{"$or": []bson.M{
+			{"grade": normalizedGrade},
+			{"grade": bson.M{"$regex": primitive.Regex{Pattern: normalizedGrade, Options: "i"}}},
+		}})
+	}
+	if len(query.Tags) > 0 {
+		// Every requested tag must be present
+		filter["tags"] = bson.M{"$all": query.Tags}
+	}
+	if query.Language != "" {
+		filter["language"] = query.Language
+	}
+	if query.Licence != "" {
+		filter["licence"] = query.Licence
+	}
+	if query.ContentType != "" {
+		filter["content_type"] = query.ContentType
+	}
+	if query.Filename != "" {
+		filter["original_filename"] = bson.M{"$regex": primitive.Regex{Pattern: regexp.QuoteMeta(query.Filename), Options: "i"}}
+	}
+	if size := rangeCondition(query.MinSize, query.MaxSize); size != nil {
+		filter["size"] = size
+	}
+	if pages := rangeCondition(int64(query.MinPages), int64(query.MaxPages)); pages != nil {
+		filter["page_count"] = pages
+	}
+
+	// Documents in the trash are never found
+	filter["deleted_at"] = bson.M{"$exists": false}
+
+	if len(conditions) == 1 {
+		for key, value := range conditions[0] {
+			filter[key] = value
+		}
+	} else if len(conditions) > 1 {
+		filter["$and"] = conditions
+	}
+
+	if correctRole == true {
+		// Admin or moderator role, allow viewing of all documents except denied
+		filter["moderated"] = bson.M{"$in": []bool{true, false}}
+		// Files that failed validation never reach moderators
+		filter["validation_status"] = bson.M{"$ne": "failed"}
+		// Drafts stay private until their owner submits them for review
+		filter["approvalStatus"] = bson.M{"$ne": "draft"}
+		//filter["approvalStatus"] = bson.M{"$ne": "denied"}
+	} else {
+		// Regular user, only show approved documents
+		filter["moderated"] = true
+		filter["reported"] = false
+		filter["approvalStatus"] = "approved"
+	}
+
+	return filter
+}
+
+// rangeCondition matches values between min and max inclusive. A bound of
+// zero is left open, and no condition is returned when both are.
+func rangeCondition(min, max int64) bson.M {
+	condition := bson.M{}
+	if min > 0 {
+		condition["$gte"] = min
+	}
+	if max > 0 {
+		condition["$lte"] = max
+	}
+	if len(condition) == 0 {
+		return nil
+	}
+	return condition
+}
+
+// normalizeGrade reduces user input such as "Grade 10" to the bare "10"
+// that is stored on documents.
+func normalizeGrade(grade string) string {
+	normalizedGrade := strings.ToLower(strings.TrimSpace(grade))
+	normalizedGrade = strings.ReplaceAll(normalizedGrade, " ", "")
+	return strings.TrimPrefix(normalizedGrade, "grade")
+}
+
 func (m *MongoDBRepo) GetFAQs() ([]models.FAQs, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
 	defer cancel()
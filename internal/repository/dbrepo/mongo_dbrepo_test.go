@@ -134,9 +134,7 @@ func TestMongoDBRepo_FindDocuments(t *testing.T) {
 		reportsCollection       db.Collection
 	}
 	type args struct {
-		title       string
-		subject     string
-		grade       string
+		query       models.DocumentQuery
 		correctRole bool
 	}
 	tests := []struct {
@@ -159,7 +157,7 @@ func TestMongoDBRepo_FindDocuments(t *testing.T) {
 				moderateCollection:      tt.fields.moderateCollection,
 				reportsCollection:       tt.fields.reportsCollection,
 			}
-			got, err := m.FindDocuments(tt.args.title, tt.args.subject, tt.args.grade, tt.args.correctRole)
+			got, err := m.FindDocuments(tt.args.query, tt.args.correctRole)
 			if (err != nil) != tt.wantErr {
 				t.Errorf("FindDocuments() error = %v, wantErr %v", err, tt.wantErr)
 				return
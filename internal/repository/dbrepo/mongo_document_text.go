@@ -0,0 +1,112 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+	"log"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// maxTextMatches bounds how many documents a search finds through their
+// extracted text, best matches first.
+const maxTextMatches = 500
+
+// CreateDocumentTextIndex creates the text index searches match the extracted
+// text of documents with. It does nothing when the index already exists.
+func (m *MongoDBRepo) CreateDocumentTextIndex() error {
+	// Building the index over existing text can take a while
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	_, err := m.documentTextCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "content", Value: "text"}},
+		Options: options.Index().SetName("content_text"),
+	})
+	return err
+}
+
+// withTextMatches fills in the documents whose extracted text matches the
+// search text of query. When the text cannot be searched, e.g. because the
+// index is still being built, the search falls back to the metadata alone.
+func (m *MongoDBRepo) withTextMatches(ctx context.Context, query models.DocumentQuery) models.DocumentQuery {
+	if query.Text == "" {
+		return query
+	}
+
+	score := bson.M{"score": bson.M{"$meta": "textScore"}}
+	opts := options.Find().SetProjection(score).SetSort(score).SetLimit(maxTextMatches)
+
+	cursor, err := m.documentTextCollection.Find(ctx, bson.M{"$text": bson.M{"$search": query.Text}}, opts)
+	if err != nil {
+		log.Printf("Error searching document text: %v", err)
+		return query
+	}
+	defer cursor.Close(ctx)
+
+	var matches []primitive.ObjectID
+	for cursor.Next(ctx) {
+		var match struct {
+			ID primitive.ObjectID `bson:"_id"`
+		}
+		if err := cursor.Decode(&match); err != nil {
+			log.Printf("Error searching document text: %v", err)
+			return query
+		}
+		matches = append(matches, match.ID)
+	}
+	if err := cursor.Err(); err != nil {
+		log.Printf("Error searching document text: %v", err)
+		return query
+	}
+
+	query.TextMatches = matches
+	return query
+}
+
+// GetDocumentText retrieves the extracted text of a document.
+func (m *MongoDBRepo) GetDocumentText(documentID primitive.ObjectID) (*models.DocumentText, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.documentTextCollection
+
+	var text models.DocumentText
+	err := collection.FindOne(ctx, bson.M{"_id": documentID}).Decode(&text)
+	if err != nil {
+		return nil, err
+	}
+
+	return &text, nil
+}
+
+// SaveDocumentText stores the extracted text of a document, replacing any
+// text extracted from an earlier version of the file.
+func (m *MongoDBRepo) SaveDocumentText(text *models.DocumentText) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.documentTextCollection
+
+	update := bson.M{
+		"$set": bson.M{
+			"content":      text.Content,
+			"pages":        text.Pages,
+			"slides":       text.Slides,
+			"object_key":   text.ObjectKey,
+			"etag":         text.ETag,
+			"extracted_at": text.ExtractedAt,
+		},
+	}
+
+	_, err := collection.UpdateOne(ctx, bson.M{"_id": text.DocumentID}, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// DeleteDocument removes a document's metadata along with its rating,
+// reports, revisions, extracted text and share links, and takes it out of
+// collections, all in one transaction so a failure never leaves part of it
+// behind.
+func (m *MongoDBRepo) DeleteDocument(document *models.Document) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	return m.runInTransaction(ctx, func(ctx context.Context) error {
+		_, err := m.metadataCollection.DeleteOne(ctx, bson.M{"_id": document.ID})
+		if err != nil {
+			return err
+		}
+
+		_, err = m.ratingsCollection.DeleteOne(ctx, bson.M{"_id": document.RatingID})
+		if err != nil {
+			return err
+		}
+
+		_, err = m.reportsCollection.DeleteMany(ctx, bson.M{"documentID": document.ID})
+		if err != nil {
+			return err
+		}
+
+		_, err = m.revisionsCollection.DeleteMany(ctx, bson.M{"document_id": document.ID})
+		if err != nil {
+			return err
+		}
+
+		_, err = m.documentTextCollection.DeleteOne(ctx, bson.M{"_id": document.ID})
+		if err != nil {
+			return err
+		}
+
+		_, err = m.shareLinksCollection.DeleteMany(ctx, bson.M{"document_id": document.ID})
+		if err != nil {
+			return err
+		}
+
+		_, err = m.collectionsCollection.UpdateMany(ctx, bson.M{"document_ids": document.ID}, bson.M{"$pull": bson.M{"document_ids": document.ID}})
+		return err
+	})
+}
+
+// BackfillModeratedAt gives approved documents that predate approval times
+// being recorded the time of their last approval in the moderation log, or
+// their upload time when the log has none. The feed and harvesting order by
+// approval time and would otherwise never show them.
+func (m *MongoDBRepo) BackfillModeratedAt() error {
+	// Runs once over the whole catalogue, so it gets more time than a request
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	filter := bson.M{"approvalStatus": "approved", "moderated_at": bson.M{"$exists": false}}
+
+	approvals := bson.M{"$filter": bson.M{
+		"input": "$moderations",
+		"cond":  bson.M{"$eq": bson.A{"$$this.approvalStatus", "approved"}},
+	}}
+	pipeline := []bson.M{
+		{"$match": filter},
+		{"$lookup": bson.M{"from": "moderate", "localField": "_id", "foreignField": "documentID", "as": "moderations"}},
+		{"$project": bson.M{"moderated_at": bson.M{"$ifNull": bson.A{
+			bson.M{"$max": bson.M{"$map": bson.M{"input": approvals, "in": "$$this.moderatedAt"}}},
+			bson.M{"$toDate": "$_id"},
+		}}}},
+		{"$merge": bson.M{"into": "metadata", "on": "_id", "whenMatched": "merge", "whenNotMatched": "discard"}},
+	}
+
+	// $merge writes the results back, so the cursor itself is empty
+	cursor, err := m.metadataCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return err
+	}
+
+	return cursor.Close(ctx)
+}
+
+// FindDocumentsBySHA256 returns every document whose file has one of the
+// given SHA-256 hashes, oldest first.
+func (m *MongoDBRepo) FindDocumentsBySHA256(hashes []string) ([]models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	documents := []models.Document{}
+	if len(hashes) == 0 {
+		return documents, nil
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := m.metadataCollection.Find(ctx, bson.M{"sha256": bson.M{"$in": hashes}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	for cursor.Next(ctx) {
+		var doc models.Document
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		documents = append(documents, doc)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
+
+// GetDerivedDocuments returns the documents that were derived from parentID,
+// oldest first.
+func (m *MongoDBRepo) GetDerivedDocuments(parentID primitive.ObjectID) ([]models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := m.metadataCollection.Find(ctx, bson.M{"derived_from": parentID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	return decodeDocuments(ctx, cursor)
+}
+
+// SetDocumentApprovalStatus changes the approval status of a document that is
+// not in the trash to to, provided it currently is one of from, and marks it
+// as unmoderated. It reports whether the document was changed.
+func (m *MongoDBRepo) SetDocumentApprovalStatus(id primitive.ObjectID, from []string, to string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "approvalStatus": bson.M{"$in": from}, "deleted_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"approvalStatus": to, "moderated": false}}
+
+	result, err := m.metadataCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
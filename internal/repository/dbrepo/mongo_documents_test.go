@@ -0,0 +1,216 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"backend/pkg/db"
+	"context"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// inTransaction marks the context a fake transaction passes to its operations.
+type inTransaction struct{}
+
+// fakeTransactions runs fn directly, with a context that shows it ran inside
+// the transaction.
+func fakeTransactions(ctx context.Context, fn func(ctx context.Context) error) error {
+	return fn(context.WithValue(ctx, inTransaction{}, true))
+}
+
+func TestMongoDBRepo_DeleteDocument(t *testing.T) {
+	document := &models.Document{ID: primitive.NewObjectID(), RatingID: primitive.NewObjectID()}
+
+	tests := []struct {
+		name        string
+		metadataErr error
+		wantDeletes []string
+		wantErr     bool
+	}{
+		{
+			name:        "removes every record of the document",
+			wantDeletes: []string{"metadata", "ratings", "reports", "revisions", "document_text", "share_links"},
+		},
+		{
+			name:        "stops when the metadata cannot be removed",
+			metadataErr: mongo.ErrClientDisconnected,
+			wantDeletes: []string{"metadata"},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var deletes []string
+			collection := func(name string, err error) db.Collection {
+				deleteFunc := func(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+					if ctx.Value(inTransaction{}) == nil {
+						t.Errorf("DeleteDocument() deleted from %s outside the transaction", name)
+					}
+					deletes = append(deletes, name)
+					return &mongo.DeleteResult{}, err
+				}
+				return &db.MongoCollectionMock{DeleteOneFunc: deleteFunc, DeleteManyFunc: deleteFunc}
+			}
+
+			m := &MongoDBRepo{
+				runInTransaction:       fakeTransactions,
+				metadataCollection:     collection("metadata", tt.metadataErr),
+				ratingsCollection:      collection("ratings", nil),
+				reportsCollection:      collection("reports", nil),
+				revisionsCollection:    collection("revisions", nil),
+				documentTextCollection: collection("document_text", nil),
+				shareLinksCollection:   collection("share_links", nil),
+				collectionsCollection:  &db.MongoCollectionMock{},
+			}
+
+			err := m.DeleteDocument(document)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("DeleteDocument() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(deletes) != len(tt.wantDeletes) {
+				t.Fatalf("DeleteDocument() deleted from %v, want %v", deletes, tt.wantDeletes)
+			}
+			for i := range deletes {
+				if deletes[i] != tt.wantDeletes[i] {
+					t.Errorf("DeleteDocument() deleted from %v, want %v", deletes, tt.wantDeletes)
+					break
+				}
+			}
+		})
+	}
+}
+
+func Test_documentFilter(t *testing.T) {
+	t.Run("public search only shows approved documents", func(t *testing.T) {
+		filter := documentFilter(models.DocumentQuery{}, false)
+		if filter["approvalStatus"] != "approved" || filter["moderated"] != true {
+			t.Errorf("documentFilter() = %v, want approved documents only", filter)
+		}
+	})
+
+	t.Run("documents in the trash are hidden from every role", func(t *testing.T) {
+		for _, correctRole := range []bool{false, true} {
+			filter := documentFilter(models.DocumentQuery{}, correctRole)
+			if !reflect.DeepEqual(filter["deleted_at"], bson.M{"$exists": false}) {
+				t.Errorf("documentFilter(correctRole=%v) = %v, want deleted documents excluded", correctRole, filter)
+			}
+		}
+	})
+
+	t.Run("moderators do not see drafts", func(t *testing.T) {
+		filter := documentFilter(models.DocumentQuery{}, true)
+		if !reflect.DeepEqual(filter["approvalStatus"], bson.M{"$ne": "draft"}) {
+			t.Errorf("documentFilter() = %v, want drafts excluded", filter)
+		}
+	})
+
+	t.Run("exact filters", func(t *testing.T) {
+		query := models.DocumentQuery{Tags: []string{"algebra"}, Language: "en", Licence: "CC-BY-4.0", ContentType: "application/pdf"}
+		filter := documentFilter(query, false)
+		if filter["language"] != "en" || filter["licence"] != "CC-BY-4.0" || filter["content_type"] != "application/pdf" {
+			t.Errorf("documentFilter() = %v", filter)
+		}
+		if !reflect.DeepEqual(filter["tags"], bson.M{"$all": []string{"algebra"}}) {
+			t.Errorf("documentFilter() tags = %v", filter["tags"])
+		}
+	})
+
+	t.Run("file filters", func(t *testing.T) {
+		query := models.DocumentQuery{Filename: "notes (1).pdf", MinSize: 1024, MaxPages: 10}
+		filter := documentFilter(query, false)
+		pattern := filter["original_filename"].(bson.M)["$regex"].(primitive.Regex).Pattern
+		if pattern != `notes \(1\)\.pdf` {
+			t.Errorf("documentFilter() filename pattern = %q, want it quoted", pattern)
+		}
+		if !reflect.DeepEqual(filter["size"], bson.M{"$gte": int64(1024)}) {
+			t.Errorf("documentFilter() size = %v", filter["size"])
+		}
+		if !reflect.DeepEqual(filter["page_count"], bson.M{"$lte": int64(10)}) {
+			t.Errorf("documentFilter() page_count = %v", filter["page_count"])
+		}
+	})
+
+	t.Run("text and grade are combined", func(t *testing.T) {
+		filter := documentFilter(models.DocumentQuery{Text: "x+1", Grade: "Grade 10"}, true)
+		and, ok := filter["$and"].([]bson.M)
+		if !ok || len(and) != 2 {
+			t.Fatalf("documentFilter() $and = %v, want text and grade conditions", filter["$and"])
+		}
+		pattern := and[0]["$or"].([]bson.M)[0]["title"].(bson.M)["$regex"].(primitive.Regex).Pattern
+		if pattern != `x\+1` {
+			t.Errorf("documentFilter() text pattern = %q, want it quoted", pattern)
+		}
+	})
+
+	t.Run("text matches extracted text", func(t *testing.T) {
+		id := primitive.NewObjectID()
+		filter := documentFilter(models.DocumentQuery{Text: "photosynthesis", TextMatches: []primitive.ObjectID{id}}, false)
+		or := filter["$or"].([]bson.M)
+		if !reflect.DeepEqual(or[len(or)-1], bson.M{"_id": bson.M{"$in": []primitive.ObjectID{id}}}) {
+			t.Errorf("documentFilter() $or = %v, want the text matches", or)
+		}
+
+		filter = documentFilter(models.DocumentQuery{Text: "photosynthesis"}, false)
+		if or := filter["$or"].([]bson.M); len(or) != 3 {
+			t.Errorf("documentFilter() $or = %v, want no empty $in", or)
+		}
+	})
+
+	t.Run("single condition is not wrapped", func(t *testing.T) {
+		filter := documentFilter(models.DocumentQuery{Grade: "10"}, true)
+		if _, ok := filter["$and"]; ok {
+			t.Errorf("documentFilter() = %v, want no $and", filter)
+		}
+		if _, ok := filter["$or"]; !ok {
+			t.Errorf("documentFilter() = %v, want grade $or", filter)
+		}
+	})
+}
+
+func TestMongoDBRepo_FindDocumentsBySHA256(t *testing.T) {
+	t.Run("does not query without hashes", func(t *testing.T) {
+		// A query would dereference the missing collection
+		m := &MongoDBRepo{}
+
+		documents, err := m.FindDocumentsBySHA256(nil)
+		if err != nil {
+			t.Fatalf("FindDocumentsBySHA256() error = %v", err)
+		}
+		if len(documents) != 0 {
+			t.Errorf("FindDocumentsBySHA256() = %v, want no documents", documents)
+		}
+	})
+}
+
+func TestMongoDBRepo_BackfillModeratedAt(t *testing.T) {
+	var stages []string
+	m := &MongoDBRepo{
+		metadataCollection: &db.MongoCollectionMock{
+			AggregateFunc: func(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+				for _, stage := range pipeline.([]bson.M) {
+					for name := range stage {
+						stages = append(stages, name)
+					}
+				}
+				match := pipeline.([]bson.M)[0]["$match"].(bson.M)
+				if match["approvalStatus"] != "approved" || !reflect.DeepEqual(match["moderated_at"], bson.M{"$exists": false}) {
+					t.Errorf("BackfillModeratedAt() matches %v, want approved documents without an approval time", match)
+				}
+				return mongo.NewCursorFromDocuments(nil, nil, nil)
+			},
+		},
+	}
+
+	if err := m.BackfillModeratedAt(); err != nil {
+		t.Fatalf("BackfillModeratedAt() error = %v", err)
+	}
+
+	want := []string{"$match", "$lookup", "$project", "$merge"}
+	if !reflect.DeepEqual(stages, want) {
+		t.Errorf("BackfillModeratedAt() stages = %v, want %v", stages, want)
+	}
+}
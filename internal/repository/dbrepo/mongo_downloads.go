@@ -0,0 +1,95 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+	"regexp"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecordDownload stores a download event and bumps the download counter of
+// the document.
+func (m *MongoDBRepo) RecordDownload(event *models.DownloadEvent) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.downloadsCollection.InsertOne(ctx, event)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.metadataCollection.UpdateOne(ctx, bson.M{"_id": event.DocumentID}, bson.M{"$inc": bson.M{"download_count": 1}})
+	return err
+}
+
+// GetPopularDocuments returns, per subject, the documents downloaded most
+// often since the given time. An empty subject includes every subject.
+func (m *MongoDBRepo) GetPopularDocuments(since time.Time, subject string, limit int) ([]models.SubjectPopularity, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	cursor, err := m.downloadsCollection.Aggregate(ctx, popularityPipeline(since, subject, limit))
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	subjects := []models.SubjectPopularity{}
+
+	for cursor.Next(ctx) {
+		var popularity models.SubjectPopularity
+		if err := cursor.Decode(&popularity); err != nil {
+			return nil, err
+		}
+		subjects = append(subjects, popularity)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return subjects, nil
+}
+
+// popularityPipeline counts downloads per document and keeps the top limit
+// documents of each subject. Documents the public may no longer see are
+// dropped before the top documents are taken, so they do not take up places.
+func popularityPipeline(since time.Time, subject string, limit int) []bson.M {
+	match := bson.M{"downloaded_at": bson.M{"$gte": since}}
+	if subject != "" {
+		// Subjects are typed by uploaders, so match them regardless of case
+		match["subject"] = primitive.Regex{Pattern: "^" + regexp.QuoteMeta(subject) + "$", Options: "i"}
+	}
+
+	return []bson.M{
+		{"$match": match},
+		{"$group": bson.M{
+			"_id":       bson.M{"subject": "$subject", "document_id": "$document_id"},
+			"downloads": bson.M{"$sum": 1},
+		}},
+		{"$lookup": bson.M{
+			"from": "metadata",
+			"let":  bson.M{"document_id": "$_id.document_id"},
+			"pipeline": bson.A{
+				bson.M{"$match": bson.M{"$expr": bson.M{"$eq": bson.A{"$_id", "$$document_id"}}}},
+				bson.M{"$match": documentFilter(models.DocumentQuery{}, false)},
+				bson.M{"$project": bson.M{"_id": 1}},
+			},
+			"as": "document",
+		}},
+		{"$match": bson.M{"document": bson.M{"$ne": bson.A{}}}},
+		{"$sort": bson.D{{Key: "downloads", Value: -1}, {Key: "_id.document_id", Value: 1}}},
+		{"$group": bson.M{
+			"_id": "$_id.subject",
+			"documents": bson.M{"$push": bson.M{
+				"document_id": "$_id.document_id",
+				"downloads":   "$downloads",
+			}},
+		}},
+		{"$project": bson.M{"documents": bson.M{"$slice": bson.A{"$documents", limit}}}},
+		{"$sort": bson.M{"_id": 1}},
+	}
+}
@@ -0,0 +1,92 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"backend/pkg/db"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoDBRepo_RecordDownload(t *testing.T) {
+	event := &models.DownloadEvent{ID: primitive.NewObjectID(), DocumentID: primitive.NewObjectID(), DownloadedAt: time.Now()}
+
+	tests := []struct {
+		name        string
+		insertErr   error
+		wantCounted bool
+		wantErr     bool
+	}{
+		{name: "stores event and bumps counter", wantCounted: true},
+		{name: "counter untouched when event is not stored", insertErr: mongo.ErrClientDisconnected, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			counted := false
+			m := &MongoDBRepo{
+				downloadsCollection: &db.MongoCollectionMock{
+					InsertOneFunc: func(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error) {
+						return &mongo.InsertOneResult{}, tt.insertErr
+					},
+				},
+				metadataCollection: &db.MongoCollectionMock{
+					UpdateOneFunc: func(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+						if filter.(bson.M)["_id"] != event.DocumentID {
+							return nil, errors.New("wrong document")
+						}
+						counted = true
+						return &mongo.UpdateResult{ModifiedCount: 1}, nil
+					},
+				},
+			}
+
+			err := m.RecordDownload(event)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("RecordDownload() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if counted != tt.wantCounted {
+				t.Errorf("RecordDownload() counted = %v, want %v", counted, tt.wantCounted)
+			}
+		})
+	}
+}
+
+func Test_popularityPipeline(t *testing.T) {
+	since := time.Now().AddDate(0, 0, -7)
+
+	pipeline := popularityPipeline(since, "Physical Sciences", 5)
+	match := pipeline[0]["$match"].(bson.M)
+	if match["downloaded_at"].(bson.M)["$gte"] != since {
+		t.Errorf("popularityPipeline() does not start at %v: %v", since, match)
+	}
+	if match["subject"].(primitive.Regex).Pattern != `^Physical Sciences$` {
+		t.Errorf("popularityPipeline() subject = %v", match["subject"])
+	}
+
+	if _, ok := popularityPipeline(since, "", 5)[0]["$match"].(bson.M)["subject"]; ok {
+		t.Error("popularityPipeline() filters on subject when none was given")
+	}
+
+	// Unpublished documents must be dropped before the top documents are taken
+	published := -1
+	for i, stage := range pipeline {
+		if lookup, ok := stage["$lookup"].(bson.M); ok {
+			filter := lookup["pipeline"].(bson.A)[1].(bson.M)["$match"].(bson.M)
+			if filter["approvalStatus"] == "approved" {
+				published = i
+			}
+		}
+		if _, ok := stage["$sort"]; ok {
+			if published < 0 || published > i {
+				t.Fatal("popularityPipeline() sorts before dropping unpublished documents")
+			}
+			break
+		}
+	}
+}
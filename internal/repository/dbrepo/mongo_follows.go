@@ -0,0 +1,268 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// FollowUser subscribes followerID to documents approved for followeeID.
+// Following the same educator twice is a no-op.
+func (m *MongoDBRepo) FollowUser(followerID, followeeID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.followsCollection
+
+	filter := bson.M{"follower_id": followerID, "followee_id": followeeID}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":        primitive.NewObjectID(),
+			"created_at": time.Now(),
+		},
+	}
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnfollowUser removes the subscription of followerID on followeeID.
+func (m *MongoDBRepo) UnfollowUser(followerID, followeeID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.followsCollection
+
+	_, err := collection.DeleteOne(ctx, bson.M{"follower_id": followerID, "followee_id": followeeID})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FollowSubject subscribes followerID to documents approved for a subject and grade.
+func (m *MongoDBRepo) FollowSubject(followerID primitive.ObjectID, subject, grade string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.followsCollection
+
+	filter := bson.M{
+		"follower_id": followerID,
+		"subject":     strings.TrimSpace(subject),
+		"grade":       normalizeGrade(grade),
+	}
+	update := bson.M{
+		"$setOnInsert": bson.M{
+			"_id":        primitive.NewObjectID(),
+			"created_at": time.Now(),
+		},
+	}
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// UnfollowSubject removes the subscription of followerID on a subject and grade.
+func (m *MongoDBRepo) UnfollowSubject(followerID primitive.ObjectID, subject, grade string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.followsCollection
+
+	filter := bson.M{
+		"follower_id": followerID,
+		"subject":     strings.TrimSpace(subject),
+		"grade":       normalizeGrade(grade),
+	}
+
+	_, err := collection.DeleteOne(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetFollows returns every educator and subject/grade pair followed by followerID.
+func (m *MongoDBRepo) GetFollows(followerID primitive.ObjectID) ([]models.Follow, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.followsCollection
+
+	cursor, err := collection.Find(ctx, bson.M{"follower_id": followerID})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var follows []models.Follow
+
+	for cursor.Next(ctx) {
+		var follow models.Follow
+		if err := cursor.Decode(&follow); err != nil {
+			return nil, err
+		}
+		follows = append(follows, follow)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return follows, nil
+}
+
+// GetFeed returns up to limit approved documents from the educators and
+// subject/grade pairs followed by userID, newest approval first. The
+// returned cursor is passed back to fetch the next page and is empty once
+// the feed is exhausted.
+func (m *MongoDBRepo) GetFeed(userID primitive.ObjectID, cursor string, limit int) ([]models.Document, string, error) {
+	follows, err := m.GetFollows(userID)
+	if err != nil {
+		return nil, "", err
+	}
+
+	var followedUsers []primitive.ObjectID
+	var sources []bson.M
+	for _, follow := range follows {
+		if !follow.FolloweeID.IsZero() {
+			followedUsers = append(followedUsers, follow.FolloweeID)
+			continue
+		}
+		sources = append(sources, bson.M{
+			"subject": bson.M{"$regex": primitive.Regex{Pattern: "^" + regexp.QuoteMeta(follow.Subject) + "$", Options: "i"}},
+			"grade":   gradeCondition(follow.Grade),
+		})
+	}
+	if len(followedUsers) > 0 {
+		sources = append(sources, bson.M{"user_id": bson.M{"$in": followedUsers}})
+	}
+
+	// Nothing followed yet, so there is nothing to show
+	if len(sources) == 0 {
+		return nil, "", nil
+	}
+
+	filter := bson.M{
+		"moderated":      true,
+		"reported":       false,
+		"approvalStatus": "approved",
+		"deleted_at":     bson.M{"$exists": false},
+		"$or":            sources,
+	}
+
+	if cursor != "" {
+		moderatedAt, lastID, err := decodeFeedCursor(cursor)
+		if err != nil {
+			return nil, "", err
+		}
+		filter["$and"] = []bson.M{
+			{"$or": []bson.M{
+				{"moderated_at": bson.M{"$lt": moderatedAt}},
+				{"moderated_at": moderatedAt, "_id": bson.M{"$lt": lastID}},
+			}},
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.metadataCollection
+
+	// Fetch one extra document to find out whether there is another page
+	opts := options.Find().
+		SetSort(bson.D{{Key: "moderated_at", Value: -1}, {Key: "_id", Value: -1}}).
+		SetLimit(int64(limit + 1))
+
+	results, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, "", err
+	}
+	defer results.Close(ctx)
+
+	var documents []models.Document
+
+	for results.Next(ctx) {
+		var doc models.Document
+		if err := results.Decode(&doc); err != nil {
+			return nil, "", err
+		}
+		documents = append(documents, doc)
+	}
+
+	if err := results.Err(); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+	if len(documents) > limit {
+		documents = documents[:limit]
+		last := documents[len(documents)-1]
+		nextCursor = encodeFeedCursor(last.ModeratedAt, last.ID)
+	}
+
+	return documents, nextCursor, nil
+}
+
+// gradeCondition matches the grades uploaders type for a normalized grade, so
+// a follow of "10" finds documents stored as "10", "Grade 10" or "grade10".
+func gradeCondition(grade string) bson.M {
+	pattern := `^\s*(grade\s*)?` + regexp.QuoteMeta(normalizeGrade(grade)) + `\s*$`
+	return bson.M{"$regex": primitive.Regex{Pattern: pattern, Options: "i"}}
+}
+
+// encodeFeedCursor packs the sort key of the last document on a feed page
+// into an opaque token.
+func encodeFeedCursor(moderatedAt time.Time, id primitive.ObjectID) string {
+	raw := fmt.Sprintf("%d:%s", moderatedAt.UnixMilli(), id.Hex())
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// decodeFeedCursor reverses encodeFeedCursor.
+func decodeFeedCursor(cursor string) (time.Time, primitive.ObjectID, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	parts := strings.SplitN(string(raw), ":", 2)
+	if len(parts) != 2 {
+		return time.Time{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	millis, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	id, err := primitive.ObjectIDFromHex(parts[1])
+	if err != nil {
+		return time.Time{}, primitive.NilObjectID, ErrInvalidCursor
+	}
+
+	return time.UnixMilli(millis).UTC(), id, nil
+}
@@ -0,0 +1,96 @@
+package dbrepo
+
+import (
+	"backend/pkg/db"
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoDBRepo_FollowUser(t *testing.T) {
+	type args struct {
+		followerID primitive.ObjectID
+		followeeID primitive.ObjectID
+	}
+	tests := []struct {
+		name              string
+		followsCollection db.Collection
+		args              args
+		wantErr           bool
+	}{
+		{
+			name: "upserts follow",
+			args: args{followerID: testUserJoe.ID, followeeID: primitive.NewObjectID()},
+			followsCollection: &db.MongoCollectionMock{
+				UpdateOneFunc: func(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+					if len(opts) != 1 || opts[0].Upsert == nil || !*opts[0].Upsert {
+						return nil, errors.New("follow must be an upsert")
+					}
+					if filter.(bson.M)["follower_id"] != testUserJoe.ID {
+						return nil, errors.New("wrong follower")
+					}
+					return &mongo.UpdateResult{UpsertedCount: 1}, nil
+				},
+			},
+		},
+		{
+			name: "database error",
+			args: args{followerID: testUserJoe.ID, followeeID: primitive.NewObjectID()},
+			followsCollection: &db.MongoCollectionMock{
+				UpdateOneFunc: func(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+					return nil, mongo.ErrClientDisconnected
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MongoDBRepo{followsCollection: tt.followsCollection}
+			if err := m.FollowUser(tt.args.followerID, tt.args.followeeID); (err != nil) != tt.wantErr {
+				t.Errorf("FollowUser() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_feedCursor(t *testing.T) {
+	moderatedAt := time.Date(2024, 9, 1, 10, 30, 0, 0, time.UTC)
+	id := primitive.NewObjectID()
+
+	gotTime, gotID, err := decodeFeedCursor(encodeFeedCursor(moderatedAt, id))
+	if err != nil {
+		t.Fatalf("decodeFeedCursor() error = %v", err)
+	}
+	if !gotTime.Equal(moderatedAt) || gotID != id {
+		t.Errorf("decodeFeedCursor() = %v, %v, want %v, %v", gotTime, gotID, moderatedAt, id)
+	}
+
+	if _, _, err := decodeFeedCursor("not a cursor"); !errors.Is(err, ErrInvalidCursor) {
+		t.Errorf("decodeFeedCursor() error = %v, want %v", err, ErrInvalidCursor)
+	}
+}
+
+func Test_gradeCondition(t *testing.T) {
+	for _, follow := range []string{"10", "Grade 10", " grade10 "} {
+		pattern := regexp.MustCompile("(?i)" + gradeCondition(follow)["$regex"].(primitive.Regex).Pattern)
+
+		for _, stored := range []string{"10", "Grade 10", "grade10", "GRADE 10 "} {
+			if !pattern.MatchString(stored) {
+				t.Errorf("gradeCondition(%q) does not match %q", follow, stored)
+			}
+		}
+		for _, stored := range []string{"1", "100", "Grade 11"} {
+			if pattern.MatchString(stored) {
+				t.Errorf("gradeCondition(%q) matches %q", follow, stored)
+			}
+		}
+	}
+}
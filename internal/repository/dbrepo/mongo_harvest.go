@@ -0,0 +1,97 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// HarvestDocuments returns published documents approved in the range of the
+// query, ordered by approval time. Documents approved before approval times
+// were recorded cannot be placed in that order and are left out.
+func (m *MongoDBRepo) HarvestDocuments(query models.HarvestQuery) ([]models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	opts := options.Find().
+		SetSort(bson.D{{Key: "moderated_at", Value: 1}, {Key: "_id", Value: 1}}).
+		SetLimit(int64(query.Limit))
+
+	cursor, err := m.metadataCollection.Find(ctx, harvestFilter(query), opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	return decodeDocuments(ctx, cursor)
+}
+
+// harvestFilter builds the filter selecting the documents of a harvest.
+func harvestFilter(query models.HarvestQuery) bson.M {
+	// Only what public search shows is harvested
+	filter := documentFilter(models.DocumentQuery{}, false)
+
+	// Documents approved before approval times were recorded get one from
+	// BackfillModeratedAt at startup, so this only guards against stragglers
+	moderatedAt := bson.M{"$exists": true}
+	if !query.From.IsZero() {
+		moderatedAt["$gte"] = query.From
+	}
+	if !query.Before.IsZero() {
+		moderatedAt["$lt"] = query.Before
+	}
+	filter["moderated_at"] = moderatedAt
+
+	if len(query.Subjects) > 0 {
+		filter["subject"] = bson.M{"$in": query.Subjects}
+	}
+	if len(query.Grades) > 0 {
+		filter["grade"] = bson.M{"$in": query.Grades}
+	}
+
+	if !query.AfterID.IsZero() {
+		filter["$or"] = []bson.M{
+			{"moderated_at": bson.M{"$gt": query.AfterModeratedAt}},
+			{"moderated_at": query.AfterModeratedAt, "_id": bson.M{"$gt": query.AfterID}},
+		}
+	}
+
+	return filter
+}
+
+// GetPublishedSubjectsAndGrades returns the distinct subjects and grades of
+// published documents.
+func (m *MongoDBRepo) GetPublishedSubjectsAndGrades() (*models.SubjectsAndGrades, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	pipeline := []bson.M{
+		{"$match": documentFilter(models.DocumentQuery{}, false)},
+		{"$group": bson.M{
+			"_id":      nil,
+			"subjects": bson.M{"$addToSet": "$subject"},
+			"grades":   bson.M{"$addToSet": "$grade"},
+		}},
+	}
+
+	cursor, err := m.metadataCollection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	result := &models.SubjectsAndGrades{}
+	if cursor.Next(ctx) {
+		if err := cursor.Decode(result); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
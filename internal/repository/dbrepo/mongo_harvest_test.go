@@ -0,0 +1,60 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func Test_harvestFilter(t *testing.T) {
+	t.Run("only published documents with an approval time", func(t *testing.T) {
+		filter := harvestFilter(models.HarvestQuery{})
+		if filter["approvalStatus"] != "approved" || filter["reported"] != false {
+			t.Errorf("harvestFilter() = %v, want published documents only", filter)
+		}
+		if !reflect.DeepEqual(filter["moderated_at"], bson.M{"$exists": true}) {
+			t.Errorf("harvestFilter() moderated_at = %v", filter["moderated_at"])
+		}
+		if _, ok := filter["$or"]; ok {
+			t.Errorf("harvestFilter() = %v, want no continuation on the first page", filter)
+		}
+	})
+
+	t.Run("range, sets and continuation", func(t *testing.T) {
+		from := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		before := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		last := time.Date(2024, 1, 15, 8, 30, 0, 0, time.UTC)
+		lastID := primitive.NewObjectID()
+
+		filter := harvestFilter(models.HarvestQuery{
+			From:             from,
+			Before:           before,
+			Subjects:         []string{"Maths", "maths"},
+			Grades:           []string{"10", "Grade 10"},
+			AfterModeratedAt: last,
+			AfterID:          lastID,
+		})
+
+		if !reflect.DeepEqual(filter["moderated_at"], bson.M{"$exists": true, "$gte": from, "$lt": before}) {
+			t.Errorf("harvestFilter() moderated_at = %v", filter["moderated_at"])
+		}
+		if !reflect.DeepEqual(filter["subject"], bson.M{"$in": []string{"Maths", "maths"}}) {
+			t.Errorf("harvestFilter() subject = %v", filter["subject"])
+		}
+		if !reflect.DeepEqual(filter["grade"], bson.M{"$in": []string{"10", "Grade 10"}}) {
+			t.Errorf("harvestFilter() grade = %v", filter["grade"])
+		}
+
+		want := []bson.M{
+			{"moderated_at": bson.M{"$gt": last}},
+			{"moderated_at": last, "_id": bson.M{"$gt": lastID}},
+		}
+		if !reflect.DeepEqual(filter["$or"], want) {
+			t.Errorf("harvestFilter() $or = %v, want %v", filter["$or"], want)
+		}
+	})
+}
@@ -0,0 +1,187 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateLTIPlatform stores a platform registration.
+func (m *MongoDBRepo) CreateLTIPlatform(platform *models.LTIPlatform) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.ltiPlatformsCollection.InsertOne(ctx, platform)
+	return err
+}
+
+// GetLTIPlatforms returns every platform registration, oldest first.
+func (m *MongoDBRepo) GetLTIPlatforms() ([]models.LTIPlatform, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+	cursor, err := m.ltiPlatformsCollection.Find(ctx, bson.M{}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	platforms := []models.LTIPlatform{}
+	for cursor.Next(ctx) {
+		var platform models.LTIPlatform
+		if err := cursor.Decode(&platform); err != nil {
+			return nil, err
+		}
+		platforms = append(platforms, platform)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return platforms, nil
+}
+
+// GetLTIPlatform finds the registration of a platform by its issuer and the
+// client ID it gave this tool. An empty client ID matches any registration
+// of the issuer.
+func (m *MongoDBRepo) GetLTIPlatform(issuer, clientID string) (*models.LTIPlatform, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	filter := bson.M{"issuer": issuer}
+	if clientID != "" {
+		filter["client_id"] = clientID
+	}
+
+	var platform models.LTIPlatform
+	err := m.ltiPlatformsCollection.FindOne(ctx, filter).Decode(&platform)
+	if err != nil {
+		return nil, err
+	}
+
+	return &platform, nil
+}
+
+// GetLTIPlatformByID returns a platform registration.
+func (m *MongoDBRepo) GetLTIPlatformByID(id primitive.ObjectID) (*models.LTIPlatform, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var platform models.LTIPlatform
+	err := m.ltiPlatformsCollection.FindOne(ctx, bson.M{"_id": id}).Decode(&platform)
+	if err != nil {
+		return nil, err
+	}
+
+	return &platform, nil
+}
+
+// DeleteLTIPlatform removes a platform registration and reports whether it
+// existed.
+func (m *MongoDBRepo) DeleteLTIPlatform(id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.ltiPlatformsCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount == 1, nil
+}
+
+// CreateLTILogin stores the state of an OIDC login until its launch arrives.
+func (m *MongoDBRepo) CreateLTILogin(login *models.LTILogin) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.ltiLoginsCollection.InsertOne(ctx, login)
+	return err
+}
+
+// TakeLTILogin returns the unexpired login with the given state and removes
+// it, so that a launch cannot be replayed. It returns mongo.ErrNoDocuments if
+// there is no such login or another request took it first.
+func (m *MongoDBRepo) TakeLTILogin(state string, now time.Time) (*models.LTILogin, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var login models.LTILogin
+	err := m.ltiLoginsCollection.FindOne(ctx, bson.M{"state": state, "expires_at": bson.M{"$gt": now}}).Decode(&login)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := m.ltiLoginsCollection.DeleteOne(ctx, bson.M{"_id": login.ID})
+	if err != nil {
+		return nil, err
+	}
+	if result.DeletedCount != 1 {
+		return nil, mongo.ErrNoDocuments
+	}
+
+	return &login, nil
+}
+
+// CreateLTIDeepLink stores a Deep Linking request until the teacher has
+// made their selection.
+func (m *MongoDBRepo) CreateLTIDeepLink(deepLink *models.LTIDeepLink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.ltiDeepLinksCollection.InsertOne(ctx, deepLink)
+	return err
+}
+
+// GetLTIDeepLink returns the unexpired Deep Linking request with the given
+// token.
+func (m *MongoDBRepo) GetLTIDeepLink(token string, now time.Time) (*models.LTIDeepLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var deepLink models.LTIDeepLink
+	err := m.ltiDeepLinksCollection.FindOne(ctx, bson.M{"token": token, "expires_at": bson.M{"$gt": now}}).Decode(&deepLink)
+	if err != nil {
+		return nil, err
+	}
+
+	return &deepLink, nil
+}
+
+// DeleteLTIDeepLink removes a Deep Linking request and reports whether it
+// was still there, so that only one selection is returned to the platform.
+func (m *MongoDBRepo) DeleteLTIDeepLink(id primitive.ObjectID) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	result, err := m.ltiDeepLinksCollection.DeleteOne(ctx, bson.M{"_id": id})
+	if err != nil {
+		return false, err
+	}
+
+	return result.DeletedCount == 1, nil
+}
+
+// DeleteExpiredLTISessions removes logins and Deep Linking requests that
+// expired before now.
+func (m *MongoDBRepo) DeleteExpiredLTISessions(now time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	filter := bson.M{"expires_at": bson.M{"$lte": now}}
+
+	_, err := m.ltiLoginsCollection.DeleteMany(ctx, filter)
+	if err != nil {
+		return err
+	}
+
+	_, err = m.ltiDeepLinksCollection.DeleteMany(ctx, filter)
+	return err
+}
@@ -0,0 +1,54 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"backend/pkg/db"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoDBRepo_TakeLTILogin(t *testing.T) {
+	login := models.LTILogin{ID: primitive.NewObjectID(), State: "state", Nonce: "nonce", PlatformID: primitive.NewObjectID()}
+
+	tests := []struct {
+		name    string
+		found   bool
+		deleted int64
+		wantErr error
+	}{
+		{name: "takes the login", found: true, deleted: 1},
+		{name: "unknown or expired state", found: false, wantErr: mongo.ErrNoDocuments},
+		{name: "replayed concurrently", found: true, deleted: 0, wantErr: mongo.ErrNoDocuments},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MongoDBRepo{
+				ltiLoginsCollection: &db.MongoCollectionMock{
+					FindOneFunc: func(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult {
+						if !tt.found {
+							return mongo.NewSingleResultFromDocument(models.LTILogin{}, mongo.ErrNoDocuments, nil)
+						}
+						return mongo.NewSingleResultFromDocument(login, nil, nil)
+					},
+					DeleteOneFunc: func(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+						return &mongo.DeleteResult{DeletedCount: tt.deleted}, nil
+					},
+				},
+			}
+
+			got, err := m.TakeLTILogin("state", time.Now())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TakeLTILogin() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && (got.Nonce != login.Nonce || got.PlatformID != login.PlatformID) {
+				t.Errorf("TakeLTILogin() = %+v, want %+v", got, login)
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// scanTimeout bounds queries that read a whole collection, which take far
+// longer than the lookups covered by dbTimeout.
+const scanTimeout = time.Minute
+
+// GetAllDocuments returns every document, including those in the trash.
+func (m *MongoDBRepo) GetAllDocuments() ([]models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	cursor, err := m.metadataCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	return decodeDocuments(ctx, cursor)
+}
+
+// GetAllRevisions returns every revision of every document.
+func (m *MongoDBRepo) GetAllRevisions() ([]models.Revision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), scanTimeout)
+	defer cancel()
+
+	cursor, err := m.revisionsCollection.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	revisions := []models.Revision{}
+	for cursor.Next(ctx) {
+		var revision models.Revision
+		if err := cursor.Decode(&revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
@@ -0,0 +1,242 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateRevision inserts a new revision of a document.
+func (m *MongoDBRepo) CreateRevision(revision *models.Revision) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.revisionsCollection
+
+	_, err := collection.InsertOne(ctx, revision)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// CreateInitialRevision records the original file of a document uploaded
+// before revisions existed as its version 1, unless that is already done.
+func (m *MongoDBRepo) CreateInitialRevision(revision *models.Revision) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.revisionsCollection
+
+	// An upsert, so concurrent first uploads of a new version record it once
+	filter := bson.M{"document_id": revision.DocumentID, "version": 1}
+	update := bson.M{"$setOnInsert": revision}
+
+	_, err := collection.UpdateOne(ctx, filter, update, options.Update().SetUpsert(true))
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// NextRevisionVersion allocates the next version number of a document. The
+// counter is kept on the document and incremented atomically, so concurrent
+// uploads never get the same version. Documents that predate the counter
+// continue after atLeast, the newest version already recorded.
+func (m *MongoDBRepo) NextRevisionVersion(documentID primitive.ObjectID, atLeast int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.metadataCollection
+
+	filter := bson.M{"_id": documentID, "deleted_at": bson.M{"$exists": false}}
+	update := []bson.M{{"$set": bson.M{
+		"latest_version": bson.M{"$add": bson.A{
+			bson.M{"$max": bson.A{bson.M{"$ifNull": bson.A{"$latest_version", 1}}, atLeast}},
+			1,
+		}},
+	}}}
+	opts := options.FindOneAndUpdate().
+		SetReturnDocument(options.After).
+		SetProjection(bson.M{"latest_version": 1})
+
+	var result struct {
+		LatestVersion int `bson:"latest_version"`
+	}
+	err := collection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&result)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.LatestVersion, nil
+}
+
+// GetRevisions returns every revision of a document, oldest first.
+func (m *MongoDBRepo) GetRevisions(documentID primitive.ObjectID) ([]models.Revision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.revisionsCollection
+
+	opts := options.Find().SetSort(bson.D{{Key: "version", Value: 1}})
+	cursor, err := collection.Find(ctx, bson.M{"document_id": documentID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []models.Revision
+
+	for cursor.Next(ctx) {
+		var revision models.Revision
+		if err := cursor.Decode(&revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// GetRevision retrieves a single revision of a document by its version number.
+func (m *MongoDBRepo) GetRevision(documentID primitive.ObjectID, version int) (*models.Revision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.revisionsCollection
+
+	var revision models.Revision
+	err := collection.FindOne(ctx, bson.M{"document_id": documentID, "version": version}).Decode(&revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// GetLatestApprovedRevision retrieves the newest approved revision of a document.
+func (m *MongoDBRepo) GetLatestApprovedRevision(documentID primitive.ObjectID) (*models.Revision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.revisionsCollection
+
+	filter := bson.M{"document_id": documentID, "approvalStatus": "approved"}
+	opts := options.FindOne().SetSort(bson.D{{Key: "version", Value: -1}})
+
+	var revision models.Revision
+	err := collection.FindOne(ctx, filter, opts).Decode(&revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return &revision, nil
+}
+
+// UpdateRevision applies update to one revision of a document.
+func (m *MongoDBRepo) UpdateRevision(documentID primitive.ObjectID, version int, update bson.M) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.revisionsCollection
+
+	_, err := collection.UpdateOne(ctx, bson.M{"document_id": documentID, "version": version}, update)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// FindRevisionsAwaitingValidation returns the later versions of documents
+// whose file has not been validated yet, including those uploaded before
+// versions were validated.
+func (m *MongoDBRepo) FindRevisionsAwaitingValidation() ([]models.Revision, error) {
+	return m.findRevisionsAwaiting("validation_status")
+}
+
+// FindRevisionsAwaitingScan returns the later versions of documents whose
+// file has not been scanned for malware yet, including those uploaded before
+// versions were scanned.
+func (m *MongoDBRepo) FindRevisionsAwaitingScan() ([]models.Revision, error) {
+	return m.findRevisionsAwaiting("scan_status")
+}
+
+// findRevisionsAwaiting returns the later versions whose status field is
+// pending or missing.
+func (m *MongoDBRepo) findRevisionsAwaiting(field string) ([]models.Revision, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.revisionsCollection
+
+	filter := bson.M{
+		"version": bson.M{"$gt": 1},
+		"$or": []bson.M{
+			{field: "pending"},
+			{field: bson.M{"$exists": false}},
+		},
+	}
+
+	cursor, err := collection.Find(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var revisions []models.Revision
+
+	for cursor.Next(ctx) {
+		var revision models.Revision
+		if err := cursor.Decode(&revision); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return revisions, nil
+}
+
+// SetRevisionStatus records the moderation outcome of a revision. Approving a
+// revision makes it the current version of the document if it is newer than
+// the one being served.
+func (m *MongoDBRepo) SetRevisionStatus(documentID primitive.ObjectID, version int, approvalStatus string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.revisionsCollection
+
+	filter := bson.M{"document_id": documentID, "version": version}
+	update := bson.M{"$set": bson.M{"approvalStatus": approvalStatus}}
+
+	_, err := collection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		return err
+	}
+
+	if approvalStatus != "approved" {
+		return nil
+	}
+
+	metadataUpdate := bson.M{"$max": bson.M{"current_version": version}}
+
+	_, err = m.metadataCollection.UpdateOne(ctx, bson.M{"_id": documentID}, metadataUpdate)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"backend/pkg/db"
+	"context"
+	"errors"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoDBRepo_NextRevisionVersion(t *testing.T) {
+	documentID := primitive.NewObjectID()
+
+	tests := []struct {
+		name    string
+		found   bool
+		want    int
+		wantErr error
+	}{
+		{name: "allocates the incremented counter", found: true, want: 4},
+		{name: "document missing or in the trash", found: false, wantErr: mongo.ErrNoDocuments},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MongoDBRepo{
+				metadataCollection: &db.MongoCollectionMock{
+					FindOneAndUpdateFunc: func(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+						if filter.(bson.M)["_id"] != documentID {
+							t.Errorf("NextRevisionVersion() filter = %v", filter)
+						}
+						if len(opts) != 1 || opts[0].ReturnDocument == nil || *opts[0].ReturnDocument != options.After {
+							t.Error("NextRevisionVersion() must return the incremented counter")
+						}
+						if !tt.found {
+							return mongo.NewSingleResultFromDocument(bson.M{}, mongo.ErrNoDocuments, nil)
+						}
+						return mongo.NewSingleResultFromDocument(bson.M{"_id": documentID, "latest_version": tt.want}, nil, nil)
+					},
+				},
+			}
+
+			got, err := m.NextRevisionVersion(documentID, 3)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NextRevisionVersion() error = %v, want %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("NextRevisionVersion() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMongoDBRepo_CreateInitialRevision(t *testing.T) {
+	revision := &models.Revision{ID: primitive.NewObjectID(), DocumentID: primitive.NewObjectID(), Version: 1}
+
+	m := &MongoDBRepo{
+		revisionsCollection: &db.MongoCollectionMock{
+			UpdateOneFunc: func(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+				if len(opts) != 1 || opts[0].Upsert == nil || !*opts[0].Upsert {
+					return nil, errors.New("the initial revision must be an upsert")
+				}
+				if f := filter.(bson.M); f["document_id"] != revision.DocumentID || f["version"] != 1 {
+					return nil, errors.New("wrong revision")
+				}
+				return &mongo.UpdateResult{UpsertedCount: 1}, nil
+			},
+		},
+	}
+
+	if err := m.CreateInitialRevision(revision); err != nil {
+		t.Errorf("CreateInitialRevision() error = %v", err)
+	}
+}
@@ -0,0 +1,116 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// CreateShareLink inserts a new share link.
+func (m *MongoDBRepo) CreateShareLink(link *models.ShareLink) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	_, err := m.shareLinksCollection.InsertOne(ctx, link)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetShareLinksByDocument returns every share link of a document, newest first.
+func (m *MongoDBRepo) GetShareLinksByDocument(documentID primitive.ObjectID) ([]models.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: -1}})
+	cursor, err := m.shareLinksCollection.Find(ctx, bson.M{"document_id": documentID}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	links := []models.ShareLink{}
+
+	for cursor.Next(ctx) {
+		var link models.ShareLink
+		if err := cursor.Decode(&link); err != nil {
+			return nil, err
+		}
+		links = append(links, link)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return links, nil
+}
+
+// RevokeShareLink revokes a link of a document. It reports whether a link
+// that was not yet revoked was found.
+func (m *MongoDBRepo) RevokeShareLink(documentID, linkID primitive.ObjectID, revokedAt time.Time) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	filter := bson.M{"_id": linkID, "document_id": documentID, "revoked_at": bson.M{"$exists": false}}
+	result, err := m.shareLinksCollection.UpdateOne(ctx, filter, bson.M{"$set": bson.M{"revoked_at": revokedAt}})
+	if err != nil {
+		return false, err
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// GetUsableShareLink returns the link with the given token without counting
+// a use, provided it is still usable at now. It returns mongo.ErrNoDocuments
+// for unknown and unusable links.
+func (m *MongoDBRepo) GetUsableShareLink(token string, now time.Time) (*models.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	var link models.ShareLink
+	err := m.shareLinksCollection.FindOne(ctx, shareLinkUsableFilter(token, now)).Decode(&link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// UseShareLink counts a use of the link with the given token, provided it is
+// still usable at now, and returns the link as it was before the use. It
+// returns mongo.ErrNoDocuments for unknown and unusable links. The checks and
+// the count are a single update, so concurrent uses cannot exceed the limit.
+func (m *MongoDBRepo) UseShareLink(token string, now time.Time) (*models.ShareLink, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.Before)
+
+	var link models.ShareLink
+	err := m.shareLinksCollection.FindOneAndUpdate(ctx, shareLinkUsableFilter(token, now), bson.M{"$inc": bson.M{"uses": 1}}, opts).Decode(&link)
+	if err != nil {
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// shareLinkUsableFilter matches the link with the given token if it is
+// neither revoked, expired nor used up at now.
+func shareLinkUsableFilter(token string, now time.Time) bson.M {
+	return bson.M{
+		"token":      token,
+		"revoked_at": bson.M{"$exists": false},
+		"$and": []bson.M{
+			{"$or": []bson.M{{"expires_at": bson.M{"$exists": false}}, {"expires_at": bson.M{"$gt": now}}}},
+			{"$or": []bson.M{{"max_uses": 0}, {"$expr": bson.M{"$lt": bson.A{"$uses", "$max_uses"}}}}},
+		},
+	}
+}
@@ -0,0 +1,52 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"backend/pkg/db"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMongoDBRepo_UseShareLink(t *testing.T) {
+	link := models.ShareLink{ID: primitive.NewObjectID(), Token: "token", DocumentID: primitive.NewObjectID(), MaxUses: 1}
+
+	tests := []struct {
+		name    string
+		found   bool
+		wantErr error
+	}{
+		{name: "counts a use", found: true},
+		{name: "unknown, unusable or used up concurrently", found: false, wantErr: mongo.ErrNoDocuments},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := &MongoDBRepo{
+				shareLinksCollection: &db.MongoCollectionMock{
+					FindOneAndUpdateFunc: func(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+						if len(opts) != 1 || opts[0].ReturnDocument == nil || *opts[0].ReturnDocument != options.Before {
+							t.Error("UseShareLink() must return the link as it was before the use")
+						}
+						if !tt.found {
+							return mongo.NewSingleResultFromDocument(models.ShareLink{}, mongo.ErrNoDocuments, nil)
+						}
+						return mongo.NewSingleResultFromDocument(link, nil, nil)
+					},
+				},
+			}
+
+			got, err := m.UseShareLink("token", time.Now())
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("UseShareLink() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr == nil && got.DocumentID != link.DocumentID {
+				t.Errorf("UseShareLink() = %+v, want %+v", got, link)
+			}
+		})
+	}
+}
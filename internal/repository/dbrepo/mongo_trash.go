@@ -0,0 +1,84 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// TrashDocument moves a document to the trash. Documents already in the
+// trash keep their original deletion time.
+func (m *MongoDBRepo) TrashDocument(id, deletedBy primitive.ObjectID, deletedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	filter := bson.M{"_id": id, "deleted_at": bson.M{"$exists": false}}
+	update := bson.M{"$set": bson.M{"deleted_at": deletedAt, "deleted_by": deletedBy}}
+
+	_, err := m.metadataCollection.UpdateOne(ctx, filter, update)
+	return err
+}
+
+// RestoreDocument takes a document out of the trash.
+func (m *MongoDBRepo) RestoreDocument(id primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	update := bson.M{"$unset": bson.M{"deleted_at": "", "deleted_by": ""}}
+
+	_, err := m.metadataCollection.UpdateOne(ctx, bson.M{"_id": id}, update)
+	return err
+}
+
+// GetTrashedDocuments returns the documents of a user that are in the trash,
+// most recently deleted first. The nil ID returns the trash of every user.
+func (m *MongoDBRepo) GetTrashedDocuments(userID primitive.ObjectID) ([]models.Document, error) {
+	filter := bson.M{"deleted_at": bson.M{"$exists": true}}
+	if !userID.IsZero() {
+		filter["user_id"] = userID
+	}
+
+	return m.findTrashedDocuments(filter, options.Find().SetSort(bson.D{{Key: "deleted_at", Value: -1}}))
+}
+
+// FindDocumentsTrashedBefore returns the documents that were moved to the
+// trash before cutoff.
+func (m *MongoDBRepo) FindDocumentsTrashedBefore(cutoff time.Time) ([]models.Document, error) {
+	return m.findTrashedDocuments(bson.M{"deleted_at": bson.M{"$lt": cutoff}}, options.Find())
+}
+
+func (m *MongoDBRepo) findTrashedDocuments(filter bson.M, opts *options.FindOptions) ([]models.Document, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	cursor, err := m.metadataCollection.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	return decodeDocuments(ctx, cursor)
+}
+
+// decodeDocuments reads every document from a cursor.
+func decodeDocuments(ctx context.Context, cursor *mongo.Cursor) ([]models.Document, error) {
+	documents := []models.Document{}
+	for cursor.Next(ctx) {
+		var doc models.Document
+		if err := cursor.Decode(&doc); err != nil {
+			return nil, err
+		}
+		documents = append(documents, doc)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return documents, nil
+}
@@ -0,0 +1,87 @@
+package dbrepo
+
+import (
+	"backend/internal/models"
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// CreateUploadSession records a document ID issued to a user for an upload.
+func (m *MongoDBRepo) CreateUploadSession(session *models.UploadSession) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.uploadSessionCollection
+
+	_, err := collection.InsertOne(ctx, session)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetUploadSession retrieves the upload session of a document ID.
+func (m *MongoDBRepo) GetUploadSession(documentID primitive.ObjectID) (*models.UploadSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.uploadSessionCollection
+
+	var session models.UploadSession
+	err := collection.FindOne(ctx, bson.M{"_id": documentID}).Decode(&session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &session, nil
+}
+
+// DeleteUploadSession removes the upload session of a document ID once it has
+// been used or cleaned up.
+func (m *MongoDBRepo) DeleteUploadSession(documentID primitive.ObjectID) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.uploadSessionCollection
+
+	_, err := collection.DeleteOne(ctx, bson.M{"_id": documentID})
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// GetExpiredUploadSessions returns the upload sessions that expired before now.
+func (m *MongoDBRepo) GetExpiredUploadSessions(now time.Time) ([]models.UploadSession, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dbTimeout)
+	defer cancel()
+
+	collection := m.uploadSessionCollection
+
+	cursor, err := collection.Find(ctx, bson.M{"expires_at": bson.M{"$lt": now}})
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(ctx)
+
+	var sessions []models.UploadSession
+
+	for cursor.Next(ctx) {
+		var session models.UploadSession
+		if err := cursor.Decode(&session); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+
+	if err := cursor.Err(); err != nil {
+		return nil, err
+	}
+
+	return sessions, nil
+}
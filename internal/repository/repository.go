@@ -2,8 +2,11 @@ package repository
 
 import (
 	"backend/internal/models"
+	"io"
+	"time"
 
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 
 	"go.mongodb.org/mongo-driver/bson"
@@ -16,7 +19,7 @@ type DatabaseRepo interface {
 	GetUserByID(id primitive.ObjectID) (*models.User, error)
 	RegisterUser(user *models.User) error
 	UploadDocumentMetadata(document *models.Document) error
-	FindDocuments(title, subject, grade string, correctRole bool) ([]models.Document, error)
+	FindDocuments(query models.DocumentQuery, correctRole bool) ([]models.Document, error)
 	GetFAQs() ([]models.FAQs, error)
 	GetDocumentByID(id primitive.ObjectID) (*models.Document, error)
 	GetDocumentRating(id primitive.ObjectID) (*models.Rating, error)
@@ -28,6 +31,76 @@ type DatabaseRepo interface {
 	UpdateDocumentsByID(documentID primitive.ObjectID, updateData bson.M) error
 	InsertModerationData(userID, documentID primitive.ObjectID, approvalStatus, comments string) error
 	InsertReport(report bson.M) (*mongo.InsertOneResult, error)
+	FollowUser(followerID, followeeID primitive.ObjectID) error
+	UnfollowUser(followerID, followeeID primitive.ObjectID) error
+	FollowSubject(followerID primitive.ObjectID, subject, grade string) error
+	UnfollowSubject(followerID primitive.ObjectID, subject, grade string) error
+	GetFollows(followerID primitive.ObjectID) ([]models.Follow, error)
+	BackfillModeratedAt() error
+	GetFeed(userID primitive.ObjectID, cursor string, limit int) ([]models.Document, string, error)
+	CreateRevision(revision *models.Revision) error
+	CreateInitialRevision(revision *models.Revision) error
+	NextRevisionVersion(documentID primitive.ObjectID, atLeast int) (int, error)
+	GetRevisions(documentID primitive.ObjectID) ([]models.Revision, error)
+	GetRevision(documentID primitive.ObjectID, version int) (*models.Revision, error)
+	GetLatestApprovedRevision(documentID primitive.ObjectID) (*models.Revision, error)
+	SetRevisionStatus(documentID primitive.ObjectID, version int, approvalStatus string) error
+	UpdateRevision(documentID primitive.ObjectID, version int, update bson.M) error
+	FindRevisionsAwaitingValidation() ([]models.Revision, error)
+	FindRevisionsAwaitingScan() ([]models.Revision, error)
+	CreateUploadSession(session *models.UploadSession) error
+	GetUploadSession(documentID primitive.ObjectID) (*models.UploadSession, error)
+	DeleteUploadSession(documentID primitive.ObjectID) error
+	GetExpiredUploadSessions(now time.Time) ([]models.UploadSession, error)
+	FindDocumentsByValidationStatus(status string) ([]models.Document, error)
+	FindDocumentsAwaitingScan() ([]models.Document, error)
+	GetDocumentText(documentID primitive.ObjectID) (*models.DocumentText, error)
+	SaveDocumentText(text *models.DocumentText) error
+	DeleteDocument(document *models.Document) error
+	CreateCollection(collection *models.Collection) error
+	GetCollection(id primitive.ObjectID) (*models.Collection, error)
+	GetCollectionsByUser(userID primitive.ObjectID) ([]models.Collection, error)
+	UpdateCollection(collection *models.Collection) error
+	DeleteCollection(id primitive.ObjectID) error
+	GetDocumentsByIDs(ids []primitive.ObjectID) ([]models.Document, error)
+	FindDocumentsBySHA256(hashes []string) ([]models.Document, error)
+	GetDerivedDocuments(parentID primitive.ObjectID) ([]models.Document, error)
+	SetDocumentApprovalStatus(id primitive.ObjectID, from []string, to string) (bool, error)
+	TrashDocument(id, deletedBy primitive.ObjectID, deletedAt time.Time) error
+	RestoreDocument(id primitive.ObjectID) error
+	GetTrashedDocuments(userID primitive.ObjectID) ([]models.Document, error)
+	FindDocumentsTrashedBefore(cutoff time.Time) ([]models.Document, error)
+	GetAllDocuments() ([]models.Document, error)
+	GetAllRevisions() ([]models.Revision, error)
+	RecordDownload(event *models.DownloadEvent) error
+	GetPopularDocuments(since time.Time, subject string, limit int) ([]models.SubjectPopularity, error)
+	CreateBulkImport(bulkImport *models.BulkImport) error
+	GetBulkImport(id primitive.ObjectID) (*models.BulkImport, error)
+	GetBulkImportsByUser(userID primitive.ObjectID) ([]models.BulkImport, error)
+	FindBulkImportsByStatus(status string) ([]models.BulkImport, error)
+	SetBulkImportStatus(id primitive.ObjectID, from, to string) (bool, error)
+	FinishBulkImport(bulkImport *models.BulkImport) error
+	DeleteBulkImport(id primitive.ObjectID) error
+	CreateShareLink(link *models.ShareLink) error
+	GetShareLinksByDocument(documentID primitive.ObjectID) ([]models.ShareLink, error)
+	RevokeShareLink(documentID, linkID primitive.ObjectID, revokedAt time.Time) (bool, error)
+	GetUsableShareLink(token string, now time.Time) (*models.ShareLink, error)
+	UseShareLink(token string, now time.Time) (*models.ShareLink, error)
+	CreateDocumentTextIndex() error
+	ExportCatalogue(query models.DocumentQuery, fn func(entry *models.CatalogueEntry) error) error
+	HarvestDocuments(query models.HarvestQuery) ([]models.Document, error)
+	GetPublishedSubjectsAndGrades() (*models.SubjectsAndGrades, error)
+	CreateLTIPlatform(platform *models.LTIPlatform) error
+	GetLTIPlatforms() ([]models.LTIPlatform, error)
+	GetLTIPlatform(issuer, clientID string) (*models.LTIPlatform, error)
+	GetLTIPlatformByID(id primitive.ObjectID) (*models.LTIPlatform, error)
+	DeleteLTIPlatform(id primitive.ObjectID) (bool, error)
+	CreateLTILogin(login *models.LTILogin) error
+	TakeLTILogin(state string, now time.Time) (*models.LTILogin, error)
+	CreateLTIDeepLink(deepLink *models.LTIDeepLink) error
+	GetLTIDeepLink(token string, now time.Time) (*models.LTIDeepLink, error)
+	DeleteLTIDeepLink(id primitive.ObjectID) (bool, error)
+	DeleteExpiredLTISessions(now time.Time) error
 }
 
 type StorageRepo interface {
@@ -36,6 +109,18 @@ type StorageRepo interface {
 	CreateBucket(name string, region string) error
 	PutObject(bucketName string, objectKey string, lifetimeSecs int64) (*v4.PresignedHTTPRequest, error)
 	GetObject(bucketName string, objectKey string, lifetimeSecs int64) (*v4.PresignedHTTPRequest, error)
+	GetObjectAttachment(bucketName string, objectKey string, filename string, contentType string, lifetimeSecs int64) (*v4.PresignedHTTPRequest, error)
+	PostObject(bucketName string, objectKey string, contentType string, maxBytes int64, lifetimeSecs int64) (*s3.PresignedPostRequest, error)
+	HeadObject(bucketName string, objectKey string) (*s3.HeadObjectOutput, error)
+	DeleteObjects(bucketName string, objectKeys []string) error
+	ReadObject(bucketName string, objectKey string) (io.ReadCloser, error)
+	WriteObject(bucketName string, objectKey string, contentType string, body io.Reader) error
+	CreateMultipartUpload(bucketName string, objectKey string, contentType string) (string, error)
+	UploadPart(bucketName string, objectKey string, uploadID string, partNumber int32, size int64, lifetimeSecs int64) (*v4.PresignedHTTPRequest, error)
+	ListParts(bucketName string, objectKey string, uploadID string) ([]types.Part, error)
+	CompleteMultipartUpload(bucketName string, objectKey string, uploadID string, parts []types.CompletedPart) error
+	AbortMultipartUpload(bucketName string, objectKey string, uploadID string) error
+	ListMultipartUploads(bucketName string) ([]types.MultipartUpload, error)
 }
 
 type MailRepo interface {
@@ -126,18 +126,24 @@ func (s *StorageRepo) CopyToBucket(sourceBucket string, destinationBucket string
 	return err
 }
 
-// ListObjects lists the objects in a bucket.
+// ListObjects lists every object in a bucket, following continuation tokens
+// past the first page of results.
 func (s *StorageRepo) ListObjects(bucketName string) ([]types.Object, error) {
-	result, err := s.S3Client.ListObjectsV2(context.TODO(), &s3.ListObjectsV2Input{
+	var contents []types.Object
+
+	paginator := s3.NewListObjectsV2Paginator(s.S3Client, &s3.ListObjectsV2Input{
 		Bucket: aws.String(bucketName),
 	})
-	var contents []types.Object
-	if err != nil {
-		log.Printf("Couldn't list objects in bucket %v. Here's why: %v\n", bucketName, err)
-	} else {
-		contents = result.Contents
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			log.Printf("Couldn't list objects in bucket %v. Here's why: %v\n", bucketName, err)
+			return nil, err
+		}
+		contents = append(contents, page.Contents...)
 	}
-	return contents, err
+
+	return contents, nil
 }
 
 // DeleteObjects deletes a list of objects from a bucket.
@@ -157,3 +163,53 @@ func (s *StorageRepo) DeleteObjects(bucketName string, objectKeys []string) erro
 	}
 	return err
 }
+
+// HeadObject retrieves the metadata of an object without downloading it.
+// ErrObjectNotFound is returned when the key does not exist.
+func (s *StorageRepo) HeadObject(bucketName string, objectKey string) (*s3.HeadObjectOutput, error) {
+	result, err := s.S3Client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		var notFound *types.NotFound
+		if errors.As(err, &notFound) {
+			return nil, ErrObjectNotFound
+		}
+		log.Printf("Couldn't get metadata of object %v:%v. Here's why: %v\n", bucketName, objectKey, err)
+		return nil, err
+	}
+	return result, nil
+}
+
+// ReadObject opens an object in a bucket for streaming. The caller must close
+// the returned body.
+func (s *StorageRepo) ReadObject(bucketName string, objectKey string) (io.ReadCloser, error) {
+	result, err := s.S3Client.GetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		var noSuchKey *types.NoSuchKey
+		if errors.As(err, &noSuchKey) {
+			return nil, ErrObjectNotFound
+		}
+		log.Printf("Couldn't get object %v:%v. Here's why: %v\n", bucketName, objectKey, err)
+		return nil, err
+	}
+	return result.Body, nil
+}
+
+// WriteObject stores the content of body as an object in a bucket.
+func (s *StorageRepo) WriteObject(bucketName string, objectKey string, contentType string, body io.Reader) error {
+	_, err := s.S3Client.PutObject(context.TODO(), &s3.PutObjectInput{
+		Bucket:      aws.String(bucketName),
+		Key:         aws.String(objectKey),
+		ContentType: aws.String(contentType),
+		Body:        body,
+	})
+	if err != nil {
+		log.Printf("Couldn't write object %v:%v. Here's why: %v\n", bucketName, objectKey, err)
+	}
+	return err
+}
@@ -0,0 +1,128 @@
+package storagerepo
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// CreateMultipartUpload starts a multipart upload of an object with the given
+// content type and returns its upload ID.
+func (s *StorageRepo) CreateMultipartUpload(bucketName string, objectKey string, contentType string) (string, error) {
+	result, err := s.S3Client.CreateMultipartUpload(context.TODO(), &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(bucketName),
+		Key:         aws.String(objectKey),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		log.Printf("Couldn't start multipart upload of %v:%v. Here's why: %v\n", bucketName, objectKey, err)
+		return "", err
+	}
+	return aws.ToString(result.UploadId), nil
+}
+
+// UploadPart makes a presigned request that can be used to upload one part of
+// a multipart upload. The part must be exactly size bytes long, as the
+// Content-Length is signed. The presigned request is valid for the specified
+// number of seconds.
+func (s *StorageRepo) UploadPart(
+	bucketName string, objectKey string, uploadID string, partNumber int32, size int64, lifetimeSecs int64) (*v4.PresignedHTTPRequest, error) {
+	request, err := s.PresignClient.PresignUploadPart(context.TODO(), &s3.UploadPartInput{
+		Bucket:        aws.String(bucketName),
+		Key:           aws.String(objectKey),
+		UploadId:      aws.String(uploadID),
+		PartNumber:    aws.Int32(partNumber),
+		ContentLength: aws.Int64(size),
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = time.Duration(lifetimeSecs * int64(time.Second))
+	})
+	if err != nil {
+		log.Printf("Couldn't get a presigned request to upload part %v of %v:%v. Here's why: %v\n",
+			partNumber, bucketName, objectKey, err)
+	}
+	return request, err
+}
+
+// ListParts lists the parts uploaded so far for a multipart upload.
+func (s *StorageRepo) ListParts(bucketName string, objectKey string, uploadID string) ([]types.Part, error) {
+	var parts []types.Part
+
+	paginator := s3.NewListPartsPaginator(s.S3Client, &s3.ListPartsInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(objectKey),
+		UploadId: aws.String(uploadID),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			log.Printf("Couldn't list parts of %v:%v. Here's why: %v\n", bucketName, objectKey, err)
+			return nil, err
+		}
+		parts = append(parts, page.Parts...)
+	}
+
+	return parts, nil
+}
+
+// CompleteMultipartUpload assembles the uploaded parts into the object.
+func (s *StorageRepo) CompleteMultipartUpload(bucketName string, objectKey string, uploadID string, parts []types.CompletedPart) error {
+	_, err := s.S3Client.CompleteMultipartUpload(context.TODO(), &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(bucketName),
+		Key:             aws.String(objectKey),
+		UploadId:        aws.String(uploadID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		log.Printf("Couldn't complete multipart upload of %v:%v. Here's why: %v\n", bucketName, objectKey, err)
+	}
+	return err
+}
+
+// AbortMultipartUpload cancels a multipart upload and frees its parts.
+func (s *StorageRepo) AbortMultipartUpload(bucketName string, objectKey string, uploadID string) error {
+	_, err := s.S3Client.AbortMultipartUpload(context.TODO(), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(bucketName),
+		Key:      aws.String(objectKey),
+		UploadId: aws.String(uploadID),
+	})
+	if err != nil {
+		var noSuchUpload *types.NoSuchUpload
+		if errors.As(err, &noSuchUpload) {
+			return ErrUploadNotFound
+		}
+		log.Printf("Couldn't abort multipart upload of %v:%v. Here's why: %v\n", bucketName, objectKey, err)
+	}
+	return err
+}
+
+// ListMultipartUploads lists the multipart uploads in a bucket that were
+// started but neither completed nor aborted.
+func (s *StorageRepo) ListMultipartUploads(bucketName string) ([]types.MultipartUpload, error) {
+	var uploads []types.MultipartUpload
+
+	input := &s3.ListMultipartUploadsInput{
+		Bucket: aws.String(bucketName),
+	}
+	for {
+		result, err := s.S3Client.ListMultipartUploads(context.TODO(), input)
+		if err != nil {
+			log.Printf("Couldn't list multipart uploads in bucket %v. Here's why: %v\n", bucketName, err)
+			return nil, err
+		}
+		uploads = append(uploads, result.Uploads...)
+
+		if !aws.ToBool(result.IsTruncated) {
+			break
+		}
+		input.KeyMarker = result.NextKeyMarker
+		input.UploadIdMarker = result.NextUploadIdMarker
+	}
+
+	return uploads, nil
+}
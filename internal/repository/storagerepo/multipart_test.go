@@ -0,0 +1,29 @@
+package storagerepo
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestStorageRepo_UploadPart(t *testing.T) {
+	client := s3.New(s3.Options{
+		Region: "af-south-1",
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+	})
+	s := StorageRepo{S3Client: client, PresignClient: s3.NewPresignClient(client)}
+
+	request, err := s.UploadPart("bucket", "65f1c0ffee0000000000abcd", "upload", 2, 5<<20, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A part of any other size no longer matches the signature
+	if got := request.SignedHeader.Get("Content-Length"); got != "5242880" {
+		t.Errorf("signed Content-Length = %q, want 5242880", got)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"log"
+	"mime"
 	"time"
 )
 
@@ -26,6 +27,25 @@ func (s StorageRepo) GetObject(
 	return request, err
 }
 
+// GetObjectAttachment makes a presigned request like GetObject, but the
+// response is served as a download named filename with the given content type.
+func (s StorageRepo) GetObjectAttachment(
+	bucketName string, objectKey string, filename string, contentType string, lifetimeSecs int64) (*v4.PresignedHTTPRequest, error) {
+	request, err := s.PresignClient.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+		Bucket:                     aws.String(bucketName),
+		Key:                        aws.String(objectKey),
+		ResponseContentDisposition: aws.String(mime.FormatMediaType("attachment", map[string]string{"filename": filename})),
+		ResponseContentType:        aws.String(contentType),
+	}, func(opts *s3.PresignOptions) {
+		opts.Expires = time.Duration(lifetimeSecs * int64(time.Second))
+	})
+	if err != nil {
+		log.Printf("Couldn't get a presigned request to get %v:%v. Here's why: %v\n",
+			bucketName, objectKey, err)
+	}
+	return request, err
+}
+
 // PutObject makes a presigned request that can be used to put an object in a bucket.
 // The presigned request is valid for the specified number of seconds.
 func (s StorageRepo) PutObject(
@@ -54,3 +74,33 @@ func (s StorageRepo) DeleteObject(bucketName string, objectKey string) (*v4.Pres
 	}
 	return request, err
 }
+
+// PostObject makes a presigned POST policy that can be used to upload an object
+// from a form. The policy only accepts exactly objectKey, a body of at most
+// maxBytes bytes and the given content type.
+// The presigned request is valid for the specified number of seconds.
+func (s StorageRepo) PostObject(
+	bucketName string, objectKey string, contentType string, maxBytes int64, lifetimeSecs int64) (*s3.PresignedPostRequest, error) {
+	request, err := s.PresignClient.PresignPostObject(context.TODO(), &s3.PutObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	}, func(opts *s3.PresignPostOptions) {
+		opts.Expires = time.Duration(lifetimeSecs * int64(time.Second))
+		opts.Conditions = []interface{}{
+			// An exact key, so a policy for one object cannot write another
+			// under the same prefix, such as a revision of the document
+			map[string]string{"key": objectKey},
+			[]interface{}{"content-length-range", 1, maxBytes},
+			map[string]string{"Content-Type": contentType},
+		}
+	})
+	if err != nil {
+		log.Printf("Couldn't get a presigned POST policy to put %v:%v. Here's why: %v\n",
+			bucketName, objectKey, err)
+		return nil, err
+	}
+
+	// The form must echo the content type for the policy to match
+	request.Values["Content-Type"] = contentType
+	return request, nil
+}
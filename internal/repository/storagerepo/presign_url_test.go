@@ -0,0 +1,62 @@
+package storagerepo
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+func TestStorageRepo_PostObject(t *testing.T) {
+	client := s3.New(s3.Options{
+		Region: "af-south-1",
+		Credentials: aws.CredentialsProviderFunc(func(ctx context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}, nil
+		}),
+	})
+	s := StorageRepo{S3Client: client, PresignClient: s3.NewPresignClient(client)}
+
+	request, err := s.PostObject("bucket", "65f1c0ffee0000000000abcd", "application/pdf", 1024, 60)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded, err := base64.StdEncoding.DecodeString(request.Values["policy"])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var policy struct {
+		Conditions []json.RawMessage `json:"conditions"`
+	}
+	if err := json.Unmarshal(decoded, &policy); err != nil {
+		t.Fatal(err)
+	}
+
+	keys := 0
+	for _, raw := range policy.Conditions {
+		var exact map[string]string
+		if json.Unmarshal(raw, &exact) == nil {
+			if key, ok := exact["key"]; ok {
+				keys++
+				if key != "65f1c0ffee0000000000abcd" {
+					t.Errorf("key condition = %q, want the exact object key", key)
+				}
+			}
+			continue
+		}
+
+		var condition []any
+		if json.Unmarshal(raw, &condition) == nil && len(condition) > 1 && condition[0] == "starts-with" && condition[1] == "$key" {
+			t.Errorf("policy allows a key prefix: %s", raw)
+		}
+	}
+	if keys != 1 {
+		t.Errorf("policy has %d key conditions, want 1: %s", keys, decoded)
+	}
+	if request.Values["Content-Type"] != "application/pdf" {
+		t.Errorf("Content-Type field = %q, want application/pdf", request.Values["Content-Type"])
+	}
+}
@@ -1,6 +1,17 @@
 package storagerepo
 
-import "github.com/aws/aws-sdk-go-v2/service/s3"
+import (
+	"errors"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+)
+
+// ErrObjectNotFound is returned when an object does not exist in the bucket.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ErrUploadNotFound is returned when a multipart upload was already completed
+// or aborted.
+var ErrUploadNotFound = errors.New("multipart upload not found")
 
 type StorageRepo struct {
 	S3Client      *s3.Client
@@ -0,0 +1,150 @@
+// Package scanner checks uploaded files for malware before they are served.
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Result is the verdict of a scan.
+type Result struct {
+	// Clean reports whether no malware was found.
+	Clean bool
+	// Signature names the malware that was found, if any.
+	Signature string
+}
+
+// Scanner scans the content of a file for malware.
+type Scanner interface {
+	Scan(r io.Reader) (Result, error)
+}
+
+// ClamdScanner scans files by streaming them to a clamd daemon over TCP or a
+// unix socket using the INSTREAM command.
+type ClamdScanner struct {
+	Network string
+	Address string
+	Timeout time.Duration
+}
+
+// NewClamdScanner creates a scanner for a clamd address such as
+// "tcp://localhost:3310" or "unix:///var/run/clamav/clamd.ctl".
+func NewClamdScanner(address string, timeout time.Duration) (*ClamdScanner, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid clamd address %q: %v", address, err)
+	}
+
+	switch u.Scheme {
+	case "tcp":
+		return &ClamdScanner{Network: "tcp", Address: u.Host, Timeout: timeout}, nil
+	case "unix":
+		return &ClamdScanner{Network: "unix", Address: u.Path, Timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("invalid clamd address %q: scheme must be tcp or unix", address)
+	}
+}
+
+// chunkSize is the size of the chunks the file is streamed to clamd in.
+const chunkSize = 32 * 1024
+
+// Scan streams r to clamd and parses its verdict.
+func (c *ClamdScanner) Scan(r io.Reader) (Result, error) {
+	conn, err := net.DialTimeout(c.Network, c.Address, c.Timeout)
+	if err != nil {
+		return Result{}, fmt.Errorf("could not connect to clamd: %v", err)
+	}
+	defer conn.Close()
+
+	err = conn.SetDeadline(time.Now().Add(c.Timeout))
+	if err != nil {
+		return Result{}, err
+	}
+
+	_, err = conn.Write([]byte("zINSTREAM\x00"))
+	if err != nil {
+		return Result{}, err
+	}
+
+	// Each chunk is prefixed with its length, a zero length ends the stream
+	buf := make([]byte, chunkSize)
+	size := make([]byte, 4)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size, uint32(n))
+			if _, err := conn.Write(size); err != nil {
+				return Result{}, err
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return Result{}, err
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return Result{}, readErr
+		}
+	}
+
+	binary.BigEndian.PutUint32(size, 0)
+	_, err = conn.Write(size)
+	if err != nil {
+		return Result{}, err
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString('\x00')
+	if err != nil && err != io.EOF {
+		return Result{}, err
+	}
+
+	return parseReply(reply)
+}
+
+// parseReply interprets a clamd reply such as "stream: OK" or
+// "stream: Eicar-Signature FOUND".
+func parseReply(reply string) (Result, error) {
+	reply = strings.TrimSpace(strings.TrimRight(reply, "\x00"))
+	reply = strings.TrimPrefix(reply, "stream: ")
+
+	switch {
+	case reply == "OK":
+		return Result{Clean: true}, nil
+	case strings.HasSuffix(reply, " FOUND"):
+		return Result{Signature: strings.TrimSuffix(reply, " FOUND")}, nil
+	case reply == "":
+		return Result{}, errors.New("empty reply from clamd")
+	default:
+		return Result{}, fmt.Errorf("clamd error: %s", reply)
+	}
+}
+
+// eicar is the standard anti-virus test file.
+var eicar = []byte(`X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`)
+
+// StubScanner is a deterministic scanner for tests and local development.
+// It reports the EICAR test file as infected and everything else as clean.
+type StubScanner struct{}
+
+// Scan reads r and looks for the EICAR test signature.
+func (StubScanner) Scan(r io.Reader) (Result, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return Result{}, err
+	}
+
+	if bytes.Contains(content, eicar) {
+		return Result{Signature: "Eicar-Test-Signature"}, nil
+	}
+
+	return Result{Clean: true}, nil
+}
@@ -0,0 +1,108 @@
+package scanner
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts one INSTREAM session and answers with reply once the
+// stream has been read completely. It returns the bytes it received.
+func fakeClamd(t *testing.T, reply string) (string, <-chan []byte) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		command := make([]byte, len("zINSTREAM\x00"))
+		if _, err := io.ReadFull(conn, command); err != nil || string(command) != "zINSTREAM\x00" {
+			received <- nil
+			return
+		}
+
+		var content bytes.Buffer
+		size := make([]byte, 4)
+		for {
+			if _, err := io.ReadFull(conn, size); err != nil {
+				received <- nil
+				return
+			}
+			n := binary.BigEndian.Uint32(size)
+			if n == 0 {
+				break
+			}
+			if _, err := io.CopyN(&content, conn, int64(n)); err != nil {
+				received <- nil
+				return
+			}
+		}
+
+		_, _ = conn.Write([]byte(reply + "\x00"))
+		received <- content.Bytes()
+	}()
+
+	return "tcp://" + listener.Addr().String(), received
+}
+
+func TestClamdScanner_Scan(t *testing.T) {
+	tests := []struct {
+		name       string
+		reply      string
+		wantResult Result
+		wantErr    bool
+	}{
+		{name: "clean", reply: "stream: OK", wantResult: Result{Clean: true}},
+		{name: "infected", reply: "stream: Eicar-Signature FOUND", wantResult: Result{Signature: "Eicar-Signature"}},
+		{name: "size limit", reply: "INSTREAM size limit exceeded. ERROR", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, received := fakeClamd(t, tt.reply)
+
+			s, err := NewClamdScanner(address, 5*time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Larger than one chunk so the stream is split
+			content := strings.Repeat("worksheet ", chunkSize/5)
+			got, err := s.Scan(strings.NewReader(content))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Scan() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.wantResult {
+				t.Errorf("Scan() = %+v, want %+v", got, tt.wantResult)
+			}
+			if string(<-received) != content {
+				t.Errorf("clamd did not receive the full file")
+			}
+		})
+	}
+}
+
+func TestStubScanner_Scan(t *testing.T) {
+	got, err := StubScanner{}.Scan(bytes.NewReader(eicar))
+	if err != nil || got.Clean {
+		t.Errorf("Scan(eicar) = %+v, %v, want infected", got, err)
+	}
+
+	got, err = StubScanner{}.Scan(strings.NewReader("%PDF-1.4 lesson plan"))
+	if err != nil || !got.Clean {
+		t.Errorf("Scan(pdf) = %+v, %v, want clean", got, err)
+	}
+}
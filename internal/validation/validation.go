@@ -0,0 +1,154 @@
+// Package validation inspects uploaded files to make sure their content
+// matches one of the document formats accepted by Share2Teach.
+package validation
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+const (
+	TypePDF  = "application/pdf"
+	TypeDOCX = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	TypePPTX = "application/vnd.openxmlformats-officedocument.presentationml.presentation"
+	TypeXLSX = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	TypePNG  = "image/png"
+	TypeJPEG = "image/jpeg"
+	TypeGIF  = "image/gif"
+	TypeWEBP = "image/webp"
+	TypeZIP  = "application/zip"
+)
+
+// Result is the outcome of inspecting a file.
+type Result struct {
+	// DetectedType is the MIME type derived from the file's content, or
+	// empty when the content was not recognised.
+	DetectedType string
+	// Valid reports whether the file is well-formed and of an accepted type.
+	Valid bool
+	// Reason explains why the file was rejected.
+	Reason string
+}
+
+// ooxmlMainParts maps the part that identifies each OOXML container to its type.
+var ooxmlMainParts = map[string]string{
+	"word/document.xml":    TypeDOCX,
+	"ppt/presentation.xml": TypePPTX,
+	"xl/workbook.xml":      TypeXLSX,
+}
+
+// Sniff returns the MIME type of a file from its leading bytes. OOXML files
+// are reported as TypeZIP since telling them apart requires the whole archive.
+func Sniff(header []byte) string {
+	switch {
+	case bytes.HasPrefix(header, []byte("%PDF-")):
+		return TypePDF
+	case bytes.HasPrefix(header, []byte("PK\x03\x04")):
+		return TypeZIP
+	case bytes.HasPrefix(header, []byte("\x89PNG\r\n\x1a\n")):
+		return TypePNG
+	case bytes.HasPrefix(header, []byte("\xff\xd8\xff")):
+		return TypeJPEG
+	case bytes.HasPrefix(header, []byte("GIF87a")), bytes.HasPrefix(header, []byte("GIF89a")):
+		return TypeGIF
+	case len(header) >= 12 && bytes.Equal(header[0:4], []byte("RIFF")) && bytes.Equal(header[8:12], []byte("WEBP")):
+		return TypeWEBP
+	}
+	return ""
+}
+
+// Inspect detects the real type of the file in r and checks that it parses
+// as that type. declaredType is the MIME type the file was uploaded as.
+func Inspect(r io.ReaderAt, size int64, declaredType string) Result {
+	header := make([]byte, 16)
+	n, err := r.ReadAt(header, 0)
+	if err != nil && err != io.EOF {
+		return Result{Reason: fmt.Sprintf("could not read file: %v", err)}
+	}
+	header = header[:n]
+
+	detected := Sniff(header)
+
+	var check error
+	switch detected {
+	case TypePDF:
+		check = checkPDF(r, size)
+	case TypeZIP:
+		detected, check = checkOOXML(r, size)
+	case TypePNG, TypeJPEG, TypeGIF:
+		_, _, check = image.DecodeConfig(io.NewSectionReader(r, 0, size))
+	case TypeWEBP:
+		// The standard library has no WebP decoder, the RIFF header is all we check
+	default:
+		return Result{Reason: "file content is not a supported document or image format"}
+	}
+
+	if check != nil {
+		return Result{DetectedType: detected, Reason: fmt.Sprintf("file is not a valid %s: %v", detected, check)}
+	}
+
+	if declaredType != "" && declaredType != detected {
+		return Result{
+			DetectedType: detected,
+			Reason:       fmt.Sprintf("file content is %s but it was uploaded as %s", detected, declaredType),
+		}
+	}
+
+	return Result{DetectedType: detected, Valid: true}
+}
+
+// checkPDF makes sure the file has a cross-reference pointer and an end of
+// file marker, which every complete PDF ends with.
+func checkPDF(r io.ReaderAt, size int64) error {
+	tailSize := int64(1024)
+	if size < tailSize {
+		tailSize = size
+	}
+
+	tail := make([]byte, tailSize)
+	_, err := r.ReadAt(tail, size-tailSize)
+	if err != nil && err != io.EOF {
+		return err
+	}
+
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return fmt.Errorf("missing end of file marker")
+	}
+	if !bytes.Contains(tail, []byte("startxref")) {
+		return fmt.Errorf("missing cross-reference table")
+	}
+
+	return nil
+}
+
+// checkOOXML opens the file as a zip archive and identifies which Office
+// format it holds from its parts.
+func checkOOXML(r io.ReaderAt, size int64) (string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return TypeZIP, err
+	}
+
+	hasContentTypes := false
+	detected := ""
+	for _, file := range archive.File {
+		if file.Name == "[Content_Types].xml" {
+			hasContentTypes = true
+		}
+		if t, ok := ooxmlMainParts[file.Name]; ok {
+			detected = t
+		}
+	}
+
+	if !hasContentTypes || detected == "" {
+		return TypeZIP, fmt.Errorf("archive is not an Office document")
+	}
+
+	return detected, nil
+}
@@ -0,0 +1,56 @@
+package validation
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+)
+
+func zipWith(t *testing.T, names ...string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		f, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _ = f.Write([]byte("<xml/>"))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestInspect(t *testing.T) {
+	validPDF := []byte("%PDF-1.4\n1 0 obj\n<<>>\nendobj\nxref\n0 1\ntrailer\n<<>>\nstartxref\n9\n%%EOF\n")
+	docx := zipWith(t, "[Content_Types].xml", "word/document.xml")
+
+	tests := []struct {
+		name         string
+		content      []byte
+		declaredType string
+		wantType     string
+		wantValid    bool
+	}{
+		{name: "valid pdf", content: validPDF, declaredType: TypePDF, wantType: TypePDF, wantValid: true},
+		{name: "truncated pdf", content: validPDF[:20], declaredType: TypePDF, wantType: TypePDF},
+		{name: "valid docx", content: docx, declaredType: TypeDOCX, wantType: TypeDOCX, wantValid: true},
+		{name: "plain zip", content: zipWith(t, "notes.txt"), declaredType: TypeDOCX, wantType: TypeZIP},
+		{name: "renamed executable", content: []byte("MZ\x90\x00\x03\x00\x00\x00"), declaredType: TypePDF},
+		{name: "docx uploaded as pdf", content: docx, declaredType: TypePDF, wantType: TypeDOCX},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Inspect(bytes.NewReader(tt.content), int64(len(tt.content)), tt.declaredType)
+			if got.DetectedType != tt.wantType || got.Valid != tt.wantValid {
+				t.Errorf("Inspect() = %+v, want type %q valid %v", got, tt.wantType, tt.wantValid)
+			}
+			if !got.Valid && got.Reason == "" {
+				t.Errorf("Inspect() rejected the file without a reason")
+			}
+		})
+	}
+}
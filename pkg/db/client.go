@@ -11,12 +11,24 @@ type Collection interface {
 	InsertOne(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
 	Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (cur *mongo.Cursor, err error)
 	UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error)
+	FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult
+	Indexes() mongo.IndexView
 }
 
 type MongoCollectionMock struct {
-	UpdateOneFunc func(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
-	FindOneFunc   func(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
-	InsertOneFunc func(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	UpdateOneFunc  func(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	FindOneFunc    func(ctx context.Context, filter interface{}, opts ...*options.FindOneOptions) *mongo.SingleResult
+	InsertOneFunc  func(ctx context.Context, document interface{}, opts ...*options.InsertOneOptions) (*mongo.InsertOneResult, error)
+	DeleteOneFunc  func(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	DeleteManyFunc func(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error)
+	UpdateManyFunc func(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error)
+	AggregateFunc  func(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error)
+
+	FindOneAndUpdateFunc func(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult
 }
 
 func (m *MongoCollectionMock) UpdateOne(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
@@ -40,6 +52,45 @@ func (m *MongoCollectionMock) InsertOne(ctx context.Context, document interface{
 	return &mongo.InsertOneResult{}, nil
 }
 
+func (m *MongoCollectionMock) DeleteOne(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	if m.DeleteOneFunc != nil {
+		return m.DeleteOneFunc(ctx, filter, opts...)
+	}
+	return &mongo.DeleteResult{}, nil
+}
+
+func (m *MongoCollectionMock) DeleteMany(ctx context.Context, filter interface{}, opts ...*options.DeleteOptions) (*mongo.DeleteResult, error) {
+	if m.DeleteManyFunc != nil {
+		return m.DeleteManyFunc(ctx, filter, opts...)
+	}
+	return &mongo.DeleteResult{}, nil
+}
+
+func (m *MongoCollectionMock) UpdateMany(ctx context.Context, filter interface{}, update interface{}, opts ...*options.UpdateOptions) (*mongo.UpdateResult, error) {
+	if m.UpdateManyFunc != nil {
+		return m.UpdateManyFunc(ctx, filter, update, opts...)
+	}
+	return &mongo.UpdateResult{}, nil
+}
+
+func (m *MongoCollectionMock) Aggregate(ctx context.Context, pipeline interface{}, opts ...*options.AggregateOptions) (*mongo.Cursor, error) {
+	if m.AggregateFunc != nil {
+		return m.AggregateFunc(ctx, pipeline, opts...)
+	}
+	return &mongo.Cursor{}, nil
+}
+
+func (m *MongoCollectionMock) FindOneAndUpdate(ctx context.Context, filter interface{}, update interface{}, opts ...*options.FindOneAndUpdateOptions) *mongo.SingleResult {
+	if m.FindOneAndUpdateFunc != nil {
+		return m.FindOneAndUpdateFunc(ctx, filter, update, opts...)
+	}
+	return &mongo.SingleResult{}
+}
+
+func (m *MongoCollectionMock) Indexes() mongo.IndexView {
+	return mongo.IndexView{}
+}
+
 func (m *MongoCollectionMock) Find(ctx context.Context, filter interface{}, opts ...*options.FindOptions) (cur *mongo.Cursor, err error) {
 	return &mongo.Cursor{}, nil
 }